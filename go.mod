@@ -1,3 +1,5 @@
 module appstract
 
-go 1.22
\ No newline at end of file
+go 1.22
+
+require github.com/ulikunitz/xz v0.5.15
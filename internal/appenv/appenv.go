@@ -0,0 +1,167 @@
+// Package appenv collects the environment variables and PATH entries that
+// installed apps declare in their manifests and publishes them through one
+// or more backends: generated files under <root>/env that users source, and
+// optionally the Windows user environment in the registry.
+package appenv
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"appstract/internal/manifest"
+)
+
+// Var is one environment variable to set.
+type Var struct {
+	Name  string
+	Value string
+}
+
+// AppEnv is what a single installed app contributes.
+type AppEnv struct {
+	App   string
+	Vars  []Var
+	Paths []string
+}
+
+// Environment is the merged contribution of every installed app, ordered by
+// app name. When two apps set the same variable the later one wins and the
+// clash is listed in Conflicts.
+type Environment struct {
+	Apps      []AppEnv
+	Conflicts []string
+}
+
+// Vars returns the effective variables after conflict resolution, sorted by
+// name.
+func (e *Environment) Vars() []Var {
+	byName := map[string]Var{}
+	for _, app := range e.Apps {
+		for _, v := range app.Vars {
+			byName[strings.ToUpper(v.Name)] = v
+		}
+	}
+	vars := make([]Var, 0, len(byName))
+	for _, v := range byName {
+		vars = append(vars, v)
+	}
+	sort.Slice(vars, func(i, j int) bool { return strings.ToUpper(vars[i].Name) < strings.ToUpper(vars[j].Name) })
+	return vars
+}
+
+// Paths returns every PATH entry in app order without duplicates.
+func (e *Environment) Paths() []string {
+	var paths []string
+	seen := map[string]bool{}
+	for _, app := range e.Apps {
+		for _, p := range app.Paths {
+			key := strings.ToLower(p)
+			if !seen[key] {
+				seen[key] = true
+				paths = append(paths, p)
+			}
+		}
+	}
+	return paths
+}
+
+// env_backend config values.
+const (
+	EnvBackendFiles    = "files"
+	EnvBackendRegistry = "registry"
+)
+
+// Backend publishes an environment. Apply receives the complete environment
+// each time and must drop whatever an earlier Apply set that is gone now.
+type Backend interface {
+	Name() string
+	Apply(env *Environment) error
+}
+
+// Collect reads manifests/*.json and keeps the apps that have a current
+// version and declare env_set or env_add_path. A broken manifest is skipped:
+// it cannot have been installed from either.
+func Collect(root string) (*Environment, error) {
+	entries, err := os.ReadDir(filepath.Join(root, "manifests"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &Environment{}, nil
+		}
+		return nil, fmt.Errorf("read manifests: %w", err)
+	}
+	env := &Environment{}
+	owner := map[string]string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || !strings.EqualFold(filepath.Ext(name), ".json") {
+			continue
+		}
+		app := strings.TrimSuffix(name, filepath.Ext(name))
+		man, err := manifest.ParseFile(filepath.Join(root, "manifests", name))
+		if err != nil || (len(man.EnvSet) == 0 && len(man.EnvAddPath) == 0) {
+			continue
+		}
+		dir := filepath.Join(root, "apps", app, "current")
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		appEnv := resolve(app, dir, man)
+		for _, v := range appEnv.Vars {
+			key := strings.ToUpper(v.Name)
+			if prev, ok := owner[key]; ok {
+				env.Conflicts = append(env.Conflicts, fmt.Sprintf("%s set by %s and %s; using %s", v.Name, prev, app, app))
+			}
+			owner[key] = app
+		}
+		env.Apps = append(env.Apps, appEnv)
+	}
+	sort.Slice(env.Apps, func(i, j int) bool { return env.Apps[i].App < env.Apps[j].App })
+	return env, nil
+}
+
+func resolve(app, dir string, man *manifest.Manifest) AppEnv {
+	appEnv := AppEnv{App: app}
+	names := make([]string, 0, len(man.EnvSet))
+	for name := range man.EnvSet {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := strings.ReplaceAll(man.EnvSet[name], "$dir", dir)
+		appEnv.Vars = append(appEnv.Vars, Var{Name: name, Value: value})
+	}
+	for _, entry := range man.EnvAddPath {
+		appEnv.Paths = append(appEnv.Paths, filepath.Join(dir, filepath.FromSlash(entry)))
+	}
+	return appEnv
+}
+
+// Regenerate collects the environment and hands it to every backend. All
+// backends run even when one fails; the errors are joined.
+func Regenerate(root string, backends ...Backend) (*Environment, error) {
+	env, err := Collect(root)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, backend := range backends {
+		if err := backend.Apply(env); err != nil {
+			errs = append(errs, fmt.Errorf("%s environment: %w", backend.Name(), err))
+		}
+	}
+	return env, errors.Join(errs...)
+}
+
+// Backends returns the backends for an env_backend config value: the files
+// always, plus the Windows user environment for "registry".
+func Backends(root, kind string) []Backend {
+	backends := []Backend{FileBackend{Root: root}}
+	if kind == EnvBackendRegistry {
+		backends = append(backends, NewRegistryBackend(root))
+	}
+	return backends
+}
@@ -0,0 +1,144 @@
+package appenv
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeApp(t *testing.T, root, app, extra string, installed bool) {
+	t.Helper()
+	content := `{"version": "1.0", "bin": "app.exe", "architecture": {"64bit": {"url": "https://example.com/app.zip", "hash": "abc"}}` + extra + `}`
+	if err := os.MkdirAll(filepath.Join(root, "manifests"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "manifests", app+".json"), []byte(content), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	if installed {
+		if err := os.MkdirAll(filepath.Join(root, "apps", app, "current"), 0o755); err != nil {
+			t.Fatalf("mkdir current: %v", err)
+		}
+	}
+}
+
+func TestCollectResolvesAgainstCurrent(t *testing.T) {
+	root := t.TempDir()
+	writeApp(t, root, "jre", `, "env_set": {"JAVA_HOME": "$dir"}, "env_add_path": "bin"`, true)
+	writeApp(t, root, "node", `, "env_set": {"JAVA_HOME": "$dir/other", "NODE_OPTS": "--x"}, "env_add_path": ["", "tools"]`, true)
+	writeApp(t, root, "pending", `, "env_add_path": "bin"`, false)
+	writeApp(t, root, "plain", ``, true)
+
+	env, err := Collect(root)
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	if len(env.Apps) != 2 || env.Apps[0].App != "jre" || env.Apps[1].App != "node" {
+		t.Fatalf("expected installed apps with env only, got %+v", env.Apps)
+	}
+	jreDir := filepath.Join(root, "apps", "jre", "current")
+	nodeDir := filepath.Join(root, "apps", "node", "current")
+	paths := env.Paths()
+	want := []string{filepath.Join(jreDir, "bin"), nodeDir, filepath.Join(nodeDir, "tools")}
+	if strings.Join(paths, "|") != strings.Join(want, "|") {
+		t.Fatalf("unexpected paths: %v", paths)
+	}
+	vars := env.Vars()
+	if len(vars) != 2 || vars[0].Name != "JAVA_HOME" || vars[0].Value != nodeDir+"/other" || vars[1].Name != "NODE_OPTS" {
+		t.Fatalf("unexpected vars: %+v", vars)
+	}
+	if len(env.Conflicts) != 1 || !strings.Contains(env.Conflicts[0], "JAVA_HOME set by jre and node") {
+		t.Fatalf("expected JAVA_HOME conflict, got %v", env.Conflicts)
+	}
+}
+
+func TestFileBackendWritesAllFormats(t *testing.T) {
+	root := t.TempDir()
+	env := &Environment{Apps: []AppEnv{{
+		App:   "jre",
+		Vars:  []Var{{Name: "JAVA_HOME", Value: `/opt/it's "here"`}},
+		Paths: []string{"/a/bin", "/b/bin"},
+	}}}
+	backend := FileBackend{Root: root}
+	if err := backend.Apply(env); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	read := func(name string) string {
+		b, err := os.ReadFile(filepath.Join(root, "env", name))
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		return string(b)
+	}
+	sep := string(os.PathListSeparator)
+	if got := read(PowerShellFile); !strings.Contains(got, `$env:JAVA_HOME = '/opt/it''s "here"'`) ||
+		!strings.Contains(got, "$env:PATH = '/a/bin"+sep+"/b/bin"+sep+"' + $env:PATH") {
+		t.Fatalf("unexpected powershell snippet:\n%s", got)
+	}
+	if got := read(ShellFile); !strings.Contains(got, `export JAVA_HOME='/opt/it'\''s "here"'`) ||
+		!strings.Contains(got, `export PATH='/a/bin:/b/bin':"$PATH"`) {
+		t.Fatalf("unexpected sh export file:\n%s", got)
+	}
+	if got := read(DotEnvFile); !strings.Contains(got, `JAVA_HOME="/opt/it's \"here\""`) ||
+		!strings.Contains(got, `APPSTRACT_PATH="/a/bin`+sep+`/b/bin"`) {
+		t.Fatalf("unexpected .env file:\n%s", got)
+	}
+
+	// An empty environment still produces sourceable files.
+	if err := backend.Apply(&Environment{}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if got := read(ShellFile); strings.Contains(got, "export") {
+		t.Fatalf("expected no exports after apps are gone:\n%s", got)
+	}
+}
+
+type fakeStore struct {
+	values    map[string]string
+	broadcast int
+}
+
+func (s *fakeStore) Get(name string) (string, bool, error) {
+	v, ok := s.values[name]
+	return v, ok, nil
+}
+
+func (s *fakeStore) Set(name, value string) error {
+	s.values[name] = value
+	return nil
+}
+
+func (s *fakeStore) Delete(name string) error {
+	delete(s.values, name)
+	return nil
+}
+
+func (s *fakeStore) Broadcast() { s.broadcast++ }
+
+func TestRegistryBackendTracksWhatItOwns(t *testing.T) {
+	root := t.TempDir()
+	store := &fakeStore{values: map[string]string{"Path": `C:\Users\me\bin;C:\Old\jre\bin`, "EDITOR": "vim"}}
+	backend := RegistryBackend{Root: root, store: store}
+
+	first := &Environment{Apps: []AppEnv{{App: "jre", Vars: []Var{{Name: "JAVA_HOME", Value: `C:\A\jre`}}, Paths: []string{`C:\A\jre\bin`}}}}
+	if err := backend.Apply(first); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if store.values["JAVA_HOME"] != `C:\A\jre` || store.values["Path"] != `C:\A\jre\bin;C:\Users\me\bin;C:\Old\jre\bin` {
+		t.Fatalf("unexpected registry after first apply: %v", store.values)
+	}
+
+	if err := backend.Apply(&Environment{}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if _, ok := store.values["JAVA_HOME"]; ok {
+		t.Fatalf("expected JAVA_HOME removed: %v", store.values)
+	}
+	if store.values["Path"] != `C:\Users\me\bin;C:\Old\jre\bin` || store.values["EDITOR"] != "vim" {
+		t.Fatalf("user values must survive: %v", store.values)
+	}
+	if store.broadcast != 2 {
+		t.Fatalf("expected a broadcast per apply, got %d", store.broadcast)
+	}
+}
@@ -0,0 +1,124 @@
+package appenv
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Generated file names under <root>/env.
+const (
+	PowerShellFile = "appstract.ps1"
+	DotEnvFile     = "appstract.env"
+	ShellFile      = "appstract.sh"
+)
+
+const generatedHeader = "generated by appstract after every switch and cleanup; do not edit"
+
+// FileBackend writes the environment as a PowerShell profile snippet, a
+// dotenv file and a POSIX sh script under <root>/env.
+type FileBackend struct {
+	Root string
+}
+
+func (FileBackend) Name() string { return "files" }
+
+// Dir is where the generated files live.
+func (b FileBackend) Dir() string {
+	return filepath.Join(b.Root, "env")
+}
+
+func (b FileBackend) Apply(env *Environment) error {
+	if err := os.MkdirAll(b.Dir(), 0o755); err != nil {
+		return fmt.Errorf("create env directory: %w", err)
+	}
+	files := map[string]string{
+		PowerShellFile: renderPowerShell(env),
+		DotEnvFile:     renderDotEnv(env),
+		ShellFile:      renderShell(env),
+	}
+	for _, name := range []string{PowerShellFile, DotEnvFile, ShellFile} {
+		if err := writeIfChanged(filepath.Join(b.Dir(), name), files[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func renderPowerShell(env *Environment) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n# usage: . '%s'\n", generatedHeader, PowerShellFile)
+	for _, v := range env.Vars() {
+		fmt.Fprintf(&b, "$env:%s = %s\n", v.Name, quotePowerShell(v.Value))
+	}
+	if paths := env.Paths(); len(paths) > 0 {
+		sep := string(os.PathListSeparator)
+		fmt.Fprintf(&b, "$env:PATH = %s + $env:PATH\n", quotePowerShell(strings.Join(paths, sep)+sep))
+	}
+	return b.String()
+}
+
+// renderDotEnv lists the variables as KEY="value". Dotenv loaders cannot
+// extend an existing PATH portably, so the entries go to APPSTRACT_PATH for
+// the caller to prepend.
+func renderDotEnv(env *Environment) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", generatedHeader)
+	for _, v := range env.Vars() {
+		fmt.Fprintf(&b, "%s=%s\n", v.Name, quoteDotEnv(v.Value))
+	}
+	if paths := env.Paths(); len(paths) > 0 {
+		fmt.Fprintf(&b, "APPSTRACT_PATH=%s\n", quoteDotEnv(strings.Join(paths, string(os.PathListSeparator))))
+	}
+	return b.String()
+}
+
+func renderShell(env *Environment) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n# usage: . ./%s\n", generatedHeader, ShellFile)
+	for _, v := range env.Vars() {
+		fmt.Fprintf(&b, "export %s=%s\n", v.Name, quoteShell(v.Value))
+	}
+	if paths := env.Paths(); len(paths) > 0 {
+		fmt.Fprintf(&b, "export PATH=%s:\"$PATH\"\n", quoteShell(strings.Join(paths, ":")))
+	}
+	return b.String()
+}
+
+func quotePowerShell(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func quoteShell(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func quoteDotEnv(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "$", `\$`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// writeIfChanged replaces path atomically, leaving it untouched when the
+// content is already current so file watchers are not woken for nothing.
+func writeIfChanged(path, content string) error {
+	if existing, err := os.ReadFile(path); err == nil && string(existing) == content {
+		return nil
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("write %s: %w", filepath.Base(path), err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write %s: %w", filepath.Base(path), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
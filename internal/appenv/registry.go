@@ -0,0 +1,138 @@
+package appenv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// registryStateFile remembers what the registry backend wrote so the next
+// Apply can take back variables and PATH entries of removed apps without
+// touching anything the user set.
+const registryStateFile = ".registry.json"
+
+// userEnvStore is the per-user environment as persisted by the OS.
+type userEnvStore interface {
+	Get(name string) (string, bool, error)
+	Set(name, value string) error
+	Delete(name string) error
+	// Broadcast tells running programs (Explorer, new shells) to reload.
+	Broadcast()
+}
+
+var errRegistryUnsupported = errors.New("registry environment backend is only available on Windows")
+
+// RegistryBackend writes variables and PATH entries into the Windows user
+// environment (HKCU\Environment).
+type RegistryBackend struct {
+	Root  string
+	store userEnvStore
+}
+
+func NewRegistryBackend(root string) RegistryBackend {
+	return RegistryBackend{Root: root, store: newUserEnvStore()}
+}
+
+func (RegistryBackend) Name() string { return "registry" }
+
+type registryState struct {
+	Vars  []string `json:"vars,omitempty"`
+	Paths []string `json:"paths,omitempty"`
+}
+
+func (b RegistryBackend) statePath() string {
+	return filepath.Join(b.Root, "env", registryStateFile)
+}
+
+func (b RegistryBackend) Apply(env *Environment) error {
+	prev, err := b.loadState()
+	if err != nil {
+		return err
+	}
+	vars := env.Vars()
+	next := registryState{Paths: env.Paths()}
+	keep := map[string]bool{}
+	for _, v := range vars {
+		keep[strings.ToUpper(v.Name)] = true
+		next.Vars = append(next.Vars, v.Name)
+	}
+	for _, name := range prev.Vars {
+		if keep[strings.ToUpper(name)] {
+			continue
+		}
+		if err := b.store.Delete(name); err != nil {
+			return fmt.Errorf("delete %s: %w", name, err)
+		}
+	}
+	for _, v := range vars {
+		if current, ok, err := b.store.Get(v.Name); err != nil {
+			return fmt.Errorf("read %s: %w", v.Name, err)
+		} else if ok && current == v.Value {
+			continue
+		}
+		if err := b.store.Set(v.Name, v.Value); err != nil {
+			return fmt.Errorf("set %s: %w", v.Name, err)
+		}
+	}
+
+	userPath, _, err := b.store.Get("Path")
+	if err != nil {
+		return fmt.Errorf("read Path: %w", err)
+	}
+	if merged := mergeUserPath(userPath, prev.Paths, next.Paths); merged != userPath {
+		if err := b.store.Set("Path", merged); err != nil {
+			return fmt.Errorf("set Path: %w", err)
+		}
+	}
+	b.store.Broadcast()
+	return b.saveState(next)
+}
+
+// mergeUserPath drops the entries appstract added last time and prepends the
+// current ones, keeping the user's own entries in their order.
+func mergeUserPath(userPath string, previous, current []string) string {
+	owned := map[string]bool{}
+	for _, p := range previous {
+		owned[strings.ToLower(p)] = true
+	}
+	for _, p := range current {
+		owned[strings.ToLower(p)] = true
+	}
+	entries := append([]string{}, current...)
+	for _, entry := range strings.Split(userPath, ";") {
+		if entry == "" || owned[strings.ToLower(entry)] {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return strings.Join(entries, ";")
+}
+
+func (b RegistryBackend) loadState() (registryState, error) {
+	var s registryState
+	data, err := os.ReadFile(b.statePath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return s, nil
+		}
+		return s, fmt.Errorf("read registry state: %w", err)
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("decode registry state: %w", err)
+	}
+	return s, nil
+}
+
+func (b RegistryBackend) saveState(s registryState) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(b.statePath()), 0o755); err != nil {
+		return err
+	}
+	return writeIfChanged(b.statePath(), string(data)+"\n")
+}
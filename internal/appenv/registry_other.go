@@ -0,0 +1,12 @@
+//go:build !windows
+
+package appenv
+
+type unsupportedStore struct{}
+
+func newUserEnvStore() userEnvStore { return unsupportedStore{} }
+
+func (unsupportedStore) Get(string) (string, bool, error) { return "", false, errRegistryUnsupported }
+func (unsupportedStore) Set(string, string) error         { return errRegistryUnsupported }
+func (unsupportedStore) Delete(string) error              { return errRegistryUnsupported }
+func (unsupportedStore) Broadcast()                       {}
@@ -0,0 +1,124 @@
+//go:build windows
+
+package appenv
+
+import (
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	keyQueryValue    = 0x0001
+	keySetValue      = 0x0002
+	regSZ            = 1
+	regExpandSZ      = 2
+	wmSettingChange  = 0x001A
+	hwndBroadcast    = 0xFFFF
+	smtoAbortIfHung  = 0x0002
+	broadcastTimeout = 5000
+)
+
+var (
+	advapi32            = syscall.NewLazyDLL("advapi32.dll")
+	procRegSetValueExW  = advapi32.NewProc("RegSetValueExW")
+	procRegDeleteValueW = advapi32.NewProc("RegDeleteValueW")
+	user32              = syscall.NewLazyDLL("user32.dll")
+	procSendMessageTO   = user32.NewProc("SendMessageTimeoutW")
+)
+
+type registryStore struct{}
+
+func newUserEnvStore() userEnvStore { return registryStore{} }
+
+func openEnvironmentKey(access uint32) (syscall.Handle, error) {
+	var h syscall.Handle
+	subkey, err := syscall.UTF16PtrFromString("Environment")
+	if err != nil {
+		return 0, err
+	}
+	if err := syscall.RegOpenKeyEx(syscall.HKEY_CURRENT_USER, subkey, 0, access, &h); err != nil {
+		return 0, err
+	}
+	return h, nil
+}
+
+func (registryStore) Get(name string) (string, bool, error) {
+	h, err := openEnvironmentKey(keyQueryValue)
+	if err != nil {
+		return "", false, err
+	}
+	defer syscall.RegCloseKey(h)
+	namePtr, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return "", false, err
+	}
+	var valType, size uint32
+	if err := syscall.RegQueryValueEx(h, namePtr, nil, &valType, nil, &size); err != nil {
+		if err == syscall.ERROR_FILE_NOT_FOUND {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	if size == 0 {
+		return "", true, nil
+	}
+	buf := make([]uint16, size/2+1)
+	if err := syscall.RegQueryValueEx(h, namePtr, nil, &valType, (*byte)(unsafe.Pointer(&buf[0])), &size); err != nil {
+		return "", false, err
+	}
+	return syscall.UTF16ToString(buf), true, nil
+}
+
+func (registryStore) Set(name, value string) error {
+	h, err := openEnvironmentKey(keySetValue)
+	if err != nil {
+		return err
+	}
+	defer syscall.RegCloseKey(h)
+	namePtr, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+	data, err := syscall.UTF16FromString(value)
+	if err != nil {
+		return err
+	}
+	valType := uint32(regSZ)
+	if strings.Contains(value, "%") {
+		valType = regExpandSZ
+	}
+	r, _, _ := procRegSetValueExW.Call(uintptr(h), uintptr(unsafe.Pointer(namePtr)), 0, uintptr(valType),
+		uintptr(unsafe.Pointer(&data[0])), uintptr(len(data)*2))
+	if r != 0 {
+		return syscall.Errno(r)
+	}
+	return nil
+}
+
+func (registryStore) Delete(name string) error {
+	h, err := openEnvironmentKey(keySetValue)
+	if err != nil {
+		return err
+	}
+	defer syscall.RegCloseKey(h)
+	namePtr, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+	r, _, _ := procRegDeleteValueW.Call(uintptr(h), uintptr(unsafe.Pointer(namePtr)))
+	if r != 0 && syscall.Errno(r) != syscall.ERROR_FILE_NOT_FOUND {
+		return syscall.Errno(r)
+	}
+	return nil
+}
+
+func (registryStore) Broadcast() {
+	param, err := syscall.UTF16PtrFromString("Environment")
+	if err != nil {
+		return
+	}
+	var result uintptr
+	procSendMessageTO.Call(hwndBroadcast, wmSettingChange, 0, uintptr(unsafe.Pointer(param)),
+		smtoAbortIfHung, broadcastTimeout, uintptr(unsafe.Pointer(&result)))
+}
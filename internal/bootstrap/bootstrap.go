@@ -28,10 +28,20 @@ type LayoutState struct {
 const defaultConfigYAML = `github_token: ""
 proxy: ""
 check_ttl_seconds: 3600
+check_jitter_seconds: 300
+quiet_hours: ""
 keep_versions: 2
+architecture: "auto"
+env_backend: "files"
 output_level: "default"
 download_timeout_seconds: 120
 max_retry: 3
+extract_max_bytes: 8589934592
+extract_max_files: 200000
+extract_max_ratio: 200
+log_retention_days: 30
+log_retention_bytes: 67108864
+log_compact_after_days: 7
 log_level: "info"
 `
 
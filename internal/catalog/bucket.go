@@ -0,0 +1,180 @@
+// Package catalog manages where manifests come from: named buckets of
+// manifests and the origin recorded for every manifest under manifests/.
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"appstract/internal/config"
+)
+
+var bucketNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// runGit is swapped out in tests.
+var runGit = func(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// IsRemote reports whether a bucket source must be cloned rather than read in
+// place.
+func IsRemote(source string) bool {
+	if info, err := os.Stat(source); err == nil && info.IsDir() {
+		return false
+	}
+	return strings.Contains(source, "://") || strings.HasPrefix(source, "git@") || strings.HasSuffix(source, ".git")
+}
+
+// LocalDir is the directory holding a bucket's checkout: the source itself
+// for local directories, buckets/<name> under root for cloned ones.
+func LocalDir(root string, b config.Bucket) string {
+	if IsRemote(b.Source) {
+		return filepath.Join(root, "buckets", b.Name)
+	}
+	return b.Source
+}
+
+// ManifestDir returns where a bucket keeps its manifests. Scoop-style buckets
+// use a "bucket" sub-directory; plain collections keep them at the top.
+func ManifestDir(root string, b config.Bucket) string {
+	dir := LocalDir(root, b)
+	if info, err := os.Stat(filepath.Join(dir, "bucket")); err == nil && info.IsDir() {
+		return filepath.Join(dir, "bucket")
+	}
+	return dir
+}
+
+// AddBucket registers a bucket in config.yaml, cloning remote sources into
+// buckets/<name>. Local directories are stored as absolute paths.
+func AddBucket(root, name, source string) (config.Bucket, error) {
+	if !bucketNamePattern.MatchString(name) {
+		return config.Bucket{}, fmt.Errorf("invalid bucket name %q", name)
+	}
+	cfg, err := config.Load(root)
+	if err != nil {
+		return config.Bucket{}, err
+	}
+	if _, exists := cfg.FindBucket(name); exists {
+		return config.Bucket{}, fmt.Errorf("bucket %q is already registered", name)
+	}
+	b := config.Bucket{Name: name, Source: source}
+	if IsRemote(source) {
+		dest := LocalDir(root, b)
+		if _, err := os.Stat(dest); err == nil {
+			return config.Bucket{}, fmt.Errorf("bucket directory already exists: %s", dest)
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+			return config.Bucket{}, fmt.Errorf("create buckets dir: %w", err)
+		}
+		if err := runGit("", "clone", "--depth", "1", source, dest); err != nil {
+			return config.Bucket{}, fmt.Errorf("clone bucket %q: %w", name, err)
+		}
+	} else {
+		abs, err := filepath.Abs(source)
+		if err != nil {
+			return config.Bucket{}, fmt.Errorf("resolve bucket path: %w", err)
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return config.Bucket{}, fmt.Errorf("bucket path: %w", err)
+		}
+		if !info.IsDir() {
+			return config.Bucket{}, fmt.Errorf("bucket path is not a directory: %s", abs)
+		}
+		b.Source = abs
+	}
+	if err := config.SetValue(root, config.BucketKey(name), b.Source); err != nil {
+		return config.Bucket{}, fmt.Errorf("register bucket: %w", err)
+	}
+	return b, nil
+}
+
+// RemoveBucket unregisters a bucket and deletes its clone, if it has one.
+// Manifests already copied into manifests/ are left alone.
+func RemoveBucket(root, name string) error {
+	cfg, err := config.Load(root)
+	if err != nil {
+		return err
+	}
+	b, ok := cfg.FindBucket(name)
+	if !ok {
+		return fmt.Errorf("bucket %q is not registered", name)
+	}
+	if _, err := config.RemoveKey(root, config.BucketKey(name)); err != nil {
+		return fmt.Errorf("unregister bucket: %w", err)
+	}
+	if IsRemote(b.Source) {
+		if err := os.RemoveAll(LocalDir(root, b)); err != nil {
+			return fmt.Errorf("remove bucket clone: %w", err)
+		}
+	}
+	return nil
+}
+
+// RefreshBucket pulls the latest commits for cloned buckets. Local
+// directories are read as they are.
+func RefreshBucket(root string, b config.Bucket) error {
+	if !IsRemote(b.Source) {
+		return nil
+	}
+	dir := LocalDir(root, b)
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return runGit("", "clone", "--depth", "1", b.Source, dir)
+	}
+	return runGit(dir, "pull", "--ff-only")
+}
+
+// BucketManifestPath locates <app>.json inside a bucket.
+func BucketManifestPath(root string, b config.Bucket, app string) (string, error) {
+	path := filepath.Join(ManifestDir(root, b), app+".json")
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("app %q not found in bucket %q", app, b.Name)
+		}
+		return "", err
+	}
+	return path, nil
+}
+
+// BucketApps lists the app names a bucket provides, sorted.
+func BucketApps(root string, b config.Bucket) ([]string, error) {
+	entries, err := os.ReadDir(ManifestDir(root, b))
+	if err != nil {
+		return nil, fmt.Errorf("read bucket %q: %w", b.Name, err)
+	}
+	var apps []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || !strings.EqualFold(filepath.Ext(name), ".json") {
+			continue
+		}
+		apps = append(apps, strings.TrimSuffix(name, filepath.Ext(name)))
+	}
+	sort.Strings(apps)
+	return apps, nil
+}
+
+// SplitBucketRef parses "<bucket>/<app>". A reference ending in .json is a
+// file path, not a bucket reference.
+func SplitBucketRef(ref string) (string, string, bool) {
+	if strings.EqualFold(filepath.Ext(ref), ".json") {
+		return "", "", false
+	}
+	bucketName, app, ok := strings.Cut(ref, "/")
+	if !ok || !bucketNamePattern.MatchString(bucketName) || app == "" || strings.ContainsAny(app, `/\`) {
+		return "", "", false
+	}
+	return bucketName, app, true
+}
@@ -0,0 +1,161 @@
+package catalog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"appstract/internal/config"
+)
+
+const validManifest = `{"version": "%s", "bin": "app.exe", "architecture": {"64bit": {"url": "https://example.com/app.zip", "hash": "abc"}}}`
+
+func writeManifest(t *testing.T, path, version string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Replace(validManifest, "%s", version, 1)), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+}
+
+func TestAddBucketLocalDirectoryWithBucketSubdir(t *testing.T) {
+	root := t.TempDir()
+	src := t.TempDir()
+	writeManifest(t, filepath.Join(src, "bucket", "tool.json"), "1.0")
+	writeManifest(t, filepath.Join(src, "bucket", "editor.json"), "2.0")
+
+	b, err := AddBucket(root, "main", src)
+	if err != nil {
+		t.Fatalf("AddBucket failed: %v", err)
+	}
+	cfg, err := config.Load(root)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if got, ok := cfg.FindBucket("main"); !ok || got.Source != b.Source {
+		t.Fatalf("expected bucket registered in config, got %+v", cfg.Buckets)
+	}
+	apps, err := BucketApps(root, b)
+	if err != nil || strings.Join(apps, ",") != "editor,tool" {
+		t.Fatalf("unexpected apps: %v (%v)", apps, err)
+	}
+	if _, err := BucketManifestPath(root, b, "missing"); err == nil {
+		t.Fatalf("expected missing app error")
+	}
+	if _, err := AddBucket(root, "main", src); err == nil {
+		t.Fatalf("expected duplicate bucket error")
+	}
+	if _, err := AddBucket(root, "bad/name", src); err == nil {
+		t.Fatalf("expected invalid name error")
+	}
+}
+
+func TestAddBucketClonesRemoteSource(t *testing.T) {
+	root := t.TempDir()
+	var calls [][]string
+	original := runGit
+	runGit = func(dir string, args ...string) error {
+		calls = append(calls, append([]string{dir}, args...))
+		if args[0] == "clone" {
+			writeManifest(t, filepath.Join(args[len(args)-1], "tool.json"), "1.0")
+		}
+		return nil
+	}
+	defer func() { runGit = original }()
+
+	b, err := AddBucket(root, "extras", "https://example.com/extras.git")
+	if err != nil {
+		t.Fatalf("AddBucket failed: %v", err)
+	}
+	if LocalDir(root, b) != filepath.Join(root, "buckets", "extras") {
+		t.Fatalf("unexpected clone dir: %s", LocalDir(root, b))
+	}
+	if err := RefreshBucket(root, b); err != nil {
+		t.Fatalf("RefreshBucket failed: %v", err)
+	}
+	if len(calls) != 2 || calls[0][1] != "clone" || calls[1][0] != LocalDir(root, b) || calls[1][1] != "pull" {
+		t.Fatalf("unexpected git calls: %v", calls)
+	}
+	if err := RemoveBucket(root, "extras"); err != nil {
+		t.Fatalf("RemoveBucket failed: %v", err)
+	}
+	if _, err := os.Stat(LocalDir(root, b)); !os.IsNotExist(err) {
+		t.Fatalf("expected clone to be removed: %v", err)
+	}
+}
+
+func TestSyncBucketManifestsCopiesChangedManifests(t *testing.T) {
+	root := t.TempDir()
+	src := t.TempDir()
+	writeManifest(t, filepath.Join(src, "tool.json"), "1.0")
+	writeManifest(t, filepath.Join(src, "broken.json"), "")
+	b, err := AddBucket(root, "main", src)
+	if err != nil {
+		t.Fatalf("AddBucket failed: %v", err)
+	}
+	writeManifest(t, filepath.Join(root, "manifests", "tool.json"), "1.0")
+	for app, origin := range map[string]Origin{
+		"tool":   {Bucket: b.Name},
+		"broken": {Bucket: b.Name},
+		"gone":   {Bucket: "removed"},
+		"local":  {File: "/tmp/local.json"},
+	} {
+		if err := RecordOrigin(root, app, origin); err != nil {
+			t.Fatalf("RecordOrigin failed: %v", err)
+		}
+	}
+	cfg, err := config.Load(root)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	results, err := SyncBucketManifests(root, cfg, "2026-01-01T00:00:00Z")
+	if err != nil {
+		t.Fatalf("SyncBucketManifests failed: %v", err)
+	}
+	if len(results) != 3 || results[2].App != "tool" || results[2].Changed || results[2].Err != nil {
+		t.Fatalf("expected unchanged tool and two failures, got %+v", results)
+	}
+	if results[0].App != "broken" || results[0].Err == nil || results[1].App != "gone" || results[1].Err == nil {
+		t.Fatalf("expected broken and gone to fail, got %+v", results)
+	}
+
+	writeManifest(t, filepath.Join(src, "tool.json"), "1.1")
+	results, err = SyncBucketManifests(root, cfg, "2026-01-02T00:00:00Z")
+	if err != nil {
+		t.Fatalf("SyncBucketManifests failed: %v", err)
+	}
+	if !results[2].Changed {
+		t.Fatalf("expected tool to be refreshed, got %+v", results[2])
+	}
+	saved, err := os.ReadFile(filepath.Join(root, "manifests", "tool.json"))
+	if err != nil || !strings.Contains(string(saved), `"1.1"`) {
+		t.Fatalf("expected refreshed manifest, got %s (%v)", saved, err)
+	}
+	origins, err := LoadOrigins(root)
+	if err != nil {
+		t.Fatalf("LoadOrigins failed: %v", err)
+	}
+	if origins["tool"].UpdatedAt != "2026-01-02T00:00:00Z" {
+		t.Fatalf("expected refresh time recorded, got %+v", origins["tool"])
+	}
+}
+
+func TestSplitBucketRef(t *testing.T) {
+	cases := map[string]bool{
+		"main/chrome":           true,
+		"chrome.json":           false,
+		"dir/chrome.json":       false,
+		"main/sub/chrome":       false,
+		"chrome":                false,
+		`C:\manifests\app.JSON`: false,
+	}
+	for ref, want := range cases {
+		if _, _, ok := SplitBucketRef(ref); ok != want {
+			t.Fatalf("SplitBucketRef(%q) = %v, want %v", ref, ok, want)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package catalog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"appstract/internal/manifest"
+)
+
+// CycleError reports a dependency cycle. Cycle starts and ends with the same
+// app, e.g. [a b a].
+type CycleError struct {
+	Cycle []string
+}
+
+func (e *CycleError) Error() string {
+	return "dependency cycle: " + strings.Join(e.Cycle, " -> ")
+}
+
+// DependsFunc returns the direct dependencies of an app.
+type DependsFunc func(app string) ([]string, error)
+
+// InstallOrder walks the dependencies of app depth-first and returns every
+// app that has to be present, dependencies before their dependents and app
+// itself last.
+func InstallOrder(app string, depends DependsFunc) ([]string, error) {
+	var order []string
+	done := map[string]bool{}
+	var stack []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		key := strings.ToLower(name)
+		if done[key] {
+			return nil
+		}
+		for i, onStack := range stack {
+			if strings.EqualFold(onStack, name) {
+				return &CycleError{Cycle: append(append([]string{}, stack[i:]...), name)}
+			}
+		}
+		stack = append(stack, name)
+		deps, err := depends(name)
+		if err != nil {
+			return err
+		}
+		for _, dep := range deps {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		done[key] = true
+		order = append(order, name)
+		return nil
+	}
+	if err := visit(app); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// OrderByDependencies sorts apps so that every app comes after the apps it
+// depends on. Dependencies outside apps are ignored; otherwise the input order
+// is kept, which keeps the result stable for independent apps.
+func OrderByDependencies(apps []string, depends map[string][]string) ([]string, error) {
+	index := make(map[string]string, len(apps))
+	for _, app := range apps {
+		index[strings.ToLower(app)] = app
+	}
+	inSet := func(dep string) (string, bool) {
+		name, ok := index[strings.ToLower(dep)]
+		return name, ok
+	}
+	return InstallOrderAll(apps, func(app string) ([]string, error) {
+		var deps []string
+		for _, dep := range depends[app] {
+			if name, ok := inSet(dep); ok {
+				deps = append(deps, name)
+			}
+		}
+		return deps, nil
+	})
+}
+
+// InstallOrderAll is InstallOrder for several roots; each app appears once.
+func InstallOrderAll(apps []string, depends DependsFunc) ([]string, error) {
+	var order []string
+	seen := map[string]bool{}
+	for _, app := range apps {
+		sub, err := InstallOrder(app, depends)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range sub {
+			key := strings.ToLower(name)
+			if !seen[key] {
+				seen[key] = true
+				order = append(order, name)
+			}
+		}
+	}
+	return order, nil
+}
+
+// ManifestDepends reads the depends list of manifests/<app>.json.
+func ManifestDepends(root, app string) ([]string, error) {
+	m, err := manifest.ParseFile(filepath.Join(root, "manifests", app+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("manifest %s: %w", app, err)
+	}
+	return m.Depends, nil
+}
+
+// Dependents lists the apps in manifests/ that declare app as a dependency.
+// Manifests that fail to parse are skipped; they cannot be installed anyway.
+func Dependents(root, app string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(root, "manifests"))
+	if err != nil {
+		return nil, err
+	}
+	var dependents []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || !strings.EqualFold(filepath.Ext(name), ".json") {
+			continue
+		}
+		other := strings.TrimSuffix(name, filepath.Ext(name))
+		if strings.EqualFold(other, app) {
+			continue
+		}
+		deps, err := ManifestDepends(root, other)
+		if err != nil {
+			continue
+		}
+		for _, dep := range deps {
+			if strings.EqualFold(dep, app) {
+				dependents = append(dependents, other)
+				break
+			}
+		}
+	}
+	sort.Strings(dependents)
+	return dependents, nil
+}
@@ -0,0 +1,79 @@
+package catalog
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func dependsOf(graph map[string][]string) DependsFunc {
+	return func(app string) ([]string, error) {
+		return graph[app], nil
+	}
+}
+
+func TestInstallOrderPutsDependenciesFirst(t *testing.T) {
+	graph := map[string][]string{
+		"ide":  {"jre", "git"},
+		"git":  {"vcrt"},
+		"jre":  {"vcrt"},
+		"vcrt": nil,
+	}
+	order, err := InstallOrder("ide", dependsOf(graph))
+	if err != nil {
+		t.Fatalf("InstallOrder failed: %v", err)
+	}
+	if got := strings.Join(order, ","); got != "vcrt,jre,git,ide" {
+		t.Fatalf("unexpected order: %s", got)
+	}
+}
+
+func TestInstallOrderReportsCycle(t *testing.T) {
+	graph := map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+		"c": {"a"},
+	}
+	_, err := InstallOrder("a", dependsOf(graph))
+	var cycle *CycleError
+	if !errors.As(err, &cycle) {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+	if err.Error() != "dependency cycle: a -> b -> c -> a" {
+		t.Fatalf("unexpected cycle message: %v", err)
+	}
+}
+
+func TestOrderByDependenciesKeepsInputOrderOtherwise(t *testing.T) {
+	order, err := OrderByDependencies(
+		[]string{"alpha", "ide", "jre", "zeta"},
+		map[string][]string{"ide": {"JRE", "missing"}},
+	)
+	if err != nil {
+		t.Fatalf("OrderByDependencies failed: %v", err)
+	}
+	if got := strings.Join(order, ","); got != "alpha,jre,ide,zeta" {
+		t.Fatalf("unexpected order: %s", got)
+	}
+}
+
+func TestDependents(t *testing.T) {
+	root := t.TempDir()
+	writeManifest(t, filepath.Join(root, "manifests", "jre.json"), "1.0")
+	withDepends := strings.Replace(strings.Replace(validManifest, "%s", "1.0", 1), `"bin"`, `"depends": ["JRE"], "bin"`, 1)
+	if err := os.WriteFile(filepath.Join(root, "manifests", "ide.json"), []byte(withDepends), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	dependents, err := Dependents(root, "jre")
+	if err != nil {
+		t.Fatalf("Dependents failed: %v", err)
+	}
+	if strings.Join(dependents, ",") != "ide" {
+		t.Fatalf("unexpected dependents: %v", dependents)
+	}
+	if dependents, _ := Dependents(root, "ide"); len(dependents) != 0 {
+		t.Fatalf("expected no dependents of ide, got %v", dependents)
+	}
+}
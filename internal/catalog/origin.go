@@ -0,0 +1,165 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"appstract/internal/config"
+	"appstract/internal/manifest"
+)
+
+// originsFile sits next to the manifests it describes. The leading dot keeps
+// it out of the manifests/*.json scan.
+const originsFile = ".origins.json"
+
+// Origin records where manifests/<app>.json was copied from. SHA256 pins the
+// manifest content: a refresh that yields different bytes is rejected.
+type Origin struct {
+	Bucket    string `json:"bucket,omitempty"`
+	File      string `json:"file,omitempty"`
+	URL       string `json:"url,omitempty"`
+	SHA256    string `json:"sha256,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+}
+
+func originsPath(root string) string {
+	return filepath.Join(root, "manifests", originsFile)
+}
+
+func LoadOrigins(root string) (map[string]Origin, error) {
+	origins := map[string]Origin{}
+	b, err := os.ReadFile(originsPath(root))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return origins, nil
+		}
+		return nil, fmt.Errorf("read manifest origins: %w", err)
+	}
+	if err := json.Unmarshal(b, &origins); err != nil {
+		return nil, fmt.Errorf("decode manifest origins: %w", err)
+	}
+	return origins, nil
+}
+
+func SaveOrigins(root string, origins map[string]Origin) error {
+	b, err := json.MarshalIndent(origins, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(originsPath(root)), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(originsPath(root), append(b, '\n'), 0o644)
+}
+
+// RecordOrigin stores the origin of one app, replacing any previous record.
+func RecordOrigin(root, app string, origin Origin) error {
+	origins, err := LoadOrigins(root)
+	if err != nil {
+		return err
+	}
+	origins[app] = origin
+	return SaveOrigins(root, origins)
+}
+
+// ForgetOrigin drops the origin record of a removed app.
+func ForgetOrigin(root, app string) error {
+	origins, err := LoadOrigins(root)
+	if err != nil {
+		return err
+	}
+	if _, ok := origins[app]; !ok {
+		return nil
+	}
+	delete(origins, app)
+	return SaveOrigins(root, origins)
+}
+
+// SyncResult describes what refreshing one bucket-backed manifest did.
+type SyncResult struct {
+	App     string
+	Bucket  string
+	Changed bool
+	Err     error
+}
+
+// SyncBucketManifests refreshes every bucket that apps were added from and
+// copies changed, valid manifests into manifests/. Failures are reported per
+// app so one broken bucket does not block the rest.
+func SyncBucketManifests(root string, cfg config.Config, now string) ([]SyncResult, error) {
+	origins, err := LoadOrigins(root)
+	if err != nil {
+		return nil, err
+	}
+	apps := make([]string, 0, len(origins))
+	for app, origin := range origins {
+		if origin.Bucket != "" {
+			apps = append(apps, app)
+		}
+	}
+	sort.Strings(apps)
+
+	refreshed := map[string]error{}
+	var results []SyncResult
+	dirty := false
+	for _, app := range apps {
+		origin := origins[app]
+		result := SyncResult{App: app, Bucket: origin.Bucket}
+		b, ok := cfg.FindBucket(origin.Bucket)
+		if !ok {
+			result.Err = fmt.Errorf("bucket %q is no longer registered", origin.Bucket)
+			results = append(results, result)
+			continue
+		}
+		refreshErr, done := refreshed[b.Name]
+		if !done {
+			refreshErr = RefreshBucket(root, b)
+			refreshed[b.Name] = refreshErr
+		}
+		if refreshErr != nil {
+			result.Err = fmt.Errorf("refresh bucket %q: %w", b.Name, refreshErr)
+			results = append(results, result)
+			continue
+		}
+		result.Changed, result.Err = syncManifest(root, b, app)
+		if result.Changed {
+			origin.UpdatedAt = now
+			origins[app] = origin
+			dirty = true
+		}
+		results = append(results, result)
+	}
+	if dirty {
+		if err := SaveOrigins(root, origins); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+func syncManifest(root string, b config.Bucket, app string) (bool, error) {
+	source, err := BucketManifestPath(root, b, app)
+	if err != nil {
+		return false, err
+	}
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return false, err
+	}
+	target := filepath.Join(root, "manifests", app+".json")
+	if current, err := os.ReadFile(target); err == nil && bytes.Equal(current, data) {
+		return false, nil
+	}
+	if _, err := manifest.ParseBytes(data); err != nil {
+		return false, fmt.Errorf("bucket manifest %s is invalid: %w", source, err)
+	}
+	if err := os.WriteFile(target, data, 0o644); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,157 @@
+package catalog
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"appstract/internal/manifest"
+)
+
+// maxManifestBytes bounds a downloaded manifest; real ones are a few KB.
+const maxManifestBytes = 4 << 20
+
+// IsManifestURL reports whether an add argument names a remote manifest.
+func IsManifestURL(ref string) bool {
+	lower := strings.ToLower(ref)
+	return strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://")
+}
+
+// AppNameFromURL derives the app name from the last path segment of a
+// manifest URL, e.g. https://host/bucket/chrome.json -> chrome.
+func AppNameFromURL(raw string) (string, error) {
+	u, err := neturl.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid manifest url: %w", err)
+	}
+	base := path.Base(u.Path)
+	ext := path.Ext(base)
+	if !strings.EqualFold(ext, ".json") {
+		return "", fmt.Errorf("cannot derive app name from manifest url (use --name): %s", raw)
+	}
+	app := strings.TrimSuffix(base, ext)
+	if strings.TrimSpace(app) == "" {
+		return "", fmt.Errorf("cannot derive app name from manifest url (use --name): %s", raw)
+	}
+	return app, nil
+}
+
+// FetchManifest downloads a manifest over https.
+func FetchManifest(client *http.Client, raw string) ([]byte, error) {
+	u, err := neturl.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest url: %w", err)
+	}
+	if !strings.EqualFold(u.Scheme, "https") {
+		return nil, fmt.Errorf("insecure manifest url scheme %q", u.Scheme)
+	}
+	resp, err := client.Get(raw)
+	if err != nil {
+		return nil, fmt.Errorf("fetch manifest: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("fetch manifest: http status %d", resp.StatusCode)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("fetch manifest: %w", err)
+	}
+	if len(b) > maxManifestBytes {
+		return nil, fmt.Errorf("fetch manifest: larger than %d bytes", maxManifestBytes)
+	}
+	return b, nil
+}
+
+// CheckPin verifies content against a sha256 pin; an empty pin accepts
+// anything.
+func CheckPin(content []byte, pin string) error {
+	if pin == "" {
+		return nil
+	}
+	sum := sha256.Sum256(content)
+	actual := hex.EncodeToString(sum[:])
+	expected := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(pin)), "sha256:")
+	if actual != expected {
+		return fmt.Errorf("manifest sha256 mismatch: pinned %s got %s", expected, actual)
+	}
+	return nil
+}
+
+// URLRefresh is the outcome of re-fetching one URL-backed manifest. Fetched
+// is set only when the content differs from Current and passed validation.
+type URLRefresh struct {
+	App     string
+	URL     string
+	Current []byte
+	Fetched []byte
+	Err     error
+}
+
+func (r URLRefresh) Changed() bool {
+	return r.Err == nil && r.Fetched != nil
+}
+
+// FetchURLManifests re-downloads every manifest that was added from a URL.
+// Nothing is written; callers review each change and call AcceptRefresh.
+func FetchURLManifests(root string, fetch func(url string) ([]byte, error)) ([]URLRefresh, error) {
+	origins, err := LoadOrigins(root)
+	if err != nil {
+		return nil, err
+	}
+	apps := make([]string, 0, len(origins))
+	for app, origin := range origins {
+		if origin.URL != "" {
+			apps = append(apps, app)
+		}
+	}
+	sort.Strings(apps)
+
+	results := make([]URLRefresh, 0, len(apps))
+	for _, app := range apps {
+		origin := origins[app]
+		result := URLRefresh{App: app, URL: origin.URL}
+		result.Current, _ = os.ReadFile(filepath.Join(root, "manifests", app+".json"))
+		fetched, err := fetch(origin.URL)
+		switch {
+		case err != nil:
+			result.Err = err
+		case bytes.Equal(fetched, result.Current):
+		default:
+			if err := CheckPin(fetched, origin.SHA256); err != nil {
+				result.Err = err
+			} else if _, err := manifest.ParseBytes(fetched); err != nil {
+				result.Err = fmt.Errorf("fetched manifest is invalid: %w", err)
+			} else {
+				result.Fetched = fetched
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// AcceptRefresh writes a reviewed manifest into manifests/ and stamps its
+// origin.
+func AcceptRefresh(root, app string, content []byte, now string) error {
+	if err := os.WriteFile(filepath.Join(root, "manifests", app+".json"), content, 0o644); err != nil {
+		return err
+	}
+	origins, err := LoadOrigins(root)
+	if err != nil {
+		return err
+	}
+	origin := origins[app]
+	origin.UpdatedAt = now
+	origins[app] = origin
+	return SaveOrigins(root, origins)
+}
@@ -0,0 +1,112 @@
+package catalog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAppNameFromURL(t *testing.T) {
+	app, err := AppNameFromURL("https://example.com/bucket/Chrome.json?raw=1")
+	if err != nil || app != "Chrome" {
+		t.Fatalf("expected Chrome, got %q (%v)", app, err)
+	}
+	if _, err := AppNameFromURL("https://example.com/manifest"); err == nil || !strings.Contains(err.Error(), "--name") {
+		t.Fatalf("expected --name hint, got %v", err)
+	}
+}
+
+func TestFetchManifestRequiresHTTPS(t *testing.T) {
+	_, err := FetchManifest(http.DefaultClient, "http://example.com/app.json")
+	if err == nil || !strings.Contains(err.Error(), "insecure manifest url scheme") {
+		t.Fatalf("expected insecure scheme error, got %v", err)
+	}
+}
+
+func TestFetchManifestFromServer(t *testing.T) {
+	body := strings.Replace(validManifest, "%s", "1.0", 1)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/app.json" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	defer srv.Close()
+
+	got, err := FetchManifest(srv.Client(), srv.URL+"/app.json")
+	if err != nil || string(got) != body {
+		t.Fatalf("unexpected fetch result %q (%v)", got, err)
+	}
+	if _, err := FetchManifest(srv.Client(), srv.URL+"/missing.json"); err == nil || !strings.Contains(err.Error(), "http status 404") {
+		t.Fatalf("expected status error, got %v", err)
+	}
+}
+
+func TestCheckPin(t *testing.T) {
+	content := []byte("manifest")
+	sum := sha256.Sum256(content)
+	pin := hex.EncodeToString(sum[:])
+	if err := CheckPin(content, ""); err != nil {
+		t.Fatalf("empty pin should accept: %v", err)
+	}
+	if err := CheckPin(content, "SHA256:"+strings.ToUpper(pin)); err != nil {
+		t.Fatalf("expected pin match: %v", err)
+	}
+	if err := CheckPin([]byte("other"), pin); err == nil || !strings.Contains(err.Error(), "sha256 mismatch") {
+		t.Fatalf("expected mismatch, got %v", err)
+	}
+}
+
+func TestFetchURLManifestsReportsChangesWithoutWriting(t *testing.T) {
+	root := t.TempDir()
+	current := filepath.Join(root, "manifests", "tool.json")
+	writeManifest(t, current, "1.0")
+	writeManifest(t, filepath.Join(root, "manifests", "pinned.json"), "1.0")
+	writeManifest(t, filepath.Join(root, "manifests", "local.json"), "1.0")
+	if err := SaveOrigins(root, map[string]Origin{
+		"tool":   {URL: "https://example.com/tool.json"},
+		"pinned": {URL: "https://example.com/pinned.json", SHA256: strings.Repeat("0", 64)},
+		"local":  {File: "/src/local.json"},
+	}); err != nil {
+		t.Fatalf("SaveOrigins failed: %v", err)
+	}
+	next := strings.Replace(validManifest, "%s", "2.0", 1)
+	var fetched []string
+	results, err := FetchURLManifests(root, func(url string) ([]byte, error) {
+		fetched = append(fetched, url)
+		return []byte(next), nil
+	})
+	if err != nil {
+		t.Fatalf("FetchURLManifests failed: %v", err)
+	}
+	if len(results) != 2 || len(fetched) != 2 {
+		t.Fatalf("expected only url origins fetched, got %+v", results)
+	}
+	if results[0].App != "pinned" || results[0].Err == nil || !strings.Contains(results[0].Err.Error(), "sha256 mismatch") {
+		t.Fatalf("expected pin mismatch for pinned, got %+v", results[0])
+	}
+	if results[1].App != "tool" || !results[1].Changed() || string(results[1].Fetched) != next {
+		t.Fatalf("expected tool changed, got %+v", results[1])
+	}
+	if b, _ := os.ReadFile(current); strings.Contains(string(b), "2.0") {
+		t.Fatalf("manifest must not be written before acceptance")
+	}
+
+	if err := AcceptRefresh(root, "tool", results[1].Fetched, "2026-01-02T03:04:05Z"); err != nil {
+		t.Fatalf("AcceptRefresh failed: %v", err)
+	}
+	if b, _ := os.ReadFile(current); string(b) != next {
+		t.Fatalf("expected accepted manifest written, got %s", b)
+	}
+	origins, err := LoadOrigins(root)
+	if err != nil || origins["tool"].UpdatedAt != "2026-01-02T03:04:05Z" || origins["tool"].URL == "" {
+		t.Fatalf("unexpected origin after accept: %+v (%v)", origins["tool"], err)
+	}
+}
@@ -0,0 +1,214 @@
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"appstract/internal/config"
+	"appstract/internal/manifest"
+)
+
+// LocalSource names the root manifests/ directory in search results.
+const LocalSource = "local"
+
+const searchIndexVersion = 1
+
+// IndexEntry is the searchable summary of one manifest file. ModTime and Size
+// decide whether the cached entry is still current; Error keeps a broken
+// manifest from being re-parsed until it changes.
+type IndexEntry struct {
+	App         string `json:"app"`
+	Source      string `json:"source"`
+	Path        string `json:"path"`
+	Version     string `json:"version,omitempty"`
+	Description string `json:"description,omitempty"`
+	Bin         string `json:"bin,omitempty"`
+	ModTime     int64  `json:"mtime"`
+	Size        int64  `json:"size"`
+	Error       string `json:"error,omitempty"`
+}
+
+type searchIndex struct {
+	Version int                   `json:"version"`
+	Entries map[string]IndexEntry `json:"entries"`
+}
+
+func searchIndexPath(root string) string {
+	return filepath.Join(root, "cache", "search-index.json")
+}
+
+type manifestSource struct {
+	name string
+	dir  string
+}
+
+// manifestSources lists every directory that provides manifests: the root
+// manifests/ directory first, then each bucket in config order.
+func manifestSources(root string, cfg config.Config) []manifestSource {
+	sources := []manifestSource{{name: LocalSource, dir: filepath.Join(root, "manifests")}}
+	for _, b := range cfg.Buckets {
+		sources = append(sources, manifestSource{name: b.Name, dir: ManifestDir(root, b)})
+	}
+	return sources
+}
+
+// BuildIndex brings the cached search index up to date and returns its
+// entries. Only manifests whose mtime or size changed since the last run are
+// parsed again; entries for deleted files are dropped.
+func BuildIndex(root string, cfg config.Config) ([]IndexEntry, error) {
+	index := loadSearchIndex(root)
+	fresh := make(map[string]IndexEntry, len(index.Entries))
+	changed := false
+	for _, source := range manifestSources(root, cfg) {
+		entries, err := os.ReadDir(source.dir)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("read manifest source %s: %w", source.dir, err)
+		}
+		for _, dirEntry := range entries {
+			name := dirEntry.Name()
+			if dirEntry.IsDir() || strings.HasPrefix(name, ".") || !strings.EqualFold(filepath.Ext(name), ".json") {
+				continue
+			}
+			info, err := dirEntry.Info()
+			if err != nil {
+				continue
+			}
+			path := filepath.Join(source.dir, name)
+			key := source.name + "|" + path
+			cached, ok := index.Entries[key]
+			if ok && cached.ModTime == info.ModTime().UnixNano() && cached.Size == info.Size() {
+				fresh[key] = cached
+				continue
+			}
+			fresh[key] = indexManifest(source.name, path, info)
+			changed = true
+		}
+	}
+	if len(fresh) != len(index.Entries) {
+		changed = true
+	}
+	if changed {
+		index.Entries = fresh
+		if err := saveSearchIndex(root, index); err != nil {
+			return nil, err
+		}
+	}
+
+	out := make([]IndexEntry, 0, len(fresh))
+	for _, entry := range fresh {
+		out = append(out, entry)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].App != out[j].App {
+			return out[i].App < out[j].App
+		}
+		return out[i].Source < out[j].Source
+	})
+	return out, nil
+}
+
+func indexManifest(source, path string, info os.FileInfo) IndexEntry {
+	name := info.Name()
+	entry := IndexEntry{
+		App:     strings.TrimSuffix(name, filepath.Ext(name)),
+		Source:  source,
+		Path:    path,
+		ModTime: info.ModTime().UnixNano(),
+		Size:    info.Size(),
+	}
+	m, err := manifest.ParseFile(path)
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	entry.Version = m.Version
+	entry.Description = m.Description
+	entry.Bin = m.Bin
+	return entry
+}
+
+// loadSearchIndex never fails: a missing, corrupt or outdated index simply
+// starts empty and is rebuilt.
+func loadSearchIndex(root string) searchIndex {
+	empty := searchIndex{Version: searchIndexVersion, Entries: map[string]IndexEntry{}}
+	b, err := os.ReadFile(searchIndexPath(root))
+	if err != nil {
+		return empty
+	}
+	var index searchIndex
+	if err := json.Unmarshal(b, &index); err != nil || index.Version != searchIndexVersion || index.Entries == nil {
+		return empty
+	}
+	return index
+}
+
+func saveSearchIndex(root string, index searchIndex) error {
+	path := searchIndexPath(root)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create cache dir: %w", err)
+	}
+	b, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		return fmt.Errorf("write search index: %w", err)
+	}
+	return nil
+}
+
+// Search returns the valid manifests whose app name, description or bin
+// contains query (case-insensitive). Name matches rank first: exact, then
+// prefix, then substring, then description or bin matches.
+func Search(root string, cfg config.Config, query string) ([]IndexEntry, error) {
+	entries, err := BuildIndex(root, cfg)
+	if err != nil {
+		return nil, err
+	}
+	q := strings.ToLower(strings.TrimSpace(query))
+	type ranked struct {
+		entry IndexEntry
+		rank  int
+	}
+	var matches []ranked
+	for _, entry := range entries {
+		if entry.Error != "" {
+			continue
+		}
+		if rank, ok := matchRank(entry, q); ok {
+			matches = append(matches, ranked{entry: entry, rank: rank})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].rank < matches[j].rank
+	})
+	out := make([]IndexEntry, len(matches))
+	for i, m := range matches {
+		out[i] = m.entry
+	}
+	return out, nil
+}
+
+func matchRank(entry IndexEntry, q string) (int, bool) {
+	name := strings.ToLower(entry.App)
+	switch {
+	case q == "" || name == q:
+		return 0, true
+	case strings.HasPrefix(name, q):
+		return 1, true
+	case strings.Contains(name, q):
+		return 2, true
+	case strings.Contains(strings.ToLower(entry.Description), q), strings.Contains(strings.ToLower(entry.Bin), q):
+		return 3, true
+	default:
+		return 0, false
+	}
+}
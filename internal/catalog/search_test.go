@@ -0,0 +1,105 @@
+package catalog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"appstract/internal/config"
+)
+
+func writeDescribedManifest(t *testing.T, path, version, description, bin string) {
+	t.Helper()
+	content := `{"version": "` + version + `", "description": "` + description + `", "bin": "` + bin + `", "architecture": {"64bit": {"url": "https://example.com/a.zip", "hash": "abc"}}}`
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+}
+
+func TestSearchMatchesNameDescriptionAndBin(t *testing.T) {
+	root := t.TempDir()
+	bucketDir := t.TempDir()
+	writeDescribedManifest(t, filepath.Join(root, "manifests", "notepad.json"), "1.0", "Plain text editor", "notepad.exe")
+	writeDescribedManifest(t, filepath.Join(bucketDir, "editor.json"), "2.0", "Code editor", "code.exe")
+	writeDescribedManifest(t, filepath.Join(bucketDir, "vim.json"), "9.1", "Modal text editor", "gvim.exe")
+	writeDescribedManifest(t, filepath.Join(bucketDir, "curl.json"), "8.0", "Transfer tool", "curl.exe")
+	if err := os.WriteFile(filepath.Join(bucketDir, "broken.json"), []byte("{"), 0o644); err != nil {
+		t.Fatalf("write broken manifest: %v", err)
+	}
+	cfg := config.Config{Buckets: []config.Bucket{{Name: "main", Source: bucketDir}}}
+
+	results, err := Search(root, cfg, "editor")
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	var names []string
+	for _, r := range results {
+		names = append(names, r.Source+"/"+r.App)
+	}
+	if strings.Join(names, ",") != "main/editor,local/notepad,main/vim" {
+		t.Fatalf("unexpected results: %v", names)
+	}
+
+	results, err = Search(root, cfg, "GVIM")
+	if err != nil || len(results) != 1 || results[0].App != "vim" || results[0].Version != "9.1" {
+		t.Fatalf("expected bin match on vim, got %+v (%v)", results, err)
+	}
+}
+
+func TestBuildIndexReparsesOnlyChangedManifests(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "manifests", "tool.json")
+	writeDescribedManifest(t, path, "1.0", "Tool", "tool.exe")
+	cfg := config.Config{}
+
+	if _, err := BuildIndex(root, cfg); err != nil {
+		t.Fatalf("BuildIndex failed: %v", err)
+	}
+	indexPath := searchIndexPath(root)
+	first, err := os.Stat(indexPath)
+	if err != nil {
+		t.Fatalf("expected index file: %v", err)
+	}
+
+	// An untouched tree must not rewrite the index.
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(indexPath, past, past); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	if _, err := BuildIndex(root, cfg); err != nil {
+		t.Fatalf("BuildIndex failed: %v", err)
+	}
+	second, err := os.Stat(indexPath)
+	if err != nil {
+		t.Fatalf("stat index: %v", err)
+	}
+	if !second.ModTime().Equal(past) {
+		t.Fatalf("expected index to stay untouched, mtime %v -> %v", first.ModTime(), second.ModTime())
+	}
+
+	writeDescribedManifest(t, path, "1.1", "Tool", "tool.exe")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	entries, err := BuildIndex(root, cfg)
+	if err != nil {
+		t.Fatalf("BuildIndex failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Version != "1.1" {
+		t.Fatalf("expected refreshed entry, got %+v", entries)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("remove manifest: %v", err)
+	}
+	entries, err = BuildIndex(root, cfg)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("expected deleted manifest to leave the index, got %+v (%v)", entries, err)
+	}
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"appstract/internal/catalog"
+	"appstract/internal/config"
+)
+
+func executeBucket(args []string, stdout, stderr io.Writer, envHome string) (code int) {
+	fs := flag.NewFlagSet("bucket", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	rootFlag := fs.String("root", "", "Appstract root directory")
+	outputFlag := fs.String("output", "", "Output level: silent|default|debug|json")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			printCommandUsage("bucket", stdout)
+			return 0
+		}
+		return 1
+	}
+	remain := fs.Args()
+	if len(remain) < 1 {
+		printCommandUsage("bucket", stderr)
+		return 1
+	}
+	switch {
+	case remain[0] == "add" && len(remain) == 3:
+	case remain[0] == "remove" && len(remain) == 2:
+	case remain[0] == "list" && len(remain) == 1:
+	default:
+		printCommandUsage("bucket", stderr)
+		return 1
+	}
+
+	root, executablePath, err := resolveRoot(envHome, *rootFlag)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	outputLevel, err := resolveOutputLevel(root, *outputFlag)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	output := newCommandOutput(outputLevel, stdout, stderr)
+	defer output.finish("bucket "+remain[0], &code)
+	if err := ensureWorkspaceReady(root, executablePath); err != nil {
+		output.printError("%v", err)
+		return 1
+	}
+
+	switch remain[0] {
+	case "add":
+		name, source := remain[1], remain[2]
+		if catalog.IsRemote(source) {
+			output.printDefault("cloning bucket %s from %s", name, source)
+		}
+		b, err := catalog.AddBucket(root, name, source)
+		if err != nil {
+			output.printError("%v", err)
+			return 1
+		}
+		apps, err := catalog.BucketApps(root, b)
+		if err != nil {
+			output.printError("%v", err)
+			return 1
+		}
+		output.printDefault("[ok] bucket added: %s (%d manifest(s)) %s", b.Name, len(apps), b.Source)
+	case "remove":
+		if err := catalog.RemoveBucket(root, remain[1]); err != nil {
+			output.printError("%v", err)
+			return 1
+		}
+		output.printDefault("[ok] bucket removed: %s", remain[1])
+	case "list":
+		cfg, err := config.Load(root)
+		if err != nil {
+			output.printError("%v", err)
+			return 1
+		}
+		if output.jsonMode() {
+			rows := make([]map[string]string, 0, len(cfg.Buckets))
+			for _, b := range cfg.Buckets {
+				rows = append(rows, map[string]string{"name": b.Name, "source": b.Source})
+			}
+			output.printResult("buckets", rows)
+			return 0
+		}
+		if len(cfg.Buckets) == 0 {
+			output.printDefault("no buckets registered")
+			return 0
+		}
+		for _, b := range cfg.Buckets {
+			fmt.Fprintf(stdout, "%s\t%s\n", b.Name, b.Source)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"appstract/internal/bootstrap"
+	"appstract/internal/catalog"
+)
+
+func TestExecuteBucketAddThenAddAppFromBucket(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	bucketDir := t.TempDir()
+	bucketManifest := filepath.Join(bucketDir, "chrome.json")
+	if err := os.WriteFile(bucketManifest, []byte(runManifestContent("chrome.exe")), 0o644); err != nil {
+		t.Fatalf("write bucket manifest failed: %v", err)
+	}
+
+	var out strings.Builder
+	var errOut strings.Builder
+	if code := Execute([]string{"bucket", "--root", root, "add", "main", bucketDir}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("bucket add failed: %d %s", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "bucket added: main (1 manifest(s))") {
+		t.Fatalf("unexpected stdout: %s", out.String())
+	}
+
+	oldUpdate := executeUpdateFromManifest
+	var updated []string
+	executeUpdateFromManifest = func(updateRoot, app, path string, opts updateOptions) error {
+		updated = append(updated, app)
+		return nil
+	}
+	t.Cleanup(func() { executeUpdateFromManifest = oldUpdate })
+
+	out.Reset()
+	errOut.Reset()
+	if code := Execute([]string{"add", "--root", root, "main/chrome"}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("add from bucket failed: %d %s", code, errOut.String())
+	}
+	origins, err := catalog.LoadOrigins(root)
+	if err != nil {
+		t.Fatalf("load origins failed: %v", err)
+	}
+	if origins["chrome"].Bucket != "main" {
+		t.Fatalf("expected bucket origin recorded, got %+v", origins)
+	}
+
+	// A new bucket revision is picked up by update before installing.
+	changed := strings.Replace(runManifestContent("chrome.exe"), `"1.2.3"`, `"1.3.0"`, 1)
+	if changed == runManifestContent("chrome.exe") {
+		t.Fatalf("test manifest has no version to bump")
+	}
+	if err := os.WriteFile(bucketManifest, []byte(changed), 0o644); err != nil {
+		t.Fatalf("write bucket manifest failed: %v", err)
+	}
+	out.Reset()
+	errOut.Reset()
+	if code := Execute([]string{"update", "--root", root}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("update failed: %d %s", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "manifest refreshed: chrome (bucket main)") {
+		t.Fatalf("expected refresh message: %s", out.String())
+	}
+	saved, err := os.ReadFile(filepath.Join(root, "manifests", "chrome.json"))
+	if err != nil || string(saved) != changed {
+		t.Fatalf("expected refreshed manifest, got %s (%v)", saved, err)
+	}
+	if strings.Join(updated, ",") != "chrome,chrome" {
+		t.Fatalf("unexpected update calls: %v", updated)
+	}
+}
+
+func TestExecuteAddUnknownBucket(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	var out strings.Builder
+	var errOut strings.Builder
+	code := Execute([]string{"add", "--root", root, "nope/chrome"}, &out, &errOut, "")
+	if code != 1 || !strings.Contains(errOut.String(), `bucket "nope" is not registered`) {
+		t.Fatalf("expected unknown bucket error, got %d %s", code, errOut.String())
+	}
+}
@@ -1,16 +1,24 @@
 package cli
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"appstract/internal/appenv"
 	"appstract/internal/bootstrap"
+	"appstract/internal/catalog"
 	"appstract/internal/config"
 	"appstract/internal/manifest"
 	"appstract/internal/updater"
@@ -20,7 +28,11 @@ type updateOptions struct {
 	Checkver     bool
 	PromptSwitch bool
 	Relaunch     bool
-	Output       *commandOutput
+	// DeferSwitch stages the update of a running app instead of stopping it.
+	DeferSwitch bool
+	// StageOnly stages new versions as pending; run switches to them.
+	StageOnly bool
+	Output    *commandOutput
 }
 
 var runLaunch = func(path string) error {
@@ -31,24 +43,100 @@ var runLaunch = func(path string) error {
 var resolveExecutablePath = os.Executable
 
 var executeUpdateFromManifest = func(root, app, manifestPath string, opts updateOptions) error {
+	manager, err := newManager(root, opts)
+	if err != nil {
+		return err
+	}
+	return manager.UpdateFromManifest(app, manifestPath)
+}
+
+// newManager returns an updater for root set up from config.yaml and opts.
+func newManager(root string, opts updateOptions) (*updater.Manager, error) {
 	manager := updater.NewManager(root)
 	manager.UseCheckver = opts.Checkver
 	manager.PromptSwitch = opts.PromptSwitch
 	manager.Relaunch = opts.Relaunch
+	manager.DeferSwitchIfRunning = opts.DeferSwitch
+	manager.StageOnly = opts.StageOnly
 	if opts.Output != nil {
 		manager.OnMessage = opts.Output.onUpdaterMessage
 		manager.OnProgress = opts.Output.onUpdaterProgress
+		manager.OnEvent = opts.Output.onUpdaterEvent
 	}
 	cfg, err := config.Load(root)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	manager.KeepVersions = cfg.KeepVersions
-	return manager.UpdateFromManifest(app, manifestPath)
+	manager.ExtractLimits = updater.ExtractLimits{
+		MaxBytes: cfg.ExtractMaxBytes,
+		MaxFiles: cfg.ExtractMaxFiles,
+		MaxRatio: cfg.ExtractMaxRatio,
+	}
+	manager.Architecture = cfg.Architecture
+	manager.EnvBackends = appenv.Backends(root, cfg.EnvBackend)
+	manager.LogRetention = updater.LogRetention{
+		MaxAgeDays:       cfg.LogRetentionDays,
+		MaxBytes:         cfg.LogRetentionBytes,
+		CompactAfterDays: cfg.LogCompactAfterDays,
+	}
+	return manager, nil
 }
 
-var runAsyncUpdate = func(root, app, manifestPath string, opts updateOptions) error {
-	return executeUpdateFromManifest(root, app, manifestPath, opts)
+var resolveManifestAutoupdate = func(man *manifest.Manifest, output *commandOutput) (updater.AutoupdateResult, error) {
+	manager := updater.NewManager("")
+	manager.OnMessage = output.onUpdaterMessage
+	manager.OnProgress = output.onUpdaterProgress
+	return manager.ResolveAutoupdate(man)
+}
+
+var fetchManifestURL = func(url string) ([]byte, error) {
+	return catalog.FetchManifest(&http.Client{Timeout: time.Minute}, url)
+}
+
+// interruptContext bounds the commands that run until Ctrl+C: daemon,
+// serve and log --follow. Tests replace it with a timeout.
+var interruptContext = func() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
+
+// confirmPrompt asks a yes/no question on the terminal. End of input counts
+// as "no" so unattended runs never accept changes silently.
+var confirmPrompt = func(question string) (bool, error) {
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", question)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "y" || answer == "yes", nil
+}
+
+// startBackgroundUpdate hands the update of app to a detached
+// `appstract update --app <app> --background`, which outlives run, and
+// returns its PID.
+var startBackgroundUpdate = func(root, app string) (int, error) {
+	executablePath, err := resolveExecutablePath()
+	if err != nil {
+		return 0, err
+	}
+	cmd := exec.Command(executablePath, "update", "--root", root, "--app", app, "--background")
+	detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	pid := cmd.Process.Pid
+	return pid, cmd.Process.Release()
+}
+
+// applyPendingUpdate switches app to the version a stage-only update left
+// pending, unless the app is running, and returns the version switched to.
+var applyPendingUpdate = func(root, app, bin string, opts updateOptions) (string, error) {
+	manager, err := newManager(root, opts)
+	if err != nil {
+		return "", err
+	}
+	return manager.ApplyPending(app, bin)
 }
 
 func Execute(args []string, stdout, stderr io.Writer, envHome string) int {
@@ -72,6 +160,26 @@ func Execute(args []string, stdout, stderr io.Writer, envHome string) int {
 		return executeAdd(args[1:], stdout, stderr, envHome)
 	case "update":
 		return executeUpdate(args[1:], stdout, stderr, envHome)
+	case "remove":
+		return executeRemove(args[1:], stdout, stderr, envHome)
+	case "bucket":
+		return executeBucket(args[1:], stdout, stderr, envHome)
+	case "search":
+		return executeSearch(args[1:], stdout, stderr, envHome)
+	case "log":
+		return executeLog(args[1:], stdout, stderr, envHome)
+	case "status":
+		return executeStatus(args[1:], stdout, stderr, envHome)
+	case "outdated":
+		return executeOutdated(args[1:], stdout, stderr, envHome)
+	case "history":
+		return executeHistory(args[1:], stdout, stderr, envHome)
+	case "report":
+		return executeReport(args[1:], stdout, stderr, envHome)
+	case "serve":
+		return executeServe(args[1:], stdout, stderr, envHome)
+	case "daemon":
+		return executeDaemon(args[1:], stdout, stderr, envHome)
 	default:
 		fmt.Fprintf(stderr, "unknown command: %s\n", args[0])
 		printGlobalUsage(stderr)
@@ -87,14 +195,38 @@ func printGlobalUsage(w io.Writer) {
 	fmt.Fprintln(w, "      Show command usage details.")
 	fmt.Fprintln(w, "  init [--root <path>]")
 	fmt.Fprintln(w, "      Initialize Appstract directory layout.")
-	fmt.Fprintln(w, "  add [--root <path>] [--output <silent|default|debug>] <manifest-file>")
-	fmt.Fprintln(w, "      Copy manifest into manifests/ and install the app.")
-	fmt.Fprintln(w, "  run [--root <path>] [--output <silent|default|debug>] <app>")
-	fmt.Fprintln(w, "      Launch app current version and trigger background update.")
-	fmt.Fprintln(w, "  update [--root <path>] [--output <silent|default|debug>] [--checkver] [--prompt-switch] [--relaunch] [--fail-fast]")
-	fmt.Fprintln(w, "      Update apps discovered from manifests/*.json.")
-	fmt.Fprintln(w, "  manifest [--output <silent|default|debug>] validate <file>")
-	fmt.Fprintln(w, "      Validate manifest schema and required fields.")
+	fmt.Fprintln(w, "  add [--root <path>] [--output <silent|default|debug|json>] [--name <app>] [--sha256 <hash>] <manifest-file|bucket/app|url>")
+	fmt.Fprintln(w, "      Copy manifest into manifests/ and install the app with its dependencies.")
+	fmt.Fprintln(w, "  remove [--root <path>] [--output <silent|default|debug|json>] <app>")
+	fmt.Fprintln(w, "      Uninstall an app that no other manifest depends on.")
+	fmt.Fprintln(w, "  bucket [--root <path>] [--output <silent|default|debug|json>] <add <name> <path-or-url>|list|remove <name>>")
+	fmt.Fprintln(w, "      Manage named manifest collections.")
+	fmt.Fprintln(w, "  search [--root <path>] [--output <silent|default|debug|json>] <query>")
+	fmt.Fprintln(w, "      Find apps by name, description or bin across manifests/ and buckets.")
+	fmt.Fprintln(w, "  run [--root <path>] [--output <silent|default|debug|json>] [--no-update|--force-update] <app>")
+	fmt.Fprintln(w, "      Switch to a staged pending version, launch app current version and stage the next update in a detached process.")
+	fmt.Fprintln(w, "  update [--root <path>] [--output <silent|default|debug|json>] [--checkver] [--only-outdated] [--exclude <pattern>]... [--prompt-switch] [--relaunch] [--stage-only] [--fail-fast] [--refresh-manifests [--yes]] [--app <app> [--background]] [app|pattern]...")
+	fmt.Fprintln(w, "      Update the given apps, or all apps discovered from manifests/*.json.")
+	fmt.Fprintln(w, "  log [--root <path>] [--output <silent|default|debug|json>] [--since <when>] [--stage <stage>] [--event <pattern>] [--errors-only] [--tx <id>] [--follow] [--json] [app]")
+	fmt.Fprintln(w, "      Show update events of one or all apps in time order.")
+	fmt.Fprintln(w, "  status [--root <path>] [--output <silent|default|debug|json>] [--exclude <pattern>]... [app|pattern]...")
+	fmt.Fprintln(w, "      Show installed, pending and manifest versions and the state of the last update.")
+	fmt.Fprintln(w, "  outdated [--root <path>] [--output <silent|default|debug|json>] [--json] [--exclude <pattern>]... [app|pattern]...")
+	fmt.Fprintln(w, "      List installed apps with a newer version available, without downloading anything.")
+	fmt.Fprintln(w, "  history [--root <path>] [--output <silent|default|debug|json>] <app>")
+	fmt.Fprintln(w, "      Show every update transaction of an app.")
+	fmt.Fprintln(w, "  report [--root <path>] [--output <silent|default|debug|json>]")
+	fmt.Fprintln(w, "      Summarize update history: failure rates by error code and average stage times.")
+	fmt.Fprintln(w, "  serve [--root <path>] [--output <silent|default|debug|json>] [--listen <127.0.0.1:port>] [--token <token>] [--schedule]")
+	fmt.Fprintln(w, "      Serve a token-protected local HTTP API for tray apps and launchers.")
+	fmt.Fprintln(w, "  daemon [--root <path>] [--output <silent|default|debug|json>] [--once]")
+	fmt.Fprintln(w, "      Check installed apps for updates whenever check_ttl_seconds has passed.")
+	fmt.Fprintln(w, "  manifest [--output <silent|default|debug|json>] validate [--strict] [--format <text|json>] <file|dir>...")
+	fmt.Fprintln(w, "      Validate manifests and report every issue.")
+	fmt.Fprintln(w, "  manifest autoupdate [--dry-run] <file>")
+	fmt.Fprintln(w, "      Bump manifest version, urls and hashes to the latest release.")
+	fmt.Fprintln(w, "  manifest schema")
+	fmt.Fprintln(w, "      Print the manifest JSON Schema.")
 }
 
 func printCommandUsage(cmd string, w io.Writer) bool {
@@ -104,24 +236,94 @@ func printCommandUsage(cmd string, w io.Writer) bool {
 		fmt.Fprintln(w, "show global or command-specific usage")
 		return true
 	case "init":
-		fmt.Fprintln(w, "usage: appstract init [--root <path>] [--output <silent|default|debug>]")
+		fmt.Fprintln(w, "usage: appstract init [--root <path>] [--output <silent|default|debug|json>]")
 		fmt.Fprintln(w, "initialize manifests/shims/scripts/apps and config.yaml")
 		return true
 	case "add":
-		fmt.Fprintln(w, "usage: appstract add [--root <path>] [--output <silent|default|debug>] <manifest-file>")
-		fmt.Fprintln(w, "derive app name from manifest filename, copy to manifests/<app>.json, then install")
+		fmt.Fprintln(w, "usage: appstract add [--root <path>] [--output <silent|default|debug|json>] [--name <app>] [--sha256 <hash>] <manifest-file|bucket/app|url>")
+		fmt.Fprintln(w, "derive app name from manifest filename, bucket/app or url path (or --name), copy to manifests/<app>.json, then install")
+		fmt.Fprintln(w, "--sha256 pins the manifest content; later refreshes must match it")
+		fmt.Fprintln(w, "missing apps listed in depends are installed first, from manifests/ or the same bucket, directory or url")
+		return true
+	case "remove":
+		fmt.Fprintln(w, "usage: appstract remove [--root <path>] [--output <silent|default|debug|json>] <app>")
+		fmt.Fprintln(w, "delete apps/<app> and manifests/<app>.json; refused while another manifest lists <app> in depends")
+		return true
+	case "bucket":
+		fmt.Fprintln(w, "usage: appstract bucket [--root <path>] [--output <silent|default|debug|json>] add <name> <path-or-url>")
+		fmt.Fprintln(w, "       appstract bucket [--root <path>] [--output <silent|default|debug|json>] list")
+		fmt.Fprintln(w, "       appstract bucket [--root <path>] [--output <silent|default|debug|json>] remove <name>")
+		fmt.Fprintln(w, "register a manifest directory or git repository (cloned into buckets/<name>) in config.yaml")
+		return true
+	case "search":
+		fmt.Fprintln(w, "usage: appstract search [--root <path>] [--output <silent|default|debug|json>] <query>")
+		fmt.Fprintln(w, "match query against app name, description and bin in manifests/ and every bucket; index cached in cache/search-index.json")
 		return true
 	case "run":
-		fmt.Fprintln(w, "usage: appstract run [--root <path>] [--output <silent|default|debug>] <app>")
+		fmt.Fprintln(w, "usage: appstract run [--root <path>] [--output <silent|default|debug|json>] [--no-update|--force-update] <app>")
 		fmt.Fprintln(w, "if apps/<app>/current is missing but manifests/<app>.json exists, install first")
+		fmt.Fprintln(w, "a version staged as pending is switched to before launch, unless the app is already running; nothing is stopped")
+		fmt.Fprintln(w, "after launch a detached `appstract update --app <app> --background` stages the new version, for the next run to switch to; status shows its progress and result")
+		fmt.Fprintln(w, "the background update is skipped within check_ttl_seconds of the last check, while backing off after failed updates (1m doubling to 24h) and when the download host is unreachable")
+		fmt.Fprintln(w, "hold.<app> and pin.<app> in config.yaml apply to it as to scheduled checks, even with --force-update")
+		fmt.Fprintln(w, "--no-update never starts it; --force-update always does")
 		return true
 	case "update":
-		fmt.Fprintln(w, "usage: appstract update [--root <path>] [--output <silent|default|debug>] [--checkver] [--prompt-switch] [--relaunch] [--fail-fast]")
-		fmt.Fprintln(w, "scan manifests/*.json and update each app")
+		fmt.Fprintln(w, "usage: appstract update [--root <path>] [--output <silent|default|debug|json>] [--checkver] [--only-outdated] [--exclude <pattern>]... [--prompt-switch] [--relaunch] [--stage-only] [--fail-fast] [--refresh-manifests [--yes]] [--app <app> [--background]] [app|pattern]...")
+		fmt.Fprintln(w, "refresh manifests added from buckets, then scan manifests/*.json and update each app, dependencies first")
+		fmt.Fprintln(w, "apps are names or globs such as py* (case-insensitive); a pattern matching no manifest is an error; --exclude drops matching apps")
+		fmt.Fprintln(w, "--only-outdated checks each installed app as outdated does and skips those already on the latest version, and those whose check fails")
+		fmt.Fprintln(w, "--stage-only downloads, verifies and extracts new versions and records them as pending without stopping or switching; run switches on next launch")
+		fmt.Fprintln(w, "--app updates one app; --background stages it without refreshing manifests, as the detached update started by run does")
+		fmt.Fprintln(w, "--refresh-manifests re-fetches manifests added from urls, prints a diff and asks before accepting (--yes accepts all)")
+		return true
+	case "log":
+		fmt.Fprintln(w, "usage: appstract log [--root <path>] [--output <silent|default|debug|json>] [--since <when>] [--stage <stage>] [--event <pattern>] [--errors-only] [--tx <id>] [--follow] [--json] [app]")
+		fmt.Fprintln(w, "print events from apps/<app>/logs, merged across apps by timestamp")
+		fmt.Fprintln(w, "--since takes a duration (90m, 24h, 7d), a date (2006-01-02) or an RFC3339 time; --event accepts patterns such as PKG_*")
+		fmt.Fprintln(w, "--follow keeps printing new events until interrupted; --json prints events as stored")
+		fmt.Fprintln(w, "--tx shows one update transaction as a timeline: offset from its start, stage, event and time spent in the stage")
+		return true
+	case "status":
+		fmt.Fprintln(w, "usage: appstract status [--root <path>] [--output <silent|default|debug|json>] [--exclude <pattern>]... [app|pattern]...")
+		fmt.Fprintln(w, "list every app, or the given names and globs, with its current, pending and manifest version, last check and state")
+		fmt.Fprintln(w, "state is updating (with the pid holding the lock), failed with the last error code, switch pending, not installed or ok")
+		return true
+	case "outdated":
+		fmt.Fprintln(w, "usage: appstract outdated [--root <path>] [--output <silent|default|debug|json>] [--json] [--exclude <pattern>]... [app|pattern]...")
+		fmt.Fprintln(w, "check every installed app, or the given names and globs, with checkver and list those behind the latest version with its download url")
+		fmt.Fprintln(w, "apps without checkver compare the manifest version to the installed one; nothing is downloaded or switched; an app found up to date counts as checked for check_ttl_seconds")
+		fmt.Fprintln(w, "--json prints every checked app as a JSON array")
+		return true
+	case "history":
+		fmt.Fprintln(w, "usage: appstract history [--root <path>] [--output <silent|default|debug|json>] <app>")
+		fmt.Fprintln(w, "list the update transactions recorded in apps/<app>/history.jsonl: version, outcome, download size, stage times and rollbacks")
+		return true
+	case "report":
+		fmt.Fprintln(w, "usage: appstract report [--root <path>] [--output <silent|default|debug|json>]")
+		fmt.Fprintln(w, "aggregate the history of every app: outcomes, failure rate by error code, average download and extract times")
+		return true
+	case "serve":
+		fmt.Fprintln(w, "usage: appstract serve [--root <path>] [--output <silent|default|debug|json>] [--listen <127.0.0.1:port>] [--token <token>] [--schedule]")
+		fmt.Fprintln(w, "serve list, status, update, run, rollback and log endpoints under /v1 until interrupted; update progress streams from /v1/events")
+		fmt.Fprintln(w, "--listen must be a loopback address; requests need \"Authorization: Bearer <token>\"")
+		fmt.Fprintln(w, "without --token a token is generated and written to serve.token in the root, readable only by the current user")
+		return true
+	case "daemon":
+		fmt.Fprintln(w, "usage: appstract daemon [--root <path>] [--output <silent|default|debug|json>] [--once]")
+		fmt.Fprintln(w, "check every installed app once its last check is older than check_ttl_seconds plus up to check_jitter_seconds, until interrupted")
+		fmt.Fprintln(w, "no check starts during quiet_hours; hold.<app> skips an app and pin.<app> keeps it on one version")
+		fmt.Fprintln(w, "a running app is not stopped: the new version is staged as pending and switched to by a later check once the app has exited, or by run")
+		fmt.Fprintln(w, "--once runs a single pass, e.g. from a system task scheduler; serve --schedule runs the same checks inside serve")
 		return true
 	case "manifest":
-		fmt.Fprintln(w, "usage: appstract manifest [--output <silent|default|debug>] validate <file>")
-		fmt.Fprintln(w, "parse and validate manifest file")
+		fmt.Fprintln(w, "usage: appstract manifest [--output <silent|default|debug|json>] validate [--strict] [--format <text|json>] <file|dir>...")
+		fmt.Fprintln(w, "       appstract manifest autoupdate [--dry-run] <file>")
+		fmt.Fprintln(w, "       appstract manifest schema")
+		fmt.Fprintln(w, "validate manifest files (directories expand to *.json) and report every issue with line and column")
+		fmt.Fprintln(w, "--strict turns warnings (unknown fields, urls, hashes, checkver) into errors; --format json prints a machine-readable report")
+		fmt.Fprintln(w, "autoupdate runs checkver, renders autoupdate templates, downloads artifacts to hash them and rewrites the file in place; --dry-run prints a diff")
+		fmt.Fprintln(w, "schema prints a JSON Schema for editors and CI")
 		return true
 	default:
 		return false
@@ -156,11 +358,11 @@ func ensureWorkspaceReady(root, executablePath string) error {
 	return bootstrap.EnsureReadyForCommand(root, executablePath)
 }
 
-func executeInit(args []string, stdout, stderr io.Writer, envHome string) int {
+func executeInit(args []string, stdout, stderr io.Writer, envHome string) (code int) {
 	fs := flag.NewFlagSet("init", flag.ContinueOnError)
 	fs.SetOutput(stderr)
 	rootFlag := fs.String("root", "", "Appstract root directory")
-	outputFlag := fs.String("output", "", "Output level: silent|default|debug")
+	outputFlag := fs.String("output", "", "Output level: silent|default|debug|json")
 	if err := fs.Parse(args); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			printCommandUsage("init", stdout)
@@ -179,6 +381,7 @@ func executeInit(args []string, stdout, stderr io.Writer, envHome string) int {
 		return 1
 	}
 	output := newCommandOutput(outputLevel, stdout, stderr)
+	defer output.finish("init", &code)
 	output.printDefault("initializing workspace: %s", root)
 	if err := bootstrap.InitLayout(root); err != nil {
 		output.printError("%v", err)
@@ -188,11 +391,13 @@ func executeInit(args []string, stdout, stderr io.Writer, envHome string) int {
 	return 0
 }
 
-func executeRun(args []string, stdout, stderr io.Writer, envHome string) int {
+func executeRun(args []string, stdout, stderr io.Writer, envHome string) (code int) {
 	fs := flag.NewFlagSet("run", flag.ContinueOnError)
 	fs.SetOutput(stderr)
 	rootFlag := fs.String("root", "", "Appstract root directory")
-	outputFlag := fs.String("output", "", "Output level: silent|default|debug")
+	outputFlag := fs.String("output", "", "Output level: silent|default|debug|json")
+	noUpdate := fs.Bool("no-update", false, "Launch without the background update")
+	forceUpdate := fs.Bool("force-update", false, "Start the background update even if the app was checked recently or looks offline")
 	if err := fs.Parse(args); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			printCommandUsage("run", stdout)
@@ -204,6 +409,10 @@ func executeRun(args []string, stdout, stderr io.Writer, envHome string) int {
 		printCommandUsage("run", stderr)
 		return 1
 	}
+	if *noUpdate && *forceUpdate {
+		fmt.Fprintln(stderr, "--no-update and --force-update cannot be combined")
+		return 1
+	}
 	app := fs.Arg(0)
 
 	root, executablePath, err := resolveRoot(envHome, *rootFlag)
@@ -217,9 +426,11 @@ func executeRun(args []string, stdout, stderr io.Writer, envHome string) int {
 		return 1
 	}
 	output := newCommandOutput(outputLevel, stdout, stderr)
+	defer output.finish("run", &code)
 	updateOpts := updateOptions{
 		Output: output,
 	}
+	output.setSummary("app", app)
 	output.printDefault("run start: app=%s root=%s", app, root)
 	if err := ensureWorkspaceReady(root, executablePath); err != nil {
 		output.printError("%v", err)
@@ -240,8 +451,8 @@ func executeRun(args []string, stdout, stderr io.Writer, envHome string) int {
 			}
 			output.printDefault("app %q is not installed, auto-installing from manifest: %s", app, manifestPath)
 			if err := executeUpdateFromManifest(root, app, manifestPath, updateOpts); err != nil {
-				output.printError("install app %q for run failed: %v", app, err)
-				return 1
+				output.printFailure(err, "install app %q for run failed: %v", app, err)
+				return exitCodeFor(err)
 			}
 			output.printDefault("[ok] auto-install completed: %s", app)
 			if _, err := os.Stat(currentPath); err != nil {
@@ -259,6 +470,13 @@ func executeRun(args []string, stdout, stderr io.Writer, envHome string) int {
 		output.printError("load manifest for run: %v", err)
 		return 1
 	}
+	applied, err := applyPendingUpdate(root, app, man.Bin, updateOpts)
+	if err != nil {
+		output.printFailure(err, "apply pending update for %q failed, launching current version: %v", app, err)
+	}
+	if applied != "" {
+		output.setSummary("applied_version", applied)
+	}
 	binPath := filepath.Join(currentPath, man.Bin)
 	if _, err := os.Stat(binPath); err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -273,19 +491,43 @@ func executeRun(args []string, stdout, stderr io.Writer, envHome string) int {
 		output.printError("launch app %q failed: %v", app, err)
 		return 1
 	}
-	go func() {
-		if err := runAsyncUpdate(root, app, manifestPath, updateOpts); err != nil {
-			output.printError("background update failed for %q: %v", app, err)
-		}
-	}()
 	output.printDefault("[ok] run-started: %s (%s)", app, binPath)
+	if *noUpdate {
+		return 0
+	}
+	cfg, err := config.Load(root)
+	if err != nil {
+		output.printWarning("background update failed for %q: %v", app, err)
+		return 0
+	}
+	reason := heldUpdateSkip(root, app, man, cfg)
+	if reason == "" && !*forceUpdate {
+		manager, err := newManager(root, updateOptions{})
+		if err != nil {
+			output.printWarning("background update failed for %q: %v", app, err)
+			return 0
+		}
+		reason = backgroundUpdateSkip(manager, app, man, cfg, time.Now())
+	}
+	if reason != "" {
+		output.setSummary("update_skipped", reason)
+		output.printDefault("background update skipped: %s", reason)
+		return 0
+	}
+	pid, err := startBackgroundUpdate(root, app)
+	if err != nil {
+		output.printWarning("background update failed for %q: %v", app, err)
+		return 0
+	}
+	output.setSummary("update_pid", pid)
+	output.printDefault("background update started: pid %d, see `appstract status %s`", pid, app)
 	return 0
 }
 
-func executeManifest(args []string, stdout, stderr io.Writer) int {
+func executeManifest(args []string, stdout, stderr io.Writer) (code int) {
 	fs := flag.NewFlagSet("manifest", flag.ContinueOnError)
 	fs.SetOutput(stderr)
-	outputFlag := fs.String("output", "", "Output level: silent|default|debug")
+	outputFlag := fs.String("output", "", "Output level: silent|default|debug|json")
 	if err := fs.Parse(args); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			printCommandUsage("manifest", stdout)
@@ -295,7 +537,7 @@ func executeManifest(args []string, stdout, stderr io.Writer) int {
 	}
 
 	remain := fs.Args()
-	if len(remain) < 2 || remain[0] != "validate" {
+	if len(remain) < 1 {
 		printCommandUsage("manifest", stderr)
 		return 1
 	}
@@ -306,21 +548,263 @@ func executeManifest(args []string, stdout, stderr io.Writer) int {
 		return 1
 	}
 	output := newCommandOutput(outputLevel, stdout, stderr)
+	defer output.finish("manifest "+remain[0], &code)
+
+	switch remain[0] {
+	case "validate":
+		return executeManifestValidate(remain[1:], output, stdout, stderr)
+	case "autoupdate":
+		return executeManifestAutoupdate(remain[1:], output, stdout, stderr)
+	case "schema":
+		b, err := manifest.JSONSchema()
+		if err != nil {
+			output.printError("%v", err)
+			return 1
+		}
+		if output.jsonMode() {
+			output.printResult("schema", json.RawMessage(b))
+			return 0
+		}
+		fmt.Fprintln(stdout, string(b))
+		return 0
+	default:
+		printCommandUsage("manifest", stderr)
+		return 1
+	}
+}
+
+func executeManifestAutoupdate(args []string, output *commandOutput, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("manifest autoupdate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dryRunFlag := fs.Bool("dry-run", false, "Show the diff without writing the file")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			printCommandUsage("manifest", stdout)
+			return 0
+		}
+		return 1
+	}
+	if fs.NArg() != 1 {
+		printCommandUsage("manifest", stderr)
+		return 1
+	}
+	path := fs.Arg(0)
 
-	m, err := manifest.ParseFile(remain[1])
+	src, err := os.ReadFile(path)
+	if err != nil {
+		output.printError("read manifest file: %v", err)
+		return 1
+	}
+	man, err := manifest.ParseBytes(src)
 	if err != nil {
 		output.printError("%v", err)
 		return 1
 	}
-	output.printDefault("[ok] manifest valid: version=%s", m.Version)
+	output.printDefault("checking latest version: %s (current %s)", path, man.Version)
+	result, err := resolveManifestAutoupdate(man, output)
+	if err != nil {
+		output.printError("autoupdate %s: %v", path, err)
+		return 1
+	}
+	if !result.Changed() {
+		output.printDefault("[ok] manifest up to date: version=%s", man.Version)
+		return 0
+	}
+	updated, err := manifest.RewriteSource(src, result.Version, result.Architecture)
+	if err != nil {
+		output.printError("rewrite %s: %v", path, err)
+		return 1
+	}
+	if *dryRunFlag {
+		diff := unifiedDiff(path, path, string(src), string(updated))
+		if output.jsonMode() {
+			output.printResult("diff", map[string]any{"path": path, "diff": diff})
+		} else {
+			fmt.Fprint(stdout, diff)
+		}
+		output.printDefault("dry run: %s would move %s -> %s", path, result.PreviousVersion, result.Version)
+		return 0
+	}
+	if err := writeFileAtomic(path, updated); err != nil {
+		output.printError("write %s: %v", path, err)
+		return 1
+	}
+	output.printDefault("[ok] manifest updated: %s %s -> %s", path, result.PreviousVersion, result.Version)
 	return 0
 }
 
-func executeAdd(args []string, stdout, stderr io.Writer, envHome string) int {
+// writeFileAtomic replaces path via a sibling temp file, keeping the original
+// permissions, so an interrupted write never leaves a truncated manifest.
+func writeFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+type manifestReport struct {
+	Files    []manifestFileReport `json:"files"`
+	Errors   int                  `json:"errors"`
+	Warnings int                  `json:"warnings"`
+}
+
+type manifestFileReport struct {
+	Path          string           `json:"path"`
+	Valid         bool             `json:"valid"`
+	Version       string           `json:"version,omitempty"`
+	Architectures []string         `json:"architectures,omitempty"`
+	Issues        []manifest.Issue `json:"issues"`
+}
+
+func executeManifestValidate(args []string, output *commandOutput, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("manifest validate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	strictFlag := fs.Bool("strict", false, "Reject unknown fields and apply deeper checks")
+	formatFlag := fs.String("format", "text", "Report format: text|json")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			printCommandUsage("manifest", stdout)
+			return 0
+		}
+		return 1
+	}
+	if fs.NArg() < 1 {
+		printCommandUsage("manifest", stderr)
+		return 1
+	}
+	if *formatFlag != "text" && *formatFlag != "json" {
+		fmt.Fprintf(stderr, "invalid format %q, expected text|json\n", *formatFlag)
+		return 1
+	}
+
+	paths, err := expandManifestPaths(fs.Args())
+	if err != nil {
+		output.printError("%v", err)
+		return 1
+	}
+	opts := manifest.ParseOptions{Strict: *strictFlag}
+	report := manifestReport{Files: make([]manifestFileReport, 0, len(paths))}
+	for _, path := range paths {
+		m, issues := manifest.CheckFile(path, opts)
+		file := manifestFileReport{Path: path, Valid: m != nil, Issues: issues}
+		if file.Issues == nil {
+			file.Issues = []manifest.Issue{}
+		}
+		if m != nil {
+			file.Version = m.Version
+			file.Architectures = m.CoveredArchitectures()
+		}
+		for _, issue := range issues {
+			if issue.Severity == manifest.SeverityError {
+				report.Errors++
+			} else {
+				report.Warnings++
+			}
+		}
+		report.Files = append(report.Files, file)
+	}
+
+	output.setSummary("files", len(report.Files))
+	output.setSummary("errors", report.Errors)
+	output.setSummary("warnings", report.Warnings)
+	if output.jsonMode() {
+		output.printResult("manifest_report", report)
+	} else if *formatFlag == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	} else {
+		printManifestReport(output, report)
+	}
+	if report.Errors > 0 {
+		return 1
+	}
+	return 0
+}
+
+func printManifestReport(output *commandOutput, report manifestReport) {
+	for _, file := range report.Files {
+		for _, issue := range file.Issues {
+			location := file.Path
+			if issue.Line > 0 {
+				location = fmt.Sprintf("%s:%d:%d", file.Path, issue.Line, issue.Column)
+			}
+			line := location + ": " + issue.Message
+			if issue.Path != "" {
+				line += " (" + issue.Path + ")"
+			}
+			if issue.Severity == manifest.SeverityError {
+				output.printError("%s", line)
+			} else {
+				output.printWarning("%s", line)
+			}
+		}
+		if file.Valid {
+			output.printDefault("[ok] manifest valid: %s version=%s", file.Path, file.Version)
+			output.printDefault("architectures: %s", strings.Join(file.Architectures, ", "))
+		}
+	}
+	if len(report.Files) > 1 {
+		output.printDefault("checked %d manifest(s): %d error(s), %d warning(s)", len(report.Files), report.Errors, report.Warnings)
+	}
+}
+
+// expandManifestPaths replaces each directory argument with the *.json files
+// it contains, sorted by name. File arguments are kept as given.
+func expandManifestPaths(args []string) ([]string, error) {
+	var paths []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil || !info.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+		entries, err := os.ReadDir(arg)
+		if err != nil {
+			return nil, fmt.Errorf("read manifest directory: %w", err)
+		}
+		found := false
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".json") {
+				continue
+			}
+			paths = append(paths, filepath.Join(arg, entry.Name()))
+			found = true
+		}
+		if !found {
+			return nil, fmt.Errorf("no manifests found in %s", arg)
+		}
+	}
+	return paths, nil
+}
+
+func executeAdd(args []string, stdout, stderr io.Writer, envHome string) (code int) {
 	fs := flag.NewFlagSet("add", flag.ContinueOnError)
 	fs.SetOutput(stderr)
 	rootFlag := fs.String("root", "", "Appstract root directory")
-	outputFlag := fs.String("output", "", "Output level: silent|default|debug")
+	outputFlag := fs.String("output", "", "Output level: silent|default|debug|json")
+	nameFlag := fs.String("name", "", "App name (default: derived from the manifest file name or URL)")
+	pinFlag := fs.String("sha256", "", "Expected sha256 of the manifest content")
 	if err := fs.Parse(args); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			printCommandUsage("add", stdout)
@@ -333,9 +817,32 @@ func executeAdd(args []string, stdout, stderr io.Writer, envHome string) int {
 		return 1
 	}
 	sourceManifestPath := fs.Arg(0)
-	app, err := deriveAppNameFromManifestPath(sourceManifestPath)
-	if err != nil {
-		fmt.Fprintln(stderr, err)
+	fromURL := catalog.IsManifestURL(sourceManifestPath)
+	bucketName, app, fromBucket := catalog.SplitBucketRef(sourceManifestPath)
+	if fromURL {
+		fromBucket = false
+		app = ""
+	}
+	switch {
+	case *nameFlag != "":
+		app = *nameFlag
+	case fromURL:
+		var err error
+		app, err = catalog.AppNameFromURL(sourceManifestPath)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	case !fromBucket:
+		var err error
+		app, err = deriveAppNameFromManifestPath(sourceManifestPath)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+	if !manifest.IsAppName(app) {
+		fmt.Fprintf(stderr, "invalid app name: %s\n", app)
 		return 1
 	}
 
@@ -350,45 +857,115 @@ func executeAdd(args []string, stdout, stderr io.Writer, envHome string) int {
 		return 1
 	}
 	output := newCommandOutput(outputLevel, stdout, stderr)
+	defer output.finish("add", &code)
 	updateOpts := updateOptions{
 		Output: output,
 	}
+	output.setSummary("app", app)
 	output.printDefault("add start: app=%s manifest=%s", app, sourceManifestPath)
 	if err := ensureWorkspaceReady(root, executablePath); err != nil {
 		output.printError("%v", err)
 		return 1
 	}
 
-	if _, err := manifest.ParseFile(sourceManifestPath); err != nil {
+	origin := catalog.Origin{SHA256: *pinFlag}
+	var content []byte
+	switch {
+	case fromURL:
+		origin.URL = sourceManifestPath
+		content, err = fetchManifestURL(sourceManifestPath)
+		if err != nil {
+			output.printError("download add manifest: %v", err)
+			return 1
+		}
+		output.printDefault("[ok] manifest downloaded: %s", sourceManifestPath)
+	case fromBucket:
+		cfg, err := config.Load(root)
+		if err != nil {
+			output.printError("%v", err)
+			return 1
+		}
+		b, ok := cfg.FindBucket(bucketName)
+		if !ok {
+			output.printError("bucket %q is not registered (see: appstract bucket add)", bucketName)
+			return 1
+		}
+		sourceManifestPath, err = catalog.BucketManifestPath(root, b, app)
+		if err != nil {
+			output.printError("%v", err)
+			return 1
+		}
+		origin.Bucket = b.Name
+		output.printDebug("resolved %s/%s to %s", b.Name, app, sourceManifestPath)
+	default:
+		if abs, err := filepath.Abs(sourceManifestPath); err == nil {
+			origin.File = abs
+		}
+	}
+	if content == nil {
+		content, err = os.ReadFile(sourceManifestPath)
+		if err != nil {
+			output.printError("validate add manifest: read manifest file: %v", err)
+			return 1
+		}
+	}
+
+	if err := catalog.CheckPin(content, origin.SHA256); err != nil {
+		output.printError("validate add manifest: %v", err)
+		return 1
+	}
+	parsed, err := manifest.ParseBytes(content)
+	if err != nil {
 		output.printError("validate add manifest: %v", err)
 		return 1
 	}
 	output.printDefault("[ok] manifest validated: %s", sourceManifestPath)
 
 	targetManifestPath := filepath.Join(root, "manifests", app+".json")
-	if err := copyFile(sourceManifestPath, targetManifestPath); err != nil {
+	if err := os.MkdirAll(filepath.Dir(targetManifestPath), 0o755); err != nil {
+		output.printError("copy manifest: %v", err)
+		return 1
+	}
+	if err := os.WriteFile(targetManifestPath, content, 0o644); err != nil {
 		output.printError("copy manifest: %v", err)
 		return 1
 	}
+	origin.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	if err := catalog.RecordOrigin(root, app, origin); err != nil {
+		output.printError("record manifest origin: %v", err)
+		return 1
+	}
 	output.printDefault("[ok] manifest saved: %s", targetManifestPath)
 
+	if err := installDependencies(root, app, parsed.Depends, origin, updateOpts); err != nil {
+		output.printFailure(err, "install dependencies of %q failed: %v", app, err)
+		return exitCodeFor(err)
+	}
 	if err := executeUpdateFromManifest(root, app, targetManifestPath, updateOpts); err != nil {
-		output.printError("install app %q from manifest failed: %v", app, err)
-		return 1
+		output.printFailure(err, "install app %q from manifest failed: %v", app, err)
+		return exitCodeFor(err)
 	}
 	output.printDefault("[ok] add completed: %s", app)
 	return 0
 }
 
-func executeUpdate(args []string, stdout, stderr io.Writer, envHome string) int {
+func executeUpdate(args []string, stdout, stderr io.Writer, envHome string) (code int) {
 	fs := flag.NewFlagSet("update", flag.ContinueOnError)
 	fs.SetOutput(stderr)
 	rootFlag := fs.String("root", "", "Appstract root directory")
-	outputFlag := fs.String("output", "", "Output level: silent|default|debug")
+	outputFlag := fs.String("output", "", "Output level: silent|default|debug|json")
 	checkver := fs.Bool("checkver", false, "Resolve latest version from checkver.github")
 	promptSwitch := fs.Bool("prompt-switch", false, "Prompt user before switching current version")
 	relaunch := fs.Bool("relaunch", false, "Relaunch app after successful switch")
 	failFast := fs.Bool("fail-fast", false, "Stop after first failed app update")
+	refreshManifests := fs.Bool("refresh-manifests", false, "Re-fetch manifests added from URLs and review the diff")
+	assumeYes := fs.Bool("yes", false, "Accept refreshed manifests without prompting")
+	stageOnly := fs.Bool("stage-only", false, "Stage new versions as pending instead of switching to them")
+	appFlag := fs.String("app", "", "Update only this app")
+	background := fs.Bool("background", false, "Stage the update of --app without refreshing manifests, as started by run")
+	onlyOutdated := fs.Bool("only-outdated", false, "Skip apps whose current version is the latest checkver finds (implies --checkver)")
+	var excludes stringsFlag
+	fs.Var(&excludes, "exclude", "Skip apps matching this name or glob; repeatable")
 	if err := fs.Parse(args); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			printCommandUsage("update", stdout)
@@ -396,8 +973,12 @@ func executeUpdate(args []string, stdout, stderr io.Writer, envHome string) int
 		}
 		return 1
 	}
-	if fs.NArg() != 0 {
-		printCommandUsage("update", stderr)
+	patterns := fs.Args()
+	if *appFlag != "" {
+		patterns = append(patterns, *appFlag)
+	}
+	if *background && (*appFlag == "" || fs.NArg() != 0) {
+		fmt.Fprintln(stderr, "--background requires --app and no other apps")
 		return 1
 	}
 
@@ -412,10 +993,19 @@ func executeUpdate(args []string, stdout, stderr io.Writer, envHome string) int
 		return 1
 	}
 	output := newCommandOutput(outputLevel, stdout, stderr)
+	defer output.finish("update", &code)
 	if err := ensureWorkspaceReady(root, executablePath); err != nil {
 		output.printError("%v", err)
 		return 1
 	}
+	// A background update is one of many launches; refreshing every
+	// manifest is left to explicit updates.
+	if !*background {
+		refreshBucketManifests(root, output)
+		if *refreshManifests {
+			refreshURLManifests(root, output, stdout, *assumeYes)
+		}
+	}
 	output.printDefault("update start: scanning manifests in %s", filepath.Join(root, "manifests"))
 
 	manifestsDir := filepath.Join(root, "manifests")
@@ -425,17 +1015,13 @@ func executeUpdate(args []string, stdout, stderr io.Writer, envHome string) int
 		return 1
 	}
 
-	type job struct {
-		app          string
-		manifestPath string
-	}
-	jobs := make([]job, 0, len(entries))
+	jobs := make([]updateJob, 0, len(entries))
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
 		}
 		name := entry.Name()
-		if !strings.EqualFold(filepath.Ext(name), ".json") {
+		if strings.HasPrefix(name, ".") || !strings.EqualFold(filepath.Ext(name), ".json") {
 			continue
 		}
 		app := strings.TrimSuffix(name, filepath.Ext(name))
@@ -443,35 +1029,84 @@ func executeUpdate(args []string, stdout, stderr io.Writer, envHome string) int
 			output.printError("skip invalid manifest filename: %s", name)
 			continue
 		}
-		jobs = append(jobs, job{
+		jobs = append(jobs, updateJob{
 			app:          app,
 			manifestPath: filepath.Join(manifestsDir, name),
 		})
 	}
 
-	if len(jobs) == 0 {
+	if len(jobs) == 0 && len(patterns) == 0 {
 		output.printDefault("no manifests found in %s", manifestsDir)
 		return 0
 	}
-	output.printDefault("found %d manifest(s)", len(jobs))
+	if len(patterns) > 0 || len(excludes) > 0 {
+		if jobs, err = selectUpdateJobs(jobs, patterns, excludes); err != nil {
+			output.printError("%v", err)
+			return 1
+		}
+		output.printDefault("selected %d app(s)", len(jobs))
+	} else {
+		output.printDefault("found %d manifest(s)", len(jobs))
+	}
+	checkFailCode := 0
+	if *onlyOutdated {
+		jobs, checkFailCode = outdatedUpdateJobs(root, jobs, output)
+	}
+	if len(jobs) == 0 {
+		output.printDefault("nothing to update")
+		return checkFailCode
+	}
+
+	jobs, depends, err := orderUpdateJobs(root, jobs, output)
+	if err != nil {
+		output.printError("%v", err)
+		return 1
+	}
 
 	opts := updateOptions{
-		Checkver:     *checkver,
+		Checkver:     *checkver || *onlyOutdated,
 		PromptSwitch: *promptSwitch,
 		Relaunch:     *relaunch,
+		StageOnly:    *stageOnly || *background,
 		Output:       output,
 	}
 
 	successCount := 0
 	failCount := 0
+	// The exit code reflects the first failure, which is usually the cause of
+	// any that follow.
+	failCode := checkFailCode
+	defer func() {
+		output.setSummary("total", len(jobs))
+		output.setSummary("success", successCount)
+		output.setSummary("failed", failCount)
+	}()
+	failed := map[string]bool{}
 	for _, item := range jobs {
+		if dep := failedDependency(depends[item.app], failed); dep != "" {
+			failCount++
+			failed[strings.ToLower(item.app)] = true
+			output.printError("update skipped: %s (dependency %s failed)", item.app, dep)
+			if failCode == 0 {
+				failCode = exitFailure
+			}
+			if *failFast {
+				output.printDefault("update summary: total=%d success=%d failed=%d", len(jobs), successCount, failCount)
+				return failCode
+			}
+			continue
+		}
 		output.printDefault("updating app: %s", item.app)
 		if err := executeUpdateFromManifest(root, item.app, item.manifestPath, opts); err != nil {
 			failCount++
-			output.printError("update failed: %s (%v)", item.app, err)
+			failed[strings.ToLower(item.app)] = true
+			output.printFailure(err, "update failed: %s (%v)", item.app, err)
+			if failCode == 0 {
+				failCode = exitCodeFor(err)
+			}
 			if *failFast {
 				output.printDefault("update summary: total=%d success=%d failed=%d", len(jobs), successCount, failCount)
-				return 1
+				return failCode
 			}
 			continue
 		}
@@ -479,10 +1114,183 @@ func executeUpdate(args []string, stdout, stderr io.Writer, envHome string) int
 		output.printDefault("[ok] update completed: %s", item.app)
 	}
 	output.printDefault("update summary: total=%d success=%d failed=%d", len(jobs), successCount, failCount)
-	if failCount > 0 {
-		return 1
+	return failCode
+}
+
+type updateJob struct {
+	app          string
+	manifestPath string
+}
+
+// selectUpdateJobs keeps the jobs of the apps selectApps picks.
+func selectUpdateJobs(jobs []updateJob, patterns, excludes []string) ([]updateJob, error) {
+	apps := make([]string, len(jobs))
+	for i, item := range jobs {
+		apps[i] = item.app
+	}
+	selected, err := selectApps(apps, patterns, excludes)
+	if err != nil {
+		return nil, err
+	}
+	kept := make([]updateJob, 0, len(selected))
+	for _, app := range selected {
+		for _, item := range jobs {
+			if item.app == app {
+				kept = append(kept, item)
+				break
+			}
+		}
+	}
+	return kept, nil
+}
+
+// outdatedUpdateJobs keeps the jobs of apps that are not installed yet and
+// of apps the version check of `outdated` finds behind. An app whose check
+// fails is dropped and reported; failCode is the exit code of the first
+// such failure.
+func outdatedUpdateJobs(root string, jobs []updateJob, output *commandOutput) (kept []updateJob, failCode int) {
+	manager, err := newManager(root, updateOptions{})
+	if err != nil {
+		output.printError("%v", err)
+		return nil, exitFailure
+	}
+	kept = make([]updateJob, 0, len(jobs))
+	for _, item := range jobs {
+		state, err := updater.ReadState(root, item.app)
+		if err != nil || state.CurrentVersion == "" {
+			kept = append(kept, item)
+			continue
+		}
+		var result updater.CheckResult
+		man, err := manifest.ParseFile(item.manifestPath)
+		if err == nil {
+			result, err = checkAppVersion(manager, item.app, man)
+		}
+		if err != nil {
+			output.printFailure(err, "check failed: %s (%v)", item.app, err)
+			if failCode == 0 {
+				failCode = exitCodeFor(err)
+			}
+			continue
+		}
+		if !result.Outdated {
+			output.printDefault("skip %s: %s is the latest version", item.app, result.LatestVersion)
+			continue
+		}
+		kept = append(kept, item)
+	}
+	return kept, failCode
+}
+
+// orderUpdateJobs puts dependencies ahead of the apps that need them. A
+// manifest that fails to parse keeps its place; its update reports the error.
+func orderUpdateJobs(root string, jobs []updateJob, output *commandOutput) ([]updateJob, map[string][]string, error) {
+	apps := make([]string, 0, len(jobs))
+	byApp := make(map[string]updateJob, len(jobs))
+	for _, item := range jobs {
+		apps = append(apps, item.app)
+		byApp[strings.ToLower(item.app)] = item
+	}
+	depends := map[string][]string{}
+	for _, item := range jobs {
+		deps, err := catalog.ManifestDepends(root, item.app)
+		if err != nil {
+			continue
+		}
+		for _, dep := range deps {
+			if _, ok := byApp[strings.ToLower(dep)]; !ok {
+				output.printWarning("%s depends on %s, which has no manifest", item.app, dep)
+			}
+		}
+		depends[item.app] = deps
+	}
+	order, err := catalog.OrderByDependencies(apps, depends)
+	if err != nil {
+		return nil, nil, err
+	}
+	ordered := make([]updateJob, 0, len(order))
+	for _, app := range order {
+		ordered = append(ordered, byApp[strings.ToLower(app)])
+	}
+	return ordered, depends, nil
+}
+
+func failedDependency(deps []string, failed map[string]bool) string {
+	for _, dep := range deps {
+		if failed[strings.ToLower(dep)] {
+			return dep
+		}
+	}
+	return ""
+}
+
+// refreshBucketManifests pulls bucket updates into manifests/ for apps that
+// were added from a bucket. Failures are reported but never block the update;
+// the app then updates from the manifest it already has.
+func refreshBucketManifests(root string, output *commandOutput) {
+	cfg, err := config.Load(root)
+	if err != nil {
+		output.printError("refresh bucket manifests: %v", err)
+		return
+	}
+	results, err := catalog.SyncBucketManifests(root, cfg, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		output.printError("refresh bucket manifests: %v", err)
+	}
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			output.printError("refresh manifest %s from bucket %s failed: %v", result.App, result.Bucket, result.Err)
+		case result.Changed:
+			output.printDefault("[ok] manifest refreshed: %s (bucket %s)", result.App, result.Bucket)
+		default:
+			output.printDebug("manifest unchanged: %s (bucket %s)", result.App, result.Bucket)
+		}
+	}
+}
+
+// refreshURLManifests re-fetches manifests that were added from a URL, shows
+// the diff for each change and writes it only once accepted.
+func refreshURLManifests(root string, output *commandOutput, stdout io.Writer, assumeYes bool) {
+	results, err := catalog.FetchURLManifests(root, fetchManifestURL)
+	if err != nil {
+		output.printError("refresh url manifests: %v", err)
+		return
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			output.printError("refresh manifest %s from %s failed: %v", result.App, result.URL, result.Err)
+			continue
+		}
+		if !result.Changed() {
+			output.printDebug("manifest unchanged: %s (%s)", result.App, result.URL)
+			continue
+		}
+		name := filepath.Join("manifests", result.App+".json")
+		diff := unifiedDiff(name, result.URL, string(result.Current), string(result.Fetched))
+		if output.jsonMode() {
+			output.printResult("manifest_diff", map[string]any{"app": result.App, "url": result.URL, "diff": diff})
+		} else {
+			fmt.Fprint(stdout, diff)
+		}
+		accept := assumeYes
+		if !accept {
+			accept, err = confirmPrompt(fmt.Sprintf("accept manifest changes for %s?", result.App))
+			if err != nil {
+				output.printError("confirm manifest refresh: %v", err)
+				continue
+			}
+		}
+		if !accept {
+			output.printDefault("kept current manifest: %s", result.App)
+			continue
+		}
+		if err := catalog.AcceptRefresh(root, result.App, result.Fetched, time.Now().UTC().Format(time.RFC3339)); err != nil {
+			output.printError("save refreshed manifest %s: %v", result.App, err)
+			continue
+		}
+		output.printDefault("[ok] manifest refreshed: %s (%s)", result.App, result.URL)
 	}
-	return 0
 }
 
 func deriveAppNameFromManifestPath(path string) (string, error) {
@@ -497,14 +1305,3 @@ func deriveAppNameFromManifestPath(path string) (string, error) {
 	}
 	return app, nil
 }
-
-func copyFile(sourcePath, targetPath string) error {
-	data, err := os.ReadFile(sourcePath)
-	if err != nil {
-		return err
-	}
-	if err := os.MkdirAll(filepath.Dir(targetPath), 0o755); err != nil {
-		return err
-	}
-	return os.WriteFile(targetPath, data, 0o644)
-}
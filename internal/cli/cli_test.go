@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"appstract/internal/bootstrap"
+	"appstract/internal/manifest"
 	"appstract/internal/updater"
 )
 
@@ -130,6 +132,7 @@ func TestExecuteRunFailsWhenCurrentMissingAndManifestMissing(t *testing.T) {
 
 func TestExecuteRunReadyWhenCurrentExists(t *testing.T) {
 	root := t.TempDir()
+	stubProbeConnectivity(t, nil)
 	if err := bootstrap.InitLayout(root); err != nil {
 		t.Fatalf("init layout failed: %v", err)
 	}
@@ -153,15 +156,15 @@ func TestExecuteRunReadyWhenCurrentExists(t *testing.T) {
 	t.Cleanup(func() { runLaunch = oldLaunch })
 
 	done := make(chan struct{})
-	oldAsync := runAsyncUpdate
-	runAsyncUpdate = func(runRoot, app, manifestPath string, opts updateOptions) error {
+	oldStart := startBackgroundUpdate
+	startBackgroundUpdate = func(runRoot, app string) (int, error) {
 		if runRoot != root || app != "chrome" {
-			t.Fatalf("unexpected async update args: root=%s app=%s", runRoot, app)
+			t.Fatalf("unexpected background update args: root=%s app=%s", runRoot, app)
 		}
 		close(done)
-		return nil
+		return 4242, nil
 	}
-	t.Cleanup(func() { runAsyncUpdate = oldAsync })
+	t.Cleanup(func() { startBackgroundUpdate = oldStart })
 
 	var out strings.Builder
 	var errOut strings.Builder
@@ -182,8 +185,60 @@ func TestExecuteRunReadyWhenCurrentExists(t *testing.T) {
 	}
 }
 
+func TestExecuteRunAppliesPendingBeforeLaunch(t *testing.T) {
+	root := t.TempDir()
+	stubProbeConnectivity(t, nil)
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	current := filepath.Join(root, "apps", "chrome", "current")
+	if err := os.MkdirAll(current, 0o755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(current, "chrome.exe"), []byte(""), 0o644); err != nil {
+		t.Fatalf("write bin failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "manifests", "chrome.json"), []byte(runManifestContent("chrome.exe")), 0o644); err != nil {
+		t.Fatalf("write manifest failed: %v", err)
+	}
+
+	var calls []string
+	oldApply := applyPendingUpdate
+	applyPendingUpdate = func(runRoot, app, bin string, opts updateOptions) (string, error) {
+		calls = append(calls, "apply:"+bin)
+		return "1.2.3", nil
+	}
+	t.Cleanup(func() { applyPendingUpdate = oldApply })
+	oldLaunch := runLaunch
+	runLaunch = func(path string) error {
+		calls = append(calls, "launch")
+		return nil
+	}
+	t.Cleanup(func() { runLaunch = oldLaunch })
+	oldStart := startBackgroundUpdate
+	startBackgroundUpdate = func(runRoot, app string) (int, error) {
+		calls = append(calls, "update")
+		return 4242, nil
+	}
+	t.Cleanup(func() { startBackgroundUpdate = oldStart })
+
+	var out strings.Builder
+	var errOut strings.Builder
+	code := Execute([]string{"run", "--root", root, "--output", "json", "chrome"}, &out, &errOut, "")
+	if code != 0 {
+		t.Fatalf("expected code 0, got %d, err=%s", code, errOut.String())
+	}
+	if strings.Join(calls, ",") != "apply:chrome.exe,launch,update" {
+		t.Fatalf("expected the pending version applied before launch, got %v", calls)
+	}
+	if !strings.Contains(out.String(), `"applied_version":"1.2.3"`) || !strings.Contains(out.String(), `"update_pid":4242`) {
+		t.Fatalf("expected the applied version in the summary: %s", out.String())
+	}
+}
+
 func TestExecuteRunAttemptsInstallWhenCurrentMissing(t *testing.T) {
 	root := t.TempDir()
+	stubProbeConnectivity(t, nil)
 	if err := bootstrap.InitLayout(root); err != nil {
 		t.Fatalf("init layout failed: %v", err)
 	}
@@ -216,12 +271,12 @@ func TestExecuteRunAttemptsInstallWhenCurrentMissing(t *testing.T) {
 	t.Cleanup(func() { runLaunch = oldLaunch })
 
 	done := make(chan struct{})
-	oldAsync := runAsyncUpdate
-	runAsyncUpdate = func(runRoot, app, path string, opts updateOptions) error {
+	oldStart := startBackgroundUpdate
+	startBackgroundUpdate = func(runRoot, app string) (int, error) {
 		close(done)
-		return nil
+		return 4242, nil
 	}
-	t.Cleanup(func() { runAsyncUpdate = oldAsync })
+	t.Cleanup(func() { startBackgroundUpdate = oldStart })
 
 	var out strings.Builder
 	var errOut strings.Builder
@@ -324,6 +379,7 @@ func TestExecuteRunFailsWhenBinMissing(t *testing.T) {
 
 func TestExecuteRunLaunchSuccessEvenIfBackgroundUpdateFails(t *testing.T) {
 	root := t.TempDir()
+	stubProbeConnectivity(t, nil)
 	if err := bootstrap.InitLayout(root); err != nil {
 		t.Fatalf("init layout failed: %v", err)
 	}
@@ -343,12 +399,12 @@ func TestExecuteRunLaunchSuccessEvenIfBackgroundUpdateFails(t *testing.T) {
 	t.Cleanup(func() { runLaunch = oldLaunch })
 
 	done := make(chan struct{})
-	oldAsync := runAsyncUpdate
-	runAsyncUpdate = func(runRoot, app, manifestPath string, opts updateOptions) error {
+	oldStart := startBackgroundUpdate
+	startBackgroundUpdate = func(runRoot, app string) (int, error) {
 		close(done)
-		return fmt.Errorf("boom")
+		return 0, fmt.Errorf("boom")
 	}
-	t.Cleanup(func() { runAsyncUpdate = oldAsync })
+	t.Cleanup(func() { startBackgroundUpdate = oldStart })
 
 	var out strings.Builder
 	var errOut strings.Builder
@@ -389,6 +445,163 @@ func TestExecuteManifestValidate(t *testing.T) {
 	if !strings.Contains(out.String(), "manifest valid") {
 		t.Fatalf("unexpected stdout: %s", out.String())
 	}
+	if !strings.Contains(out.String(), "architectures: 64bit") {
+		t.Fatalf("expected covered architectures in stdout: %s", out.String())
+	}
+}
+
+func TestExecuteManifestValidateStrictRejectsUnknownField(t *testing.T) {
+	root := t.TempDir()
+	manifestPath := filepath.Join(root, "app.json")
+	content := `{
+		"version": "1.2.3",
+		"architecture": {"64bit": {"url": "https://example.com/app.zip", "hash": "abc"}},
+		"bin": "app.exe",
+		"pre_instal": ["echo hi"]
+	}`
+	if err := os.WriteFile(manifestPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+
+	var out strings.Builder
+	var errOut strings.Builder
+	if code := Execute([]string{"manifest", "validate", manifestPath}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("expected lenient validate to pass, got %d, err=%s", code, errOut.String())
+	}
+	out.Reset()
+	errOut.Reset()
+	code := Execute([]string{"manifest", "validate", "--strict", manifestPath}, &out, &errOut, "")
+	if code != 1 {
+		t.Fatalf("expected code 1, got %d", code)
+	}
+	if !strings.Contains(errOut.String(), "unknown field pre_instal") {
+		t.Fatalf("expected unknown field path in stderr: %s", errOut.String())
+	}
+}
+
+func TestExecuteManifestValidateDirectoryReportsAllIssues(t *testing.T) {
+	dir := t.TempDir()
+	good := `{"version": "1.0", "bin": "a.exe", "architecture": {"64bit": {"url": "https://example.com/a.zip", "hash": "abc"}}}`
+	bad := "{\n  \"version\": \"\",\n  \"bin\": \"\",\n  \"architecture\": {\"64bit\": {\"url\": \"https://example.com/b.zip\", \"hash\": \"abc\"}}\n}"
+	if err := os.WriteFile(filepath.Join(dir, "a.json"), []byte(good), 0o644); err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "b.json"), []byte(bad), 0o644); err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+
+	var out strings.Builder
+	var errOut strings.Builder
+	code := Execute([]string{"manifest", "validate", dir}, &out, &errOut, "")
+	if code != 1 {
+		t.Fatalf("expected code 1, got %d", code)
+	}
+	if !strings.Contains(out.String(), "manifest valid: "+filepath.Join(dir, "a.json")) {
+		t.Fatalf("expected a.json to be valid: %s", out.String())
+	}
+	for _, want := range []string{"b.json:2:3: manifest version is required", "b.json:3:3: manifest bin is required"} {
+		if !strings.Contains(errOut.String(), want) {
+			t.Fatalf("expected %q in stderr: %s", want, errOut.String())
+		}
+	}
+	if !strings.Contains(errOut.String(), "[warn]") {
+		t.Fatalf("expected hash warnings in stderr: %s", errOut.String())
+	}
+	if !strings.Contains(out.String(), "checked 2 manifest(s): 2 error(s), 2 warning(s)") {
+		t.Fatalf("expected summary: %s", out.String())
+	}
+}
+
+func TestExecuteManifestValidateJSONFormat(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.json")
+	if err := os.WriteFile(path, []byte(`{"version": "1.0", "bin": "a.exe", "extra": 1, "architecture": {"64bit": {"url": "https://example.com/a.zip", "hash": "abc"}}}`), 0o644); err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+
+	var out strings.Builder
+	var errOut strings.Builder
+	code := Execute([]string{"manifest", "validate", "--strict", "--format", "json", path}, &out, &errOut, "")
+	if code != 1 {
+		t.Fatalf("expected code 1, got %d, err=%s", code, errOut.String())
+	}
+	var report manifestReport
+	if err := json.Unmarshal([]byte(out.String()), &report); err != nil {
+		t.Fatalf("decode report: %v\n%s", err, out.String())
+	}
+	if len(report.Files) != 1 || report.Files[0].Valid || report.Errors != 2 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if report.Files[0].Issues[0].Path != "extra" || report.Files[0].Issues[0].Line != 1 {
+		t.Fatalf("unexpected first issue: %+v", report.Files[0].Issues[0])
+	}
+}
+
+func TestExecuteManifestAutoupdate(t *testing.T) {
+	original := resolveManifestAutoupdate
+	defer func() { resolveManifestAutoupdate = original }()
+	newHash := strings.Repeat("a", 64)
+	resolveManifestAutoupdate = func(man *manifest.Manifest, output *commandOutput) (updater.AutoupdateResult, error) {
+		var arch manifest.Architecture
+		arch.Set(manifest.Arch64bit, manifest.Artifact{URL: "https://example.com/app-2.0.zip", Hash: newHash})
+		return updater.AutoupdateResult{PreviousVersion: man.Version, Version: "2.0", Architecture: arch}, nil
+	}
+
+	path := filepath.Join(t.TempDir(), "app.json")
+	content := "{\n  \"version\": \"1.0\",\n  \"architecture\": {\n    \"64bit\": {\n      \"url\": \"https://example.com/app-1.0.zip\",\n      \"hash\": \"" + strings.Repeat("0", 64) + "\"\n    }\n  },\n  \"bin\": \"app.exe\"\n}\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+
+	var out strings.Builder
+	var errOut strings.Builder
+	code := Execute([]string{"manifest", "autoupdate", "--dry-run", path}, &out, &errOut, "")
+	if code != 0 {
+		t.Fatalf("expected code 0, got %d, err=%s", code, errOut.String())
+	}
+	for _, want := range []string{`-  "version": "1.0",`, `+  "version": "2.0",`, `+      "url": "https://example.com/app-2.0.zip",`, "@@ -1,"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in diff: %s", want, out.String())
+		}
+	}
+	if b, _ := os.ReadFile(path); string(b) != content {
+		t.Fatalf("dry run must not modify the file: %s", b)
+	}
+
+	out.Reset()
+	errOut.Reset()
+	code = Execute([]string{"manifest", "autoupdate", path}, &out, &errOut, "")
+	if code != 0 {
+		t.Fatalf("expected code 0, got %d, err=%s", code, errOut.String())
+	}
+	want := strings.NewReplacer(`"1.0"`, `"2.0"`, "app-1.0.zip", "app-2.0.zip", strings.Repeat("0", 64), newHash).Replace(content)
+	if b, _ := os.ReadFile(path); string(b) != want {
+		t.Fatalf("unexpected rewritten manifest:\n%s", b)
+	}
+}
+
+func TestUnifiedDiffContextHunks(t *testing.T) {
+	oldText := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	newText := "a\nB\nc\nd\ne\nf\ng\nh\nI\nj\n"
+	diff := unifiedDiff("old", "new", oldText, newText)
+	if strings.Count(diff, "@@") != 4 {
+		t.Fatalf("expected two hunks, got:\n%s", diff)
+	}
+	if unifiedDiff("old", "new", oldText, oldText) != "" {
+		t.Fatalf("expected empty diff for identical input")
+	}
+}
+
+func TestExecuteManifestSchema(t *testing.T) {
+	var out strings.Builder
+	var errOut strings.Builder
+	code := Execute([]string{"manifest", "schema"}, &out, &errOut, "")
+	if code != 0 {
+		t.Fatalf("expected code 0, got %d, err=%s", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), `"$schema"`) || !strings.Contains(out.String(), `"pre_install"`) {
+		t.Fatalf("unexpected schema output: %s", out.String())
+	}
 }
 
 func TestExecuteManifestUsageError(t *testing.T) {
@@ -505,15 +718,19 @@ func TestExecuteAddRequiresInitOnBinaryOnlyRoot(t *testing.T) {
 	}
 }
 
-func TestExecuteUpdateUsageError(t *testing.T) {
+func TestExecuteUpdateUnknownApp(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
 	var out strings.Builder
 	var errOut strings.Builder
 
-	code := Execute([]string{"update", "aria2"}, &out, &errOut, "")
+	code := Execute([]string{"update", "--root", root, "aria2"}, &out, &errOut, "")
 	if code != 1 {
 		t.Fatalf("expected code 1, got %d", code)
 	}
-	if !strings.Contains(errOut.String(), "usage: appstract update") {
+	if !strings.Contains(errOut.String(), `no app matches "aria2"`) {
 		t.Fatalf("unexpected stderr: %s", errOut.String())
 	}
 }
@@ -772,6 +989,14 @@ func TestExecuteUpdateDebugOutput(t *testing.T) {
 	}
 }
 
+// stubProbeConnectivity answers the connectivity probe of run with err.
+func stubProbeConnectivity(t *testing.T, err error) {
+	t.Helper()
+	old := probeConnectivity
+	probeConnectivity = func(addr string) error { return err }
+	t.Cleanup(func() { probeConnectivity = old })
+}
+
 func runManifestContent(bin string) string {
 	return `{
 		"version": "1.2.3",
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"appstract/internal/scheduler"
+)
+
+func executeDaemon(args []string, stdout, stderr io.Writer, envHome string) (code int) {
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	rootFlag := fs.String("root", "", "Appstract root directory")
+	outputFlag := fs.String("output", "", "Output level: silent|default|debug|json")
+	once := fs.Bool("once", false, "Check the due apps once and exit")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			printCommandUsage("daemon", stdout)
+			return 0
+		}
+		return 1
+	}
+	if fs.NArg() != 0 {
+		printCommandUsage("daemon", stderr)
+		return 1
+	}
+
+	root, executablePath, err := resolveRoot(envHome, *rootFlag)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	outputLevel, err := resolveOutputLevel(root, *outputFlag)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	output := newCommandOutput(outputLevel, stdout, stderr)
+	defer output.finish("daemon", &code)
+	if err := ensureWorkspaceReady(root, executablePath); err != nil {
+		output.printError("%v", err)
+		return 1
+	}
+
+	checked, failed := 0, 0
+	defer func() {
+		output.setSummary("checked", checked)
+		output.setSummary("failed", failed)
+	}()
+	sched := newScheduler(root, output, func(app, manifestPath string, checkver bool) error {
+		return executeUpdateFromManifest(root, app, manifestPath, updateOptions{Checkver: checkver, DeferSwitch: true, Output: output})
+	})
+	onResult := sched.OnResult
+	sched.OnResult = func(app string, err error) {
+		checked++
+		if err != nil {
+			failed++
+		}
+		onResult(app, err)
+	}
+
+	if *once {
+		if err := sched.RunOnce(context.Background()); err != nil {
+			output.printError("%v", err)
+			return 1
+		}
+		return 0
+	}
+	output.printDefault("[ok] scheduler started: %s", root)
+	ctx, cancel := interruptContext()
+	defer cancel()
+	sched.Run(ctx)
+	output.printDefault("[ok] scheduler stopped")
+	return 0
+}
+
+// newScheduler returns a scheduler for root that reports through output and
+// checks apps with update.
+func newScheduler(root string, output *commandOutput, update func(app, manifestPath string, checkver bool) error) *scheduler.Scheduler {
+	sched := scheduler.New(root)
+	sched.Update = update
+	sched.OnSkip = func(app, reason string) {
+		output.printDefault("scheduled check skipped: %s (%s)", app, reason)
+	}
+	sched.OnResult = func(app string, err error) {
+		if err != nil {
+			output.printFailure(err, "scheduled check failed: %s (%v)", app, err)
+			return
+		}
+		output.printDefault("[ok] scheduled check completed: %s", app)
+	}
+	sched.OnError = func(err error) {
+		output.printError("scheduled pass failed, retrying on the next one: %v", err)
+	}
+	return sched
+}
@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"appstract/internal/bootstrap"
+)
+
+func TestExecuteDaemonOnceChecksDueAppsWithDeferredSwitch(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	for _, app := range []string{"a", "b"} {
+		writeTestManifest(t, filepath.Join(root, "manifests", app+".json"), runManifestContent(app+".exe"))
+		if err := os.MkdirAll(filepath.Join(root, "apps", app, "current"), 0o755); err != nil {
+			t.Fatalf("create app: %v", err)
+		}
+	}
+	// Not installed: the scheduler never installs apps.
+	writeTestManifest(t, filepath.Join(root, "manifests", "c.json"), runManifestContent("c.exe"))
+	configPath := filepath.Join(root, "config.yaml")
+	cfg, _ := os.ReadFile(configPath)
+	if err := os.WriteFile(configPath, append(cfg, []byte("hold.b: \"testing\"\n")...), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	old := executeUpdateFromManifest
+	t.Cleanup(func() { executeUpdateFromManifest = old })
+	var calls []string
+	executeUpdateFromManifest = func(root, app, manifestPath string, opts updateOptions) error {
+		if !opts.DeferSwitch || !opts.Checkver {
+			t.Fatalf("expected a deferring checkver update, got %+v", opts)
+		}
+		calls = append(calls, app)
+		return nil
+	}
+
+	var out strings.Builder
+	var errOut strings.Builder
+	if code := Execute([]string{"daemon", "--root", root, "--once"}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("daemon failed: %d %s", code, errOut.String())
+	}
+	if strings.Join(calls, ",") != "a" {
+		t.Fatalf("expected only a to be checked, got %v", calls)
+	}
+	if !strings.Contains(out.String(), "scheduled check skipped: b (held: testing)") {
+		t.Fatalf("expected the hold to be reported, got:\n%s", out.String())
+	}
+}
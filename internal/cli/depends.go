@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"appstract/internal/catalog"
+	"appstract/internal/config"
+	"appstract/internal/manifest"
+	"appstract/internal/updater"
+)
+
+// installDependencies installs the missing dependencies of app, deepest
+// first. Dependency manifests that are not in manifests/ yet are taken from
+// where app itself came from: the same bucket, the directory of the source
+// file, or the sibling URL. They are only saved once the whole graph has
+// resolved, so a cycle or a missing manifest leaves manifests/ as it was.
+func installDependencies(root, app string, depends []string, origin catalog.Origin, opts updateOptions) error {
+	output := opts.Output
+	staged := map[string]stagedManifest{}
+	order, err := catalog.InstallOrder(app, func(name string) ([]string, error) {
+		if strings.EqualFold(name, app) {
+			return depends, nil
+		}
+		if appInstalled(root, name) {
+			return nil, nil
+		}
+		if _, err := os.Stat(filepath.Join(root, "manifests", name+".json")); err == nil {
+			return catalog.ManifestDepends(root, name)
+		}
+		dep, err := loadDependencyManifest(root, name, origin)
+		if err != nil {
+			return nil, fmt.Errorf("dependency %s of %s: %w", name, app, err)
+		}
+		staged[name] = dep
+		return dep.depends, nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range order[:len(order)-1] {
+		if dep, ok := staged[name]; ok {
+			if err := saveDependencyManifest(root, name, dep, output); err != nil {
+				return fmt.Errorf("dependency %s of %s: %w", name, app, err)
+			}
+		}
+	}
+	for _, name := range order[:len(order)-1] {
+		if appInstalled(root, name) {
+			output.printDebug("dependency already installed: %s", name)
+			continue
+		}
+		output.printDefault("installing dependency: %s (required by %s)", name, app)
+		if err := executeUpdateFromManifest(root, name, filepath.Join(root, "manifests", name+".json"), opts); err != nil {
+			return fmt.Errorf("install dependency %s: %w", name, err)
+		}
+		output.printDefault("[ok] dependency installed: %s", name)
+	}
+	return nil
+}
+
+func appInstalled(root, app string) bool {
+	state, err := updater.ReadState(root, app)
+	return err == nil && state.CurrentVersion != ""
+}
+
+// stagedManifest is a validated dependency manifest that is not saved yet.
+type stagedManifest struct {
+	content []byte
+	origin  catalog.Origin
+	depends []string
+}
+
+// loadDependencyManifest reads and validates the manifest of dep from where
+// its parent came from, without saving it.
+func loadDependencyManifest(root, dep string, parent catalog.Origin) (stagedManifest, error) {
+	staged := stagedManifest{}
+	var err error
+	switch {
+	case parent.Bucket != "":
+		cfg, err := config.Load(root)
+		if err != nil {
+			return staged, err
+		}
+		b, ok := cfg.FindBucket(parent.Bucket)
+		if !ok {
+			return staged, fmt.Errorf("bucket %q is not registered", parent.Bucket)
+		}
+		path, err := catalog.BucketManifestPath(root, b, dep)
+		if err != nil {
+			return staged, err
+		}
+		if staged.content, err = os.ReadFile(path); err != nil {
+			return staged, err
+		}
+		staged.origin.Bucket = b.Name
+	case parent.File != "":
+		path := filepath.Join(filepath.Dir(parent.File), dep+".json")
+		if staged.content, err = os.ReadFile(path); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return staged, fmt.Errorf("no manifest in manifests/ or next to %s (add it first)", parent.File)
+			}
+			return staged, err
+		}
+		staged.origin.File = path
+	case parent.URL != "":
+		base, err := neturl.Parse(parent.URL)
+		if err != nil {
+			return staged, err
+		}
+		ref := base.ResolveReference(&neturl.URL{Path: neturl.PathEscape(dep) + ".json"})
+		if staged.content, err = fetchManifestURL(ref.String()); err != nil {
+			return staged, err
+		}
+		staged.origin.URL = ref.String()
+	default:
+		return staged, errors.New("no manifest in manifests/ (add it first)")
+	}
+	man, err := manifest.ParseBytes(staged.content)
+	if err != nil {
+		return staged, fmt.Errorf("validate dependency manifest: %w", err)
+	}
+	staged.depends = man.Depends
+	return staged, nil
+}
+
+// saveDependencyManifest writes a staged manifest to manifests/ and records
+// its origin.
+func saveDependencyManifest(root, dep string, staged stagedManifest, output *commandOutput) error {
+	target := filepath.Join(root, "manifests", dep+".json")
+	if err := os.WriteFile(target, staged.content, 0o644); err != nil {
+		return err
+	}
+	origin := staged.origin
+	origin.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	if err := catalog.RecordOrigin(root, dep, origin); err != nil {
+		return err
+	}
+	output.printDefault("[ok] dependency manifest saved: %s", target)
+	return nil
+}
@@ -0,0 +1,275 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"appstract/internal/bootstrap"
+	"appstract/internal/updater"
+)
+
+func dependsManifestContent(bin string, depends ...string) string {
+	content := runManifestContent(bin)
+	if len(depends) == 0 {
+		return content
+	}
+	return strings.Replace(content, `"bin"`, `"depends": ["`+strings.Join(depends, `", "`)+`"],
+		"bin"`, 1)
+}
+
+func writeTestManifest(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write manifest failed: %v", err)
+	}
+}
+
+func stubUpdateCalls(t *testing.T, fail map[string]bool) *[]string {
+	t.Helper()
+	var calls []string
+	oldUpdate := executeUpdateFromManifest
+	executeUpdateFromManifest = func(updateRoot, app, path string, opts updateOptions) error {
+		calls = append(calls, app)
+		if fail[app] {
+			return errors.New("boom")
+		}
+		return nil
+	}
+	t.Cleanup(func() { executeUpdateFromManifest = oldUpdate })
+	return &calls
+}
+
+func TestExecuteAddInstallsDependenciesFirst(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	src := t.TempDir()
+	writeTestManifest(t, filepath.Join(src, "ide.json"), dependsManifestContent("ide.exe", "jre"))
+	writeTestManifest(t, filepath.Join(src, "jre.json"), dependsManifestContent("java.exe", "vcrt"))
+	writeTestManifest(t, filepath.Join(src, "vcrt.json"), dependsManifestContent("vcrt.exe"))
+	calls := stubUpdateCalls(t, nil)
+
+	var out strings.Builder
+	var errOut strings.Builder
+	if code := Execute([]string{"add", "--root", root, filepath.Join(src, "ide.json")}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("add failed: %d %s", code, errOut.String())
+	}
+	if got := strings.Join(*calls, ","); got != "vcrt,jre,ide" {
+		t.Fatalf("unexpected install order: %s", got)
+	}
+	for _, app := range []string{"jre", "vcrt"} {
+		if _, err := os.Stat(filepath.Join(root, "manifests", app+".json")); err != nil {
+			t.Fatalf("expected dependency manifest %s staged: %v", app, err)
+		}
+	}
+	if !strings.Contains(out.String(), "installing dependency: jre (required by ide)") {
+		t.Fatalf("expected dependency message: %s", out.String())
+	}
+}
+
+func TestExecuteAddReportsDependencyCycle(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	src := t.TempDir()
+	writeTestManifest(t, filepath.Join(src, "a.json"), dependsManifestContent("a.exe", "b"))
+	writeTestManifest(t, filepath.Join(src, "b.json"), dependsManifestContent("b.exe", "a"))
+	calls := stubUpdateCalls(t, nil)
+
+	var out strings.Builder
+	var errOut strings.Builder
+	code := Execute([]string{"add", "--root", root, filepath.Join(src, "a.json")}, &out, &errOut, "")
+	if code != 1 || !strings.Contains(errOut.String(), "dependency cycle: a -> b -> a") {
+		t.Fatalf("expected cycle error, got %d %s", code, errOut.String())
+	}
+	if len(*calls) != 0 {
+		t.Fatalf("nothing should be installed on a cycle: %v", *calls)
+	}
+	if _, err := os.Stat(filepath.Join(root, "manifests", "b.json")); !os.IsNotExist(err) {
+		t.Fatalf("expected no dependency manifest saved on a cycle: %v", err)
+	}
+}
+
+func TestExecuteAddSavesNoDependencyManifestWhenOneIsMissing(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	src := t.TempDir()
+	writeTestManifest(t, filepath.Join(src, "ide.json"), dependsManifestContent("ide.exe", "jre"))
+	writeTestManifest(t, filepath.Join(src, "jre.json"), dependsManifestContent("java.exe", "vcrt"))
+	calls := stubUpdateCalls(t, nil)
+
+	var out strings.Builder
+	var errOut strings.Builder
+	code := Execute([]string{"add", "--root", root, filepath.Join(src, "ide.json")}, &out, &errOut, "")
+	if code == 0 || !strings.Contains(errOut.String(), "dependency vcrt of ide") {
+		t.Fatalf("expected missing dependency error, got %d %s", code, errOut.String())
+	}
+	if len(*calls) != 0 {
+		t.Fatalf("nothing should be installed: %v", *calls)
+	}
+	if _, err := os.Stat(filepath.Join(root, "manifests", "jre.json")); !os.IsNotExist(err) {
+		t.Fatalf("expected jre manifest not saved: %v", err)
+	}
+}
+
+func TestExecuteUpdateOrdersByDependencies(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	manifests := filepath.Join(root, "manifests")
+	writeTestManifest(t, filepath.Join(manifests, "alpha.json"), dependsManifestContent("alpha.exe"))
+	writeTestManifest(t, filepath.Join(manifests, "ide.json"), dependsManifestContent("ide.exe", "jre", "ghost"))
+	writeTestManifest(t, filepath.Join(manifests, "jre.json"), dependsManifestContent("java.exe"))
+	calls := stubUpdateCalls(t, map[string]bool{"jre": true})
+
+	var out strings.Builder
+	var errOut strings.Builder
+	if code := Execute([]string{"update", "--root", root}, &out, &errOut, ""); code != 1 {
+		t.Fatalf("expected failure exit code, got %d", code)
+	}
+	if got := strings.Join(*calls, ","); got != "alpha,jre" {
+		t.Fatalf("unexpected update order: %s", got)
+	}
+	if !strings.Contains(errOut.String(), "update skipped: ide (dependency jre failed)") {
+		t.Fatalf("expected dependent skipped: %s", errOut.String())
+	}
+	if !strings.Contains(errOut.String(), "ide depends on ghost, which has no manifest") {
+		t.Fatalf("expected missing dependency warning: %s", errOut.String())
+	}
+	if !strings.Contains(out.String(), "update summary: total=3 success=1 failed=2") {
+		t.Fatalf("unexpected summary: %s", out.String())
+	}
+}
+
+func TestExecuteUpdateReportsDependencyCycle(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	manifests := filepath.Join(root, "manifests")
+	writeTestManifest(t, filepath.Join(manifests, "a.json"), dependsManifestContent("a.exe", "b"))
+	writeTestManifest(t, filepath.Join(manifests, "b.json"), dependsManifestContent("b.exe", "a"))
+	calls := stubUpdateCalls(t, nil)
+
+	var out strings.Builder
+	var errOut strings.Builder
+	code := Execute([]string{"update", "--root", root}, &out, &errOut, "")
+	if code != 1 || !strings.Contains(errOut.String(), "dependency cycle: a -> b -> a") {
+		t.Fatalf("expected cycle error, got %d %s", code, errOut.String())
+	}
+	if len(*calls) != 0 {
+		t.Fatalf("nothing should be updated on a cycle: %v", *calls)
+	}
+}
+
+func TestExecuteRemoveRefusesWhileDependentsExist(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	manifests := filepath.Join(root, "manifests")
+	writeTestManifest(t, filepath.Join(manifests, "ide.json"), dependsManifestContent("ide.exe", "jre"))
+	writeTestManifest(t, filepath.Join(manifests, "jre.json"), dependsManifestContent("java.exe"))
+	for _, app := range []string{"ide", "jre"} {
+		if err := os.MkdirAll(filepath.Join(root, "apps", app, "1.2.3"), 0o755); err != nil {
+			t.Fatalf("mkdir failed: %v", err)
+		}
+	}
+
+	var out strings.Builder
+	var errOut strings.Builder
+	code := Execute([]string{"remove", "--root", root, "jre"}, &out, &errOut, "")
+	if code != 1 || !strings.Contains(errOut.String(), "cannot remove jre: required by ide") {
+		t.Fatalf("expected refusal, got %d %s", code, errOut.String())
+	}
+	if _, err := os.Stat(filepath.Join(root, "apps", "jre")); err != nil {
+		t.Fatalf("refused remove must keep files: %v", err)
+	}
+
+	for _, app := range []string{"ide", "jre"} {
+		out.Reset()
+		errOut.Reset()
+		if code := Execute([]string{"remove", "--root", root, app}, &out, &errOut, ""); code != 0 {
+			t.Fatalf("remove %s failed: %d %s", app, code, errOut.String())
+		}
+		if _, err := os.Stat(filepath.Join(root, "apps", app)); !os.IsNotExist(err) {
+			t.Fatalf("expected apps/%s removed: %v", app, err)
+		}
+		if _, err := os.Stat(filepath.Join(manifests, app+".json")); !os.IsNotExist(err) {
+			t.Fatalf("expected manifests/%s.json removed: %v", app, err)
+		}
+	}
+
+	errOut.Reset()
+	if code := Execute([]string{"remove", "--root", root, "jre"}, &out, &errOut, ""); code != 1 || !strings.Contains(errOut.String(), `app "jre" is not installed`) {
+		t.Fatalf("expected not installed error, got %d %s", code, errOut.String())
+	}
+}
+
+func TestExecuteRemoveRegeneratesEnvironment(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	content := strings.Replace(runManifestContent("java.exe"), `"bin"`, `"env_add_path": "bin",
+		"bin"`, 1)
+	writeTestManifest(t, filepath.Join(root, "manifests", "jre.json"), content)
+	if err := os.MkdirAll(filepath.Join(root, "apps", "jre", "current"), 0o755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	if err := regenerateEnvironment(root); err != nil {
+		t.Fatalf("regenerate failed: %v", err)
+	}
+	shPath := filepath.Join(root, "env", "appstract.sh")
+	if b, _ := os.ReadFile(shPath); !strings.Contains(string(b), filepath.Join("jre", "current", "bin")) {
+		t.Fatalf("expected jre path exported:\n%s", b)
+	}
+	// jre/current exists, so the real remove would look for running
+	// processes, which needs Windows.
+	oldRemove := removeAppFiles
+	removeAppFiles = func(root, app string) error { return os.RemoveAll(filepath.Join(root, "apps", app)) }
+	t.Cleanup(func() { removeAppFiles = oldRemove })
+
+	var out strings.Builder
+	var errOut strings.Builder
+	if code := Execute([]string{"remove", "--root", root, "jre"}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("remove failed: %d %s", code, errOut.String())
+	}
+	if b, _ := os.ReadFile(shPath); strings.Contains(string(b), "jre") {
+		t.Fatalf("expected jre path dropped after remove:\n%s", b)
+	}
+}
+
+func TestExecuteRemoveWaitsForLock(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	writeTestManifest(t, filepath.Join(root, "manifests", "jre.json"), dependsManifestContent("java.exe"))
+	versionDir := filepath.Join(root, "apps", "jre", "1.2.3")
+	if err := os.MkdirAll(versionDir, 0o755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	// A background update of jre holds the lock.
+	os.WriteFile(filepath.Join(root, "apps", "jre", ".lock"), []byte(`{"pid":`+strconv.Itoa(os.Getpid())+`}`), 0o644)
+
+	var out, errOut strings.Builder
+	if code := Execute([]string{"remove", "--root", root, "jre"}, &out, &errOut, ""); code != exitCodeFor(&updater.Error{Code: updater.ErrCodeLockBusy}) {
+		t.Fatalf("expected LOCK_BUSY exit code, got %d %s", code, errOut.String())
+	}
+	if _, err := os.Stat(versionDir); err != nil {
+		t.Fatalf("a refused remove must keep files: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "manifests", "jre.json")); err != nil {
+		t.Fatalf("a refused remove must keep the manifest: %v", err)
+	}
+}
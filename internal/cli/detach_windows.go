@@ -0,0 +1,20 @@
+//go:build windows
+
+package cli
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detachedProcess is DETACHED_PROCESS, which syscall does not define.
+const detachedProcess = 0x00000008
+
+// detach starts cmd without a console and outside the process group of
+// the caller, so closing the console of run does not end it.
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | detachedProcess,
+		HideWindow:    true,
+	}
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+// unifiedDiff renders a line-based unified diff with three lines of context.
+// It is meant for manifest-sized inputs; the LCS table is quadratic.
+func unifiedDiff(oldName, newName, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+	a := splitLines(oldText)
+	b := splitLines(newText)
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	type diffLine struct {
+		op   byte
+		text string
+		oldN int
+		newN int
+	}
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{op: ' ', text: a[i], oldN: i, newN: j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{op: '-', text: a[i], oldN: i, newN: j})
+			i++
+		default:
+			lines = append(lines, diffLine{op: '+', text: b[j], oldN: i, newN: j})
+			j++
+		}
+	}
+
+	const context = 3
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+	for start := 0; start < len(lines); {
+		if lines[start].op == ' ' {
+			start++
+			continue
+		}
+		from := max(start-context, 0)
+		end := start
+		for k := start; k < len(lines) && k-end <= 2*context; k++ {
+			if lines[k].op != ' ' {
+				end = k
+			}
+		}
+		to := min(end+context+1, len(lines))
+		oldCount, newCount := 0, 0
+		for _, l := range lines[from:to] {
+			if l.op != '+' {
+				oldCount++
+			}
+			if l.op != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", lines[from].oldN+1, oldCount, lines[from].newN+1, newCount)
+		for _, l := range lines[from:to] {
+			out.WriteByte(l.op)
+			out.WriteString(l.text)
+			out.WriteByte('\n')
+		}
+		start = to
+	}
+	return out.String()
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,47 @@
+package cli
+
+import "appstract/internal/updater"
+
+// exitFailure is the exit code for failures that carry no updater error code:
+// bad flags, unreadable workspaces and the like.
+const exitFailure = 1
+
+// updaterExitCodes gives every updater error code its own exit status so
+// scripts can tell a network hiccup from a broken package without parsing
+// output. Codes are grouped by tens per stage; the values are part of the
+// CLI contract and must not be renumbered.
+var updaterExitCodes = map[string]int{
+	updater.ErrCodeNetCheckverRequest: 20,
+	updater.ErrCodeNetCheckverHTTP:    21,
+	updater.ErrCodeNetDownload:        22,
+	updater.ErrCodeCheckverResolve:    23,
+
+	updater.ErrCodePkgDownload: 30,
+	updater.ErrCodePkgVerify:   31,
+	updater.ErrCodePkgExtract:  32,
+	updater.ErrCodePkgInstall:  33,
+
+	updater.ErrCodeScriptPreInstall: 40,
+
+	updater.ErrCodeSwitchPrompt:      50,
+	updater.ErrCodeSwitchProcess:     51,
+	updater.ErrCodeSwitchCurrent:     52,
+	updater.ErrCodeSwitchHealthcheck: 53,
+	updater.ErrCodeSwitchRollback:    54,
+
+	updater.ErrCodeManifestInvalid: 60,
+	updater.ErrCodeLockBusy:        61,
+	updater.ErrCodeFilesystem:      62,
+	updater.ErrCodeEnvSync:         63,
+}
+
+// exitCodeFor maps err to the exit status of its updater error code, or to
+// exitFailure when it has none.
+func exitCodeFor(err error) int {
+	if ue, ok := updater.AsError(err); ok {
+		if code, ok := updaterExitCodes[ue.Code]; ok {
+			return code
+		}
+	}
+	return exitFailure
+}
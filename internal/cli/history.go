@@ -0,0 +1,186 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"appstract/internal/manifest"
+	"appstract/internal/updater"
+)
+
+func executeHistory(args []string, stdout, stderr io.Writer, envHome string) (code int) {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	rootFlag := fs.String("root", "", "Appstract root directory")
+	outputFlag := fs.String("output", "", "Output level: silent|default|debug|json")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			printCommandUsage("history", stdout)
+			return 0
+		}
+		return 1
+	}
+	if fs.NArg() != 1 {
+		printCommandUsage("history", stderr)
+		return 1
+	}
+	app := fs.Arg(0)
+	if !manifest.IsAppName(app) {
+		fmt.Fprintf(stderr, "invalid app name: %s\n", app)
+		return 1
+	}
+
+	root, executablePath, err := resolveRoot(envHome, *rootFlag)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	outputLevel, err := resolveOutputLevel(root, *outputFlag)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	output := newCommandOutput(outputLevel, stdout, stderr)
+	defer output.finish("history", &code)
+	output.setSummary("app", app)
+	if err := ensureWorkspaceReady(root, executablePath); err != nil {
+		output.printError("%v", err)
+		return 1
+	}
+
+	entries, err := updater.ReadHistory(root, app)
+	if err != nil {
+		output.printError("%v", err)
+		return 1
+	}
+	output.setSummary("transactions", len(entries))
+	if output.jsonMode() {
+		output.printResult("history", entries)
+		return 0
+	}
+	if len(entries) == 0 {
+		output.printDefault("no update history for %s", app)
+		return 0
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "STARTED\tVERSION\tFROM\tOUTCOME\tSIZE\tDOWNLOAD\tEXTRACT\tTOTAL\tNOTE")
+	for _, entry := range entries {
+		from := entry.PreviousVersion
+		if from == "" {
+			from = "-"
+		}
+		note := entry.ErrorCode
+		if entry.RolledBack {
+			note = joinNote(note, "rolled back")
+		}
+		if entry.SwitchedAt != "" && !entry.RolledBack {
+			note = joinNote(note, "switched "+entry.SwitchedAt)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.StartedAt, entry.Version, from, entry.Outcome, historySize(entry.DownloadBytes),
+			stageDuration(entry.StageMS, "download"), stageDuration(entry.StageMS, "extract"),
+			formatMS(entry.DurationMS), note)
+	}
+	if err := tw.Flush(); err != nil {
+		output.printError("%v", err)
+		return 1
+	}
+	return 0
+}
+
+func executeReport(args []string, stdout, stderr io.Writer, envHome string) (code int) {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	rootFlag := fs.String("root", "", "Appstract root directory")
+	outputFlag := fs.String("output", "", "Output level: silent|default|debug|json")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			printCommandUsage("report", stdout)
+			return 0
+		}
+		return 1
+	}
+	if fs.NArg() != 0 {
+		printCommandUsage("report", stderr)
+		return 1
+	}
+
+	root, executablePath, err := resolveRoot(envHome, *rootFlag)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	outputLevel, err := resolveOutputLevel(root, *outputFlag)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	output := newCommandOutput(outputLevel, stdout, stderr)
+	defer output.finish("report", &code)
+	if err := ensureWorkspaceReady(root, executablePath); err != nil {
+		output.printError("%v", err)
+		return 1
+	}
+
+	report, err := updater.Report(root)
+	if err != nil {
+		output.printError("build report: %v", err)
+		return 1
+	}
+	output.setSummary("transactions", report.Transactions)
+	if output.jsonMode() {
+		output.printResult("report", report)
+		return 0
+	}
+	if report.Transactions == 0 {
+		output.printDefault("no update history yet")
+		return 0
+	}
+	fmt.Fprintf(stdout, "apps: %d  transactions: %d  succeeded: %d  failed: %d  declined: %d  deferred: %d  rollbacks: %d\n",
+		report.Apps, report.Transactions, report.Succeeded, report.Failed, report.Declined, report.Deferred, report.Rollbacks)
+	fmt.Fprintf(stdout, "failure rate: %.1f%%\n", report.FailureRate*100)
+	if len(report.Failures) > 0 {
+		fmt.Fprintln(stdout, "failures by code:")
+		tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		for _, f := range report.Failures {
+			fmt.Fprintf(tw, "  %s\t%d\t%.1f%%\n", f.Code, f.Count, f.Rate*100)
+		}
+		if err := tw.Flush(); err != nil {
+			output.printError("%v", err)
+			return 1
+		}
+	}
+	fmt.Fprintf(stdout, "average download: %s (%s)\n", formatMS(report.AvgDownloadMS), humanBytes(report.AvgDownloadBytes))
+	fmt.Fprintf(stdout, "average extract: %s\n", formatMS(report.AvgExtractMS))
+	return 0
+}
+
+func joinNote(note, extra string) string {
+	if note == "" {
+		return extra
+	}
+	return note + ", " + extra
+}
+
+func historySize(bytes int64) string {
+	if bytes <= 0 {
+		return "-"
+	}
+	return humanBytes(bytes)
+}
+
+func stageDuration(stages map[string]int64, stage string) string {
+	ms, ok := stages[stage]
+	if !ok {
+		return "-"
+	}
+	return formatMS(ms)
+}
+
+func formatMS(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).String()
+}
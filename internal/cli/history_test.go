@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"appstract/internal/bootstrap"
+	"appstract/internal/updater"
+)
+
+func writeHistory(t *testing.T, root, app string, entries ...updater.HistoryEntry) {
+	t.Helper()
+	var b strings.Builder
+	for _, entry := range entries {
+		line, _ := json.Marshal(entry)
+		b.Write(append(line, '\n'))
+	}
+	dir := filepath.Join(root, "apps", app)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("create app dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "history.jsonl"), []byte(b.String()), 0o644); err != nil {
+		t.Fatalf("write history: %v", err)
+	}
+}
+
+func TestExecuteHistoryAndReport(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	writeHistory(t, root, "a",
+		updater.HistoryEntry{TxID: "t1", App: "a", Version: "1.0", Outcome: updater.OutcomeSuccess, StartedAt: "2026-10-01T10:00:00Z",
+			SwitchedAt: "2026-10-01T10:00:05Z", DownloadBytes: 2048, DurationMS: 5000, StageMS: map[string]int64{"download": 3000, "extract": 1000}},
+		updater.HistoryEntry{TxID: "t2", App: "a", Version: "1.1", PreviousVersion: "1.0", Outcome: updater.OutcomeFailed, ErrorCode: updater.ErrCodeSwitchHealthcheck,
+			StartedAt: "2026-10-02T10:00:00Z", DurationMS: 7000, RolledBack: true, StageMS: map[string]int64{"download": 5000, "extract": 3000}},
+	)
+	writeHistory(t, root, "b",
+		updater.HistoryEntry{TxID: "t3", App: "b", Version: "2.0", Outcome: updater.OutcomeFailed, ErrorCode: updater.ErrCodePkgDownload, StartedAt: "2026-10-03T10:00:00Z"},
+	)
+
+	var out strings.Builder
+	var errOut strings.Builder
+	if code := Execute([]string{"history", "--root", root, "a"}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("history failed: %d %s", code, errOut.String())
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "STARTED") ||
+		!strings.Contains(lines[1], "2.0 KB") || !strings.Contains(lines[1], "switched 2026-10-01T10:00:05Z") ||
+		!strings.Contains(lines[2], "SWITCH_HEALTHCHECK, rolled back") {
+		t.Fatalf("unexpected history output:\n%s", out.String())
+	}
+
+	out.Reset()
+	if code := Execute([]string{"report", "--root", root}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("report failed: %d %s", code, errOut.String())
+	}
+	for _, want := range []string{
+		"apps: 2  transactions: 3  succeeded: 1  failed: 2  declined: 0  deferred: 0  rollbacks: 1",
+		"failure rate: 66.7%",
+		"SWITCH_HEALTHCHECK",
+		"average download: 4s",
+		"average extract: 2s",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("report missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if code := Execute([]string{"report", "--root", root, "--output", "json"}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("report json failed: %d %s", code, errOut.String())
+	}
+	results := eventsOfType(decodeEvents(t, out.String()), "result")
+	if len(results) != 1 || results[0]["kind"] != "report" || results[0]["data"].(map[string]any)["failed"] != float64(2) {
+		t.Fatalf("unexpected report result: %v", results)
+	}
+}
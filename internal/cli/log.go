@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"appstract/internal/updater"
+)
+
+var logFollowInterval = time.Second
+
+func executeLog(args []string, stdout, stderr io.Writer, envHome string) (code int) {
+	fs := flag.NewFlagSet("log", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	rootFlag := fs.String("root", "", "Appstract root directory")
+	outputFlag := fs.String("output", "", "Output level: silent|default|debug|json")
+	sinceFlag := fs.String("since", "", "Only events at or after: duration (90m, 24h, 7d), date (2006-01-02) or RFC3339 time")
+	stage := fs.String("stage", "", "Only events of this stage")
+	event := fs.String("event", "", "Only events matching this name or pattern (e.g. PKG_*)")
+	errorsOnly := fs.Bool("errors-only", false, "Only events that carry an error code")
+	follow := fs.Bool("follow", false, "Keep printing new events until interrupted")
+	rawJSON := fs.Bool("json", false, "Print events as JSON lines, as stored in the log")
+	txID := fs.String("tx", "", "Only events of this update transaction, shown as a timeline")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			printCommandUsage("log", stdout)
+			return 0
+		}
+		return 1
+	}
+	if fs.NArg() > 1 {
+		printCommandUsage("log", stderr)
+		return 1
+	}
+
+	root, executablePath, err := resolveRoot(envHome, *rootFlag)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	outputLevel, err := resolveOutputLevel(root, *outputFlag)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	output := newCommandOutput(outputLevel, stdout, stderr)
+	defer output.finish("log", &code)
+	if err := ensureWorkspaceReady(root, executablePath); err != nil {
+		output.printError("%v", err)
+		return 1
+	}
+	filter := updater.EventFilter{
+		App:        fs.Arg(0),
+		TxID:       *txID,
+		Stage:      *stage,
+		Event:      *event,
+		ErrorsOnly: *errorsOnly,
+	}
+	if *sinceFlag != "" {
+		if filter.Since, err = parseSince(*sinceFlag, time.Now()); err != nil {
+			output.printError("%v", err)
+			return 1
+		}
+	}
+
+	reader := &updater.EventReader{Root: root, Filter: filter}
+	var timeline *txTimeline
+	if *txID != "" {
+		timeline = &txTimeline{}
+	}
+	printEvents := func(events []updater.StageEvent) {
+		for _, e := range events {
+			switch {
+			case output.jsonMode():
+				output.printResult("event", e)
+			case *rawJSON:
+				b, _ := json.Marshal(e)
+				fmt.Fprintln(stdout, string(b))
+			case timeline != nil:
+				fmt.Fprintln(stdout, timeline.format(e))
+			default:
+				fmt.Fprintln(stdout, formatEvent(e))
+			}
+		}
+	}
+	events, err := reader.Read()
+	if err != nil {
+		output.printError("read event log: %v", err)
+		return 1
+	}
+	printEvents(events)
+	total := len(events)
+	defer func() { output.setSummary("events", total) }()
+	if !*follow {
+		if total == 0 && *txID != "" {
+			output.printError("no events for transaction %s", *txID)
+			return 1
+		}
+		if total == 0 {
+			output.printDebug("no matching events")
+		}
+		return 0
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+	ticker := time.NewTicker(logFollowInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-ticker.C:
+		}
+		events, err := reader.Read()
+		if err != nil {
+			output.printError("read event log: %v", err)
+			return 1
+		}
+		printEvents(events)
+		total += len(events)
+	}
+}
+
+// formatEvent renders one event as a log line: time, app, stage, event,
+// then the error code in brackets, the message and the transaction ID when
+// present.
+func formatEvent(e updater.StageEvent) string {
+	parts := []string{e.Timestamp, e.App, e.Stage, e.Event}
+	if e.ErrorCode != "" {
+		parts = append(parts, "["+e.ErrorCode+"]")
+	}
+	if e.Message != "" {
+		parts = append(parts, e.Message)
+	}
+	if e.TxID != "" {
+		parts = append(parts, "tx="+e.TxID)
+	}
+	return strings.Join(parts, "  ")
+}
+
+// txTimeline renders the events of one transaction relative to its first
+// event, after a header naming the app and the version change.
+type txTimeline struct {
+	start   time.Time
+	started bool
+}
+
+func (t *txTimeline) format(e updater.StageEvent) string {
+	ts, _ := time.Parse(time.RFC3339Nano, e.Timestamp)
+	var b strings.Builder
+	if !t.started {
+		t.started, t.start = true, ts
+		from := e.PreviousVersion
+		if from == "" {
+			from = "(none)"
+		}
+		fmt.Fprintf(&b, "transaction %s: %s %s -> %s, started %s\n", e.TxID, e.App, from, e.Version, e.Timestamp)
+	}
+	fmt.Fprintf(&b, "%+8dms  %-11s  %-26s  %6dms", ts.Sub(t.start).Milliseconds(), e.Stage, e.Event, e.ElapsedMS)
+	if e.ErrorCode != "" {
+		b.WriteString("  [" + e.ErrorCode + "]")
+	}
+	if e.Message != "" {
+		b.WriteString("  " + e.Message)
+	}
+	return b.String()
+}
+
+// parseSince accepts a duration back from now (90m, 24h, 7d), a calendar
+// date in UTC, or an RFC3339 time.
+func parseSince(raw string, now time.Time) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if days, ok := strings.CutSuffix(raw, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(raw); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse("2006-01-02", raw); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q: want a duration (90m, 24h, 7d), a date (2006-01-02) or an RFC3339 time", raw)
+}
@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"appstract/internal/bootstrap"
+	"appstract/internal/updater"
+)
+
+func writeEventLog(t *testing.T, root, app, day string, events ...updater.StageEvent) {
+	t.Helper()
+	dir := filepath.Join(root, "apps", app, "logs")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("create log dir: %v", err)
+	}
+	var b strings.Builder
+	for _, e := range events {
+		line, _ := json.Marshal(e)
+		b.Write(append(line, '\n'))
+	}
+	f, err := os.OpenFile(filepath.Join(dir, "events-"+day+".log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("open log: %v", err)
+	}
+	defer f.Close()
+	f.WriteString(b.String())
+}
+
+func TestExecuteLogFiltersAndMerges(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	writeEventLog(t, root, "a", "20261018",
+		updater.StageEvent{Timestamp: "2026-10-18T10:00:00Z", App: "a", Stage: "update", Event: "UPDATE_BEGIN"},
+		updater.StageEvent{Timestamp: "2026-10-18T10:00:02Z", App: "a", Stage: "verify", Event: "PKG_VERIFY_FAILED", ErrorCode: "PKG_VERIFY", Message: "sha256 mismatch"},
+	)
+	writeEventLog(t, root, "b", "20261018",
+		updater.StageEvent{Timestamp: "2026-10-18T10:00:01Z", App: "b", Stage: "update", Event: "UPDATE_BEGIN"},
+	)
+
+	var out strings.Builder
+	var errOut strings.Builder
+	if code := Execute([]string{"log", "--root", root}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("log failed: %d %s", code, errOut.String())
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[1], "  b  ") ||
+		lines[2] != "2026-10-18T10:00:02Z  a  verify  PKG_VERIFY_FAILED  [PKG_VERIFY]  sha256 mismatch" {
+		t.Fatalf("unexpected log output:\n%s", out.String())
+	}
+
+	out.Reset()
+	if code := Execute([]string{"log", "--root", root, "--errors-only", "--json", "a"}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("log failed: %d %s", code, errOut.String())
+	}
+	var event updater.StageEvent
+	if err := json.Unmarshal([]byte(strings.TrimSpace(out.String())), &event); err != nil || event.ErrorCode != "PKG_VERIFY" {
+		t.Fatalf("expected one raw error event, got %q (%v)", out.String(), err)
+	}
+
+	out.Reset()
+	if code := Execute([]string{"log", "--root", root, "--since", "2026-10-18T10:00:01Z", "--event", "update_*"}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("log failed: %d %s", code, errOut.String())
+	}
+	if got := strings.TrimSpace(out.String()); !strings.HasPrefix(got, "2026-10-18T10:00:01Z  b  update  UPDATE_BEGIN") || strings.Contains(got, "\n") {
+		t.Fatalf("unexpected filtered output: %q", got)
+	}
+}
+
+func TestExecuteLogFollow(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	writeEventLog(t, root, "a", "20261018",
+		updater.StageEvent{Timestamp: "2026-10-18T10:00:00Z", App: "a", Stage: "update", Event: "UPDATE_BEGIN"})
+
+	oldContext, oldInterval := interruptContext, logFollowInterval
+	logFollowInterval = 10 * time.Millisecond
+	interruptContext = func() (context.Context, context.CancelFunc) {
+		// Append once following has started, then stop a little later.
+		time.AfterFunc(30*time.Millisecond, func() {
+			writeEventLog(t, root, "a", "20261018",
+				updater.StageEvent{Timestamp: "2026-10-18T10:00:05Z", App: "a", Stage: "update", Event: "UPDATE_DONE"})
+		})
+		return context.WithTimeout(context.Background(), 200*time.Millisecond)
+	}
+	t.Cleanup(func() { interruptContext, logFollowInterval = oldContext, oldInterval })
+
+	var out strings.Builder
+	var errOut strings.Builder
+	if code := Execute([]string{"log", "--root", root, "--follow", "--output", "json"}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("log --follow failed: %d %s", code, errOut.String())
+	}
+	events := decodeEvents(t, out.String())
+	results := eventsOfType(events, "result")
+	if len(results) != 2 || results[1]["data"].(map[string]any)["event"] != "UPDATE_DONE" {
+		t.Fatalf("expected the appended event to be followed, got %v", events)
+	}
+	if last := events[len(events)-1]; last["type"] != "summary" || last["events"] != float64(2) {
+		t.Fatalf("unexpected summary: %v", last)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	cases := map[string]time.Time{
+		"90m":                  now.Add(-90 * time.Minute),
+		"7d":                   now.AddDate(0, 0, -7),
+		"2026-10-01":           time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		"2026-10-18T08:00:00Z": time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC),
+	}
+	for raw, want := range cases {
+		got, err := parseSince(raw, now)
+		if err != nil || !got.Equal(want) {
+			t.Fatalf("parseSince(%q) = %v, %v; want %v", raw, got, err, want)
+		}
+	}
+	if _, err := parseSince("yesterday", now); err == nil {
+		t.Fatalf("expected invalid --since to fail")
+	}
+}
+
+func TestExecuteLogTransactionTimeline(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	tx := func(e updater.StageEvent) updater.StageEvent {
+		e.App, e.TxID, e.Version, e.PreviousVersion = "a", "20261018T100000-0a1b2c3d", "2.0", "1.0"
+		return e
+	}
+	writeEventLog(t, root, "a", "20261018",
+		tx(updater.StageEvent{Timestamp: "2026-10-18T10:00:00.000Z", Stage: "update", Event: "UPDATE_BEGIN"}),
+		updater.StageEvent{Timestamp: "2026-10-18T10:00:00.500Z", App: "a", Stage: "update", Event: "UPDATE_BEGIN", TxID: "other"},
+		tx(updater.StageEvent{Timestamp: "2026-10-18T10:00:01.250Z", Stage: "download", Event: "PKG_DOWNLOAD_FAILED", ErrorCode: "PKG_DOWNLOAD", ElapsedMS: 1200, Message: "boom"}),
+	)
+
+	var out strings.Builder
+	var errOut strings.Builder
+	if code := Execute([]string{"log", "--root", root, "--tx", "20261018T100000-0a1b2c3d"}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("log --tx failed: %d %s", code, errOut.String())
+	}
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	if len(lines) != 3 || lines[0] != "transaction 20261018T100000-0a1b2c3d: a 1.0 -> 2.0, started 2026-10-18T10:00:00.000Z" {
+		t.Fatalf("unexpected timeline:\n%s", out.String())
+	}
+	if !strings.HasPrefix(lines[2], "   +1250ms  download") || !strings.Contains(lines[2], "1200ms  [PKG_DOWNLOAD]  boom") {
+		t.Fatalf("unexpected timeline entry: %q", lines[2])
+	}
+
+	if code := Execute([]string{"log", "--root", root, "--tx", "missing"}, &out, &errOut, ""); code != 1 {
+		t.Fatalf("expected unknown transaction to fail, got %d", code)
+	}
+}
@@ -0,0 +1,226 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"appstract/internal/manifest"
+	"appstract/internal/updater"
+)
+
+const (
+	// outdatedWorkers is how many apps outdated checks at once.
+	outdatedWorkers = 4
+	// outdatedHostInterval spaces requests to the same host, so checking
+	// many apps stays clear of release API rate limits.
+	outdatedHostInterval = 250 * time.Millisecond
+)
+
+// checkAppVersion checks one app for outdated; tests replace it.
+var checkAppVersion = func(manager *updater.Manager, app string, man *manifest.Manifest) (updater.CheckResult, error) {
+	return manager.Check(app, man)
+}
+
+// outdatedEntry is one line of `outdated --json`.
+type outdatedEntry struct {
+	updater.CheckResult
+	Error     string `json:"error,omitempty"`
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+func executeOutdated(args []string, stdout, stderr io.Writer, envHome string) (code int) {
+	fs := flag.NewFlagSet("outdated", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	rootFlag := fs.String("root", "", "Appstract root directory")
+	outputFlag := fs.String("output", "", "Output level: silent|default|debug|json")
+	rawJSON := fs.Bool("json", false, "Print every checked app as a JSON array")
+	var excludes stringsFlag
+	fs.Var(&excludes, "exclude", "Skip apps matching this name or glob; repeatable")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			printCommandUsage("outdated", stdout)
+			return 0
+		}
+		return 1
+	}
+
+	root, executablePath, err := resolveRoot(envHome, *rootFlag)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	outputLevel, err := resolveOutputLevel(root, *outputFlag)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	output := newCommandOutput(outputLevel, stdout, stderr)
+	defer output.finish("outdated", &code)
+	if err := ensureWorkspaceReady(root, executablePath); err != nil {
+		output.printError("%v", err)
+		return 1
+	}
+
+	apps, err := installedApps(root)
+	if err != nil {
+		output.printError("%v", err)
+		return 1
+	}
+	if apps, err = selectApps(apps, fs.Args(), excludes); err != nil {
+		output.printError("%v", err)
+		return 1
+	}
+	manager, err := newManager(root, updateOptions{})
+	if err != nil {
+		output.printError("%v", err)
+		return 1
+	}
+
+	entries, errs := checkApps(root, manager, apps)
+	outdated := 0
+	failed := 0
+	for i, entry := range entries {
+		if errs[i] != nil {
+			failed++
+			output.printFailure(errs[i], "check failed: %s (%v)", entry.App, errs[i])
+			if code == 0 {
+				code = exitCodeFor(errs[i])
+			}
+		} else if entry.Outdated {
+			outdated++
+		}
+	}
+	output.setSummary("checked", len(entries))
+	output.setSummary("outdated", outdated)
+	output.setSummary("failed", failed)
+
+	switch {
+	case output.jsonMode():
+		output.printResult("outdated", entries)
+		return code
+	case *rawJSON:
+		b, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			output.printError("%v", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, string(b))
+		return code
+	case outdated == 0:
+		output.printDefault("all %d app(s) are up to date", len(entries)-failed)
+		return code
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "APP\tCURRENT\tAVAILABLE\tURL")
+	for _, entry := range entries {
+		if entry.Outdated {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", entry.App, entry.CurrentVersion, entry.LatestVersion, orDash(entry.URL))
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		output.printError("%v", err)
+		return 1
+	}
+	return code
+}
+
+// installedApps returns the apps that are installed and have a manifest,
+// the apps outdated can check.
+func installedApps(root string) ([]string, error) {
+	apps, err := updater.ListApps(root)
+	if err != nil {
+		return nil, err
+	}
+	installed := make([]string, 0, len(apps))
+	for _, app := range apps {
+		st, err := updater.ReadStatus(root, app)
+		if err != nil || !st.Installed || st.ManifestVersion == "" {
+			continue
+		}
+		installed = append(installed, app)
+	}
+	return installed, nil
+}
+
+// checkApps checks apps on outdatedWorkers goroutines and returns the
+// results and errors in the order of apps.
+func checkApps(root string, manager *updater.Manager, apps []string) ([]outdatedEntry, []error) {
+	entries := make([]outdatedEntry, len(apps))
+	errs := make([]error, len(apps))
+	limiter := &hostLimiter{interval: outdatedHostInterval, next: map[string]time.Time{}}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < outdatedWorkers && w < len(apps); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				entries[i], errs[i] = checkOneApp(root, manager, apps[i], limiter)
+			}
+		}()
+	}
+	for i := range apps {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return entries, errs
+}
+
+func checkOneApp(root string, manager *updater.Manager, app string, limiter *hostLimiter) (outdatedEntry, error) {
+	entry := outdatedEntry{CheckResult: updater.CheckResult{App: app}}
+	man, err := manifest.ParseFile(filepath.Join(root, "manifests", app+".json"))
+	if err != nil {
+		err = &updater.Error{Code: updater.ErrCodeManifestInvalid, Stage: "manifest", App: app, Err: err}
+		entry.Error, entry.ErrorCode = err.Error(), updater.ErrCodeManifestInvalid
+		return entry, err
+	}
+	if man.Checkver.GitHub != "" {
+		limiter.wait(hostOf(manager.GitHubAPIBase))
+	}
+	result, err := checkAppVersion(manager, app, man)
+	entry.CheckResult = result
+	entry.App = app
+	if err != nil {
+		entry.Error = err.Error()
+		if ue, ok := updater.AsError(err); ok {
+			entry.ErrorCode = ue.Code
+		}
+	}
+	return entry, err
+}
+
+// hostLimiter lets one request per interval through to each host.
+type hostLimiter struct {
+	interval time.Duration
+	mu       sync.Mutex
+	next     map[string]time.Time
+}
+
+func (l *hostLimiter) wait(host string) {
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	l.next[host] = at.Add(l.interval)
+	l.mu.Unlock()
+	time.Sleep(at.Sub(now))
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return u.Host
+}
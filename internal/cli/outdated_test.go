@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"appstract/internal/bootstrap"
+	"appstract/internal/manifest"
+	"appstract/internal/updater"
+)
+
+func TestExecuteOutdated(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	for app, version := range map[string]string{"git": "1.0.0", "python": "1.2.3", "node": "1.0.0", "chrome": ""} {
+		writeTestManifest(t, filepath.Join(root, "manifests", app+".json"), runManifestContent(app+".exe"))
+		if version != "" {
+			writeTestState(t, root, app, version)
+			os.MkdirAll(filepath.Join(root, "apps", app, "current"), 0o755)
+		}
+	}
+
+	var mu sync.Mutex
+	var checked []string
+	oldCheck := checkAppVersion
+	checkAppVersion = func(manager *updater.Manager, app string, man *manifest.Manifest) (updater.CheckResult, error) {
+		mu.Lock()
+		checked = append(checked, app)
+		mu.Unlock()
+		if app == "node" {
+			return updater.CheckResult{}, &updater.Error{Code: updater.ErrCodeNetCheckverHTTP, Stage: "checkver", App: app}
+		}
+		state, _ := updater.ReadState(root, app)
+		return updater.CheckResult{
+			App:            app,
+			CurrentVersion: state.CurrentVersion,
+			LatestVersion:  man.Version,
+			URL:            "https://example.com/app.zip",
+			Outdated:       state.CurrentVersion != man.Version,
+		}, nil
+	}
+	t.Cleanup(func() { checkAppVersion = oldCheck })
+
+	var out, errOut strings.Builder
+	if code := Execute([]string{"outdated", "--root", root, "--exclude", "node"}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("expected code 0, got %d, err=%s", code, errOut.String())
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], "git") || !strings.Contains(lines[1], "1.2.3") || !strings.Contains(lines[1], "https://example.com/app.zip") {
+		t.Fatalf("expected only git listed:\n%s", out.String())
+	}
+	if len(checked) != 2 {
+		t.Fatalf("expected the installed apps checked, got %v", checked)
+	}
+
+	out.Reset()
+	errOut.Reset()
+	if code := Execute([]string{"outdated", "--root", root, "--json"}, &out, &errOut, ""); code == 0 {
+		t.Fatalf("expected the failed check of node to fail the command")
+	}
+	var entries []outdatedEntry
+	if err := json.Unmarshal([]byte(out.String()), &entries); err != nil {
+		t.Fatalf("decode --json output: %v\n%s", err, out.String())
+	}
+	if len(entries) != 3 || entries[0].App != "git" || entries[1].App != "node" || entries[1].ErrorCode != updater.ErrCodeNetCheckverHTTP || entries[2].Outdated {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+
+	out.Reset()
+	if code := Execute([]string{"outdated", "--root", root, "--output", "json", "python"}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("expected code 0, got %d, err=%s", code, errOut.String())
+	}
+	if result := eventsOfType(decodeEvents(t, out.String()), "result"); len(result) != 1 {
+		t.Fatalf("expected one result event: %s", out.String())
+	}
+
+	// --output json wins over --json, so the stream stays one event per line.
+	out.Reset()
+	if code := Execute([]string{"outdated", "--root", root, "--output", "json", "--json", "python"}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("expected code 0, got %d, err=%s", code, errOut.String())
+	}
+	if result := eventsOfType(decodeEvents(t, out.String()), "result"); len(result) != 1 {
+		t.Fatalf("expected one result event with --json: %s", out.String())
+	}
+}
+
+func TestHostLimiterSpacesRequests(t *testing.T) {
+	limiter := &hostLimiter{interval: 20 * time.Millisecond, next: map[string]time.Time{}}
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		limiter.wait("api.github.com")
+	}
+	limiter.wait("example.com")
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond || elapsed > time.Second {
+		t.Fatalf("expected two waits on one host and none on another, took %s", elapsed)
+	}
+}
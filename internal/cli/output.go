@@ -2,6 +2,7 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -18,6 +19,10 @@ type commandOutput struct {
 	out   io.Writer
 	err   io.Writer
 	color bool
+	now   func() time.Time
+
+	// summary collects the fields of the final JSON summary event.
+	summary map[string]any
 
 	mu             sync.Mutex
 	progressActive bool
@@ -33,19 +38,88 @@ func newCommandOutput(level config.OutputLevel, out io.Writer, err io.Writer) *c
 		level: level,
 		out:   out,
 		err:   err,
-		color: shouldUseColor(out) && shouldUseColor(err),
+		color: level != config.OutputLevelJSON && shouldUseColor(out) && shouldUseColor(err),
+		now:   time.Now,
 	}
 }
 
+func (o *commandOutput) jsonMode() bool {
+	return o.level == config.OutputLevelJSON
+}
+
+// emit writes one NDJSON event to stdout. Every event carries its type and
+// a timestamp; the remaining keys depend on the type.
+func (o *commandOutput) emit(eventType string, fields map[string]any) {
+	event := make(map[string]any, len(fields)+2)
+	for k, v := range fields {
+		event[k] = v
+	}
+	event["type"] = eventType
+	event["time"] = o.now().UTC().Format(time.RFC3339Nano)
+	b, err := json.Marshal(event)
+	if err != nil {
+		b, _ = json.Marshal(map[string]any{"type": "error", "message": fmt.Sprintf("encode %s event: %v", eventType, err)})
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.out.Write(append(b, '\n'))
+}
+
+func (o *commandOutput) emitMessage(level, msg string) {
+	o.emit("message", map[string]any{"level": level, "message": msg})
+}
+
+// setSummary records a field for the summary event that finish emits.
+func (o *commandOutput) setSummary(key string, value any) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.summary == nil {
+		o.summary = map[string]any{}
+	}
+	o.summary[key] = value
+}
+
+// printResult hands structured command output (listings, reports, diffs) to
+// JSON consumers as a result event; text mode prints it itself.
+func (o *commandOutput) printResult(kind string, data any) {
+	o.emit("result", map[string]any{"kind": kind, "data": data})
+}
+
+// finish emits the closing summary event in JSON mode. Commands defer it
+// right after creating their output so every exit path is covered.
+func (o *commandOutput) finish(command string, code *int) {
+	if !o.jsonMode() {
+		return
+	}
+	o.mu.Lock()
+	fields := make(map[string]any, len(o.summary)+3)
+	for k, v := range o.summary {
+		fields[k] = v
+	}
+	o.mu.Unlock()
+	fields["command"] = command
+	fields["exit_code"] = *code
+	fields["ok"] = *code == 0
+	o.emit("summary", fields)
+}
+
 func (o *commandOutput) printDefault(format string, args ...any) {
 	if o.level == config.OutputLevelSilent {
 		return
 	}
 	msg := fmt.Sprintf(format, args...)
+	if o.jsonMode() {
+		o.emitMessage("info", msg)
+		return
+	}
 	o.writeLine(o.out, styleMessage(msg, o.color, styleDefault))
 }
 
 func (o *commandOutput) printDebug(format string, args ...any) {
+	if o.jsonMode() {
+		o.emitMessage("debug", fmt.Sprintf(format, args...))
+		return
+	}
 	if o.level != config.OutputLevelDebug {
 		return
 	}
@@ -53,11 +127,50 @@ func (o *commandOutput) printDebug(format string, args ...any) {
 	o.writeLine(o.out, styleMessage("[dbg] "+msg, o.color, styleDebug))
 }
 
+func (o *commandOutput) printWarning(format string, args ...any) {
+	if o.level == config.OutputLevelSilent {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	if o.jsonMode() {
+		o.emitMessage("warn", msg)
+		return
+	}
+	o.writeLine(o.err, styleMessage("[warn] "+msg, o.color, styleWarning))
+}
+
 func (o *commandOutput) printError(format string, args ...any) {
 	msg := fmt.Sprintf(format, args...)
+	if o.jsonMode() {
+		o.emit("error", map[string]any{"message": msg})
+		return
+	}
 	o.writeLine(o.err, styleMessage("[err] "+msg, o.color, styleError))
 }
 
+// printFailure is printError for errors that may come from the updater: in
+// JSON mode the error event also carries the code, stage, app, version and
+// retryable flag of the underlying updater.Error, and its reason when set.
+func (o *commandOutput) printFailure(err error, format string, args ...any) {
+	ue, ok := updater.AsError(err)
+	if !ok || !o.jsonMode() {
+		o.printError(format, args...)
+		return
+	}
+	data := map[string]any{
+		"message":   fmt.Sprintf(format, args...),
+		"code":      ue.Code,
+		"stage":     ue.Stage,
+		"app":       ue.App,
+		"version":   ue.Version,
+		"retryable": ue.Retryable,
+	}
+	if ue.Reason != "" {
+		data["reason"] = ue.Reason
+	}
+	o.emit("error", data)
+}
+
 func (o *commandOutput) writeLine(w io.Writer, line string) {
 	o.mu.Lock()
 	defer o.mu.Unlock()
@@ -70,6 +183,14 @@ func (o *commandOutput) writeLine(w io.Writer, line string) {
 }
 
 func (o *commandOutput) onUpdaterMessage(level updater.MessageLevel, msg string) {
+	if o.jsonMode() {
+		if level == updater.MessageLevelDebug {
+			o.emitMessage("debug", msg)
+		} else {
+			o.emitMessage("info", msg)
+		}
+		return
+	}
 	if level == updater.MessageLevelDefault && strings.HasSuffix(msg, "...") {
 		o.startTask(strings.TrimSuffix(msg, "..."))
 		return
@@ -86,6 +207,16 @@ func (o *commandOutput) onUpdaterProgress(progress updater.DownloadProgress) {
 	if o.level == config.OutputLevelSilent {
 		return
 	}
+	if o.jsonMode() {
+		o.emit("progress", map[string]any{
+			"app":        progress.AppName,
+			"url":        progress.URL,
+			"downloaded": progress.Downloaded,
+			"total":      progress.Total,
+			"done":       progress.Done,
+		})
+		return
+	}
 
 	line := renderDownloadLine(progress)
 	o.mu.Lock()
@@ -100,6 +231,26 @@ func (o *commandOutput) onUpdaterProgress(progress updater.DownloadProgress) {
 	o.progressActive = true
 }
 
+// onUpdaterEvent forwards stage transitions; text mode already shows the
+// matching messages, so only JSON consumers get them.
+func (o *commandOutput) onUpdaterEvent(event updater.StageEvent) {
+	if !o.jsonMode() {
+		return
+	}
+	fields := map[string]any{
+		"app":   event.App,
+		"stage": event.Stage,
+		"event": event.Event,
+	}
+	if event.ErrorCode != "" {
+		fields["code"] = event.ErrorCode
+	}
+	if event.Message != "" {
+		fields["message"] = event.Message
+	}
+	o.emit("stage", fields)
+}
+
 func renderDownloadLine(progress updater.DownloadProgress) string {
 	app := progress.AppName
 	if strings.TrimSpace(app) == "" {
@@ -148,7 +299,7 @@ func parseOutputLevel(raw string) (config.OutputLevel, error) {
 	}
 	level, ok := config.ParseOutputLevel(raw)
 	if !ok {
-		return "", fmt.Errorf("invalid output level %q (expected: silent|default|debug)", raw)
+		return "", fmt.Errorf("invalid output level %q (expected: silent|default|debug|json)", raw)
 	}
 	return level, nil
 }
@@ -260,6 +411,7 @@ const (
 	styleDebug
 	styleRunning
 	styleProgress
+	styleWarning
 )
 
 var spinnerFrames = []string{"|", "/", "-", `\`}
@@ -275,6 +427,8 @@ func styleMessage(msg string, useColor bool, fallback styleType) string {
 		return colorize(msg, styleError)
 	case strings.HasPrefix(msg, "[dbg]"):
 		return colorize(msg, styleDebug)
+	case strings.HasPrefix(msg, "[warn]"):
+		return colorize(msg, styleWarning)
 	default:
 		return colorize(msg, fallback)
 	}
@@ -289,7 +443,7 @@ func colorize(msg string, style styleType) string {
 		code = "31" // red
 	case styleDebug:
 		code = "36" // cyan
-	case styleRunning:
+	case styleRunning, styleWarning:
 		code = "33" // yellow
 	case styleProgress:
 		code = "34" // blue
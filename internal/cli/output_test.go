@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"appstract/internal/bootstrap"
+	"appstract/internal/updater"
+)
+
+func decodeEvents(t *testing.T, out string) []map[string]any {
+	t.Helper()
+	var events []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var event map[string]any
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("stdout line is not JSON: %q (%v)", line, err)
+		}
+		if event["type"] == nil || event["time"] == nil {
+			t.Fatalf("event without type or time: %v", event)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func eventsOfType(events []map[string]any, eventType string) []map[string]any {
+	var matched []map[string]any
+	for _, event := range events {
+		if event["type"] == eventType {
+			matched = append(matched, event)
+		}
+	}
+	return matched
+}
+
+func TestExecuteUpdateJSONOutput(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	manifests := filepath.Join(root, "manifests")
+	writeTestManifest(t, filepath.Join(manifests, "good.json"), runManifestContent("good.exe"))
+	writeTestManifest(t, filepath.Join(manifests, "bad.json"), runManifestContent("bad.exe"))
+
+	oldUpdate := executeUpdateFromManifest
+	executeUpdateFromManifest = func(updateRoot, app, path string, opts updateOptions) error {
+		opts.Output.onUpdaterMessage(updater.MessageLevelDefault, "downloading package...")
+		opts.Output.onUpdaterProgress(updater.DownloadProgress{AppName: app, URL: "https://example.com/app.zip", Downloaded: 10, Total: 10, Done: true})
+		if app == "bad" {
+			opts.Output.onUpdaterEvent(updater.StageEvent{App: app, Stage: "verify", Event: "PKG_VERIFY_FAILED", ErrorCode: updater.ErrCodePkgVerify, Message: "hash mismatch"})
+			return errors.New("hash mismatch")
+		}
+		opts.Output.onUpdaterEvent(updater.StageEvent{App: app, Stage: "update", Event: "UPDATE_DONE"})
+		return nil
+	}
+	t.Cleanup(func() { executeUpdateFromManifest = oldUpdate })
+
+	var out strings.Builder
+	var errOut strings.Builder
+	if code := Execute([]string{"update", "--root", root, "--output", "json"}, &out, &errOut, ""); code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+	if errOut.Len() != 0 {
+		t.Fatalf("json mode must keep stderr empty, got %q", errOut.String())
+	}
+	events := decodeEvents(t, out.String())
+
+	if progress := eventsOfType(events, "progress"); len(progress) != 2 || progress[0]["done"] != true || progress[0]["total"] != float64(10) {
+		t.Fatalf("unexpected progress events: %v", progress)
+	}
+	stages := eventsOfType(events, "stage")
+	if len(stages) != 2 || stages[0]["code"] != updater.ErrCodePkgVerify || stages[0]["app"] != "bad" {
+		t.Fatalf("unexpected stage events: %v", stages)
+	}
+	if errs := eventsOfType(events, "error"); len(errs) != 1 || !strings.Contains(errs[0]["message"].(string), "update failed: bad") {
+		t.Fatalf("unexpected error events: %v", errs)
+	}
+	messages := eventsOfType(events, "message")
+	if len(messages) == 0 || messages[0]["level"] != "info" {
+		t.Fatalf("expected info messages, got %v", messages)
+	}
+	last := events[len(events)-1]
+	if last["type"] != "summary" || last["command"] != "update" || last["ok"] != false ||
+		last["exit_code"] != float64(1) || last["total"] != float64(2) || last["success"] != float64(1) || last["failed"] != float64(1) {
+		t.Fatalf("unexpected summary: %v", last)
+	}
+}
+
+func TestExecuteSearchJSONOutput(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	writeTestManifest(t, filepath.Join(root, "manifests", "chrome.json"), runManifestContent("chrome.exe"))
+
+	var out strings.Builder
+	var errOut strings.Builder
+	if code := Execute([]string{"search", "--root", root, "--output", "json", "chrome"}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("search failed: %d %s", code, errOut.String())
+	}
+	events := decodeEvents(t, out.String())
+	results := eventsOfType(events, "result")
+	if len(results) != 1 || results[0]["kind"] != "search" {
+		t.Fatalf("expected one search result event, got %v", events)
+	}
+	rows := results[0]["data"].([]any)
+	if len(rows) != 1 || rows[0].(map[string]any)["app"] != "chrome" {
+		t.Fatalf("unexpected search rows: %v", rows)
+	}
+	if last := events[len(events)-1]; last["type"] != "summary" || last["matches"] != float64(1) || last["ok"] != true {
+		t.Fatalf("unexpected summary: %v", last)
+	}
+}
+
+func TestExecuteUpdateMapsUpdaterErrorToExitCode(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	manifests := filepath.Join(root, "manifests")
+	writeTestManifest(t, filepath.Join(manifests, "a.json"), runManifestContent("a.exe"))
+	writeTestManifest(t, filepath.Join(manifests, "b.json"), runManifestContent("b.exe"))
+
+	oldUpdate := executeUpdateFromManifest
+	executeUpdateFromManifest = func(updateRoot, app, path string, opts updateOptions) error {
+		if app == "a" {
+			return &updater.Error{Code: updater.ErrCodePkgVerify, Stage: "verify", App: app, Version: "1.2.3", Err: errors.New("sha256 mismatch")}
+		}
+		return &updater.Error{Code: updater.ErrCodeLockBusy, Stage: "lock", App: app, Retryable: true, Err: errors.New("update already running")}
+	}
+	t.Cleanup(func() { executeUpdateFromManifest = oldUpdate })
+
+	var out strings.Builder
+	var errOut strings.Builder
+	if code := Execute([]string{"update", "--root", root}, &out, &errOut, ""); code != updaterExitCodes[updater.ErrCodePkgVerify] {
+		t.Fatalf("expected exit code of first failure, got %d", code)
+	}
+
+	out.Reset()
+	if code := Execute([]string{"update", "--root", root, "--output", "json"}, &out, &errOut, ""); code != 31 {
+		t.Fatalf("expected exit code 31, got %d", code)
+	}
+	errs := eventsOfType(decodeEvents(t, out.String()), "error")
+	if len(errs) != 2 {
+		t.Fatalf("expected two error events, got %v", errs)
+	}
+	if errs[0]["code"] != updater.ErrCodePkgVerify || errs[0]["stage"] != "verify" || errs[0]["app"] != "a" ||
+		errs[0]["version"] != "1.2.3" || errs[0]["retryable"] != false {
+		t.Fatalf("unexpected first error event: %v", errs[0])
+	}
+	if errs[1]["code"] != updater.ErrCodeLockBusy || errs[1]["retryable"] != true {
+		t.Fatalf("unexpected second error event: %v", errs[1])
+	}
+}
+
+func TestUpdaterExitCodesAreDistinct(t *testing.T) {
+	seen := map[int]string{}
+	for code, exit := range updaterExitCodes {
+		if exit == exitFailure || exit == 0 {
+			t.Fatalf("%s maps to reserved exit code %d", code, exit)
+		}
+		if other, ok := seen[exit]; ok {
+			t.Fatalf("%s and %s share exit code %d", code, other, exit)
+		}
+		seen[exit] = code
+	}
+}
@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"appstract/internal/bootstrap"
+	"appstract/internal/catalog"
+)
+
+func stubManifestFetch(t *testing.T, content *string) *[]string {
+	t.Helper()
+	var fetched []string
+	oldFetch := fetchManifestURL
+	fetchManifestURL = func(url string) ([]byte, error) {
+		fetched = append(fetched, url)
+		return []byte(*content), nil
+	}
+	oldUpdate := executeUpdateFromManifest
+	executeUpdateFromManifest = func(updateRoot, app, path string, opts updateOptions) error {
+		return nil
+	}
+	t.Cleanup(func() {
+		fetchManifestURL = oldFetch
+		executeUpdateFromManifest = oldUpdate
+	})
+	return &fetched
+}
+
+func TestExecuteAddFromURLRecordsOriginAndPin(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	content := runManifestContent("tool.exe")
+	fetched := stubManifestFetch(t, &content)
+	sum := sha256.Sum256([]byte(content))
+	pin := hex.EncodeToString(sum[:])
+
+	var out strings.Builder
+	var errOut strings.Builder
+	url := "https://example.com/bucket/tool.json"
+	if code := Execute([]string{"add", "--root", root, "--sha256", pin, url}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("add from url failed: %d %s", code, errOut.String())
+	}
+	if len(*fetched) != 1 || (*fetched)[0] != url {
+		t.Fatalf("unexpected fetches: %v", *fetched)
+	}
+	saved, err := os.ReadFile(filepath.Join(root, "manifests", "tool.json"))
+	if err != nil || string(saved) != content {
+		t.Fatalf("expected downloaded manifest saved, got %s (%v)", saved, err)
+	}
+	origins, err := catalog.LoadOrigins(root)
+	if err != nil {
+		t.Fatalf("load origins failed: %v", err)
+	}
+	if origins["tool"].URL != url || origins["tool"].SHA256 != pin {
+		t.Fatalf("unexpected origin: %+v", origins["tool"])
+	}
+}
+
+func TestExecuteAddFromURLRejectsPinMismatch(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	content := runManifestContent("tool.exe")
+	stubManifestFetch(t, &content)
+
+	var out strings.Builder
+	var errOut strings.Builder
+	code := Execute([]string{"add", "--root", root, "--name", "renamed", "--sha256", strings.Repeat("0", 64), "https://example.com/manifest"}, &out, &errOut, "")
+	if code != 1 || !strings.Contains(errOut.String(), "sha256 mismatch") {
+		t.Fatalf("expected pin mismatch, got %d %s", code, errOut.String())
+	}
+	if _, err := os.Stat(filepath.Join(root, "manifests", "renamed.json")); !os.IsNotExist(err) {
+		t.Fatalf("manifest must not be saved on pin mismatch: %v", err)
+	}
+}
+
+func TestExecuteUpdateRefreshManifestsShowsDiffAndAsks(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	content := runManifestContent("tool.exe")
+	stubManifestFetch(t, &content)
+	var out strings.Builder
+	var errOut strings.Builder
+	if code := Execute([]string{"add", "--root", root, "https://example.com/tool.json"}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("add from url failed: %d %s", code, errOut.String())
+	}
+
+	content = strings.Replace(content, `"1.2.3"`, `"1.3.0"`, 1)
+	var questions []string
+	answer := false
+	oldConfirm := confirmPrompt
+	confirmPrompt = func(question string) (bool, error) {
+		questions = append(questions, question)
+		return answer, nil
+	}
+	t.Cleanup(func() { confirmPrompt = oldConfirm })
+	manifestPath := filepath.Join(root, "manifests", "tool.json")
+
+	out.Reset()
+	errOut.Reset()
+	if code := Execute([]string{"update", "--root", root, "--refresh-manifests"}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("update failed: %d %s", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "-\t\t\"version\": \"1.2.3\",\n+\t\t\"version\": \"1.3.0\",") || !strings.Contains(out.String(), "kept current manifest: tool") {
+		t.Fatalf("expected diff and declined refresh: %s", out.String())
+	}
+	if len(questions) != 1 {
+		t.Fatalf("expected one prompt, got %v", questions)
+	}
+	if saved, _ := os.ReadFile(manifestPath); strings.Contains(string(saved), "1.3.0") {
+		t.Fatalf("declined refresh must not write manifest")
+	}
+
+	out.Reset()
+	errOut.Reset()
+	if code := Execute([]string{"update", "--root", root, "--refresh-manifests", "--yes"}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("update failed: %d %s", code, errOut.String())
+	}
+	if len(questions) != 1 || !strings.Contains(out.String(), "manifest refreshed: tool (https://example.com/tool.json)") {
+		t.Fatalf("expected accepted refresh without prompt: %v %s", questions, out.String())
+	}
+	if saved, _ := os.ReadFile(manifestPath); string(saved) != content {
+		t.Fatalf("expected refreshed manifest written, got %s", saved)
+	}
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"appstract/internal/appenv"
+	"appstract/internal/catalog"
+	"appstract/internal/config"
+	"appstract/internal/manifest"
+	"appstract/internal/updater"
+)
+
+func executeRemove(args []string, stdout, stderr io.Writer, envHome string) (code int) {
+	fs := flag.NewFlagSet("remove", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	rootFlag := fs.String("root", "", "Appstract root directory")
+	outputFlag := fs.String("output", "", "Output level: silent|default|debug|json")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			printCommandUsage("remove", stdout)
+			return 0
+		}
+		return 1
+	}
+	if fs.NArg() != 1 {
+		printCommandUsage("remove", stderr)
+		return 1
+	}
+	app := fs.Arg(0)
+	if !manifest.IsAppName(app) {
+		fmt.Fprintf(stderr, "invalid app name: %s\n", app)
+		return 1
+	}
+
+	root, executablePath, err := resolveRoot(envHome, *rootFlag)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	outputLevel, err := resolveOutputLevel(root, *outputFlag)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	output := newCommandOutput(outputLevel, stdout, stderr)
+	defer output.finish("remove", &code)
+	if err := ensureWorkspaceReady(root, executablePath); err != nil {
+		output.printError("%v", err)
+		return 1
+	}
+
+	manifestPath := filepath.Join(root, "manifests", app+".json")
+	appDir := filepath.Join(root, "apps", app)
+	if !pathExists(manifestPath) && !pathExists(appDir) {
+		output.printError("app %q is not installed", app)
+		return 1
+	}
+	dependents, err := catalog.Dependents(root, app)
+	if err != nil {
+		output.printError("%v", err)
+		return 1
+	}
+	if len(dependents) > 0 {
+		output.printError("cannot remove %s: required by %s (remove them first)", app, strings.Join(dependents, ", "))
+		return 1
+	}
+
+	output.setSummary("app", app)
+	output.printDefault("remove start: %s", app)
+	if pathExists(appDir) {
+		if err := removeAppFiles(root, app); err != nil {
+			output.printFailure(err, "remove app directory: %v", err)
+			return exitCodeFor(err)
+		}
+	}
+	if err := os.Remove(manifestPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		output.printError("remove manifest: %v", err)
+		return 1
+	}
+	if err := catalog.ForgetOrigin(root, app); err != nil {
+		output.printError("forget manifest origin: %v", err)
+		return 1
+	}
+	if err := regenerateEnvironment(root); err != nil {
+		output.printError("update environment: %v", err)
+		return 1
+	}
+	output.printDefault("[ok] remove completed: %s", app)
+	return 0
+}
+
+// removeAppFiles deletes apps/<app> under the app lock, stopping the app
+// first; tests replace it.
+var removeAppFiles = func(root, app string) error {
+	return updater.NewManager(root).Remove(app)
+}
+
+var regenerateEnvironment = func(root string) error {
+	cfg, err := config.Load(root)
+	if err != nil {
+		return err
+	}
+	_, err = appenv.Regenerate(root, appenv.Backends(root, cfg.EnvBackend)...)
+	return err
+}
+
+func pathExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"appstract/internal/catalog"
+	"appstract/internal/config"
+	"appstract/internal/updater"
+)
+
+func executeSearch(args []string, stdout, stderr io.Writer, envHome string) (code int) {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	rootFlag := fs.String("root", "", "Appstract root directory")
+	outputFlag := fs.String("output", "", "Output level: silent|default|debug|json")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			printCommandUsage("search", stdout)
+			return 0
+		}
+		return 1
+	}
+	if fs.NArg() != 1 {
+		printCommandUsage("search", stderr)
+		return 1
+	}
+	query := fs.Arg(0)
+
+	root, executablePath, err := resolveRoot(envHome, *rootFlag)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	outputLevel, err := resolveOutputLevel(root, *outputFlag)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	output := newCommandOutput(outputLevel, stdout, stderr)
+	defer output.finish("search", &code)
+	if err := ensureWorkspaceReady(root, executablePath); err != nil {
+		output.printError("%v", err)
+		return 1
+	}
+	cfg, err := config.Load(root)
+	if err != nil {
+		output.printError("%v", err)
+		return 1
+	}
+
+	results, err := catalog.Search(root, cfg, query)
+	if err != nil {
+		output.printError("search manifests: %v", err)
+		return 1
+	}
+	if len(results) == 0 {
+		output.printDefault("no apps match %q", query)
+		return 0
+	}
+	type searchRow struct {
+		App         string `json:"app"`
+		Version     string `json:"version"`
+		Source      string `json:"source"`
+		Installed   string `json:"installed,omitempty"`
+		Description string `json:"description,omitempty"`
+	}
+	rows := make([]searchRow, 0, len(results))
+	for _, entry := range results {
+		state, err := updater.ReadState(root, entry.App)
+		if err != nil {
+			output.printDebug("read state for %s: %v", entry.App, err)
+		}
+		rows = append(rows, searchRow{
+			App:         entry.App,
+			Version:     entry.Version,
+			Source:      entry.Source,
+			Installed:   state.CurrentVersion,
+			Description: entry.Description,
+		})
+	}
+	output.setSummary("matches", len(rows))
+	if output.jsonMode() {
+		output.printResult("search", rows)
+		return 0
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tVERSION\tSOURCE\tINSTALLED\tDESCRIPTION")
+	for _, row := range rows {
+		installed := row.Installed
+		if installed == "" {
+			installed = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", row.App, row.Version, row.Source, installed, row.Description)
+	}
+	if err := tw.Flush(); err != nil {
+		output.printError("%v", err)
+		return 1
+	}
+	output.printDebug("%d match(es)", len(results))
+	return 0
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"appstract/internal/bootstrap"
+)
+
+func TestExecuteSearchShowsVersionAndInstalledState(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "manifests", "chrome.json"), []byte(runManifestContent("chrome.exe")), 0o644); err != nil {
+		t.Fatalf("write manifest failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "manifests", "firefox.json"), []byte(runManifestContent("firefox.exe")), 0o644); err != nil {
+		t.Fatalf("write manifest failed: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "apps", "chrome"), 0o755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "apps", "chrome", "runtime.json"), []byte(`{"current_version":"1.2.0"}`), 0o644); err != nil {
+		t.Fatalf("write state failed: %v", err)
+	}
+
+	var out strings.Builder
+	var errOut strings.Builder
+	code := Execute([]string{"search", "--root", root, "chrome"}, &out, &errOut, "")
+	if code != 0 {
+		t.Fatalf("expected code 0, got %d, err=%s", code, errOut.String())
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "NAME") {
+		t.Fatalf("unexpected search output: %s", out.String())
+	}
+	if fields := strings.Fields(lines[1]); len(fields) < 4 || fields[0] != "chrome" || fields[1] != "1.2.3" || fields[2] != "local" || fields[3] != "1.2.0" {
+		t.Fatalf("unexpected result row: %q", lines[1])
+	}
+	if _, err := os.Stat(filepath.Join(root, "cache", "search-index.json")); err != nil {
+		t.Fatalf("expected cached index: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// stringsFlag collects a flag that may be given more than once.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *stringsFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+// selectApps returns the candidates that match any of patterns, or all of
+// them when there are none, minus those matching any of excludes, in
+// candidate order. Patterns are app names or globs such as "py*", matched
+// case-insensitively. A pattern that matches nothing is an error, so a
+// mistyped name is not silently skipped.
+func selectApps(candidates, patterns, excludes []string) ([]string, error) {
+	for _, p := range append(append([]string{}, patterns...), excludes...) {
+		if _, err := path.Match(strings.ToLower(p), ""); err != nil {
+			return nil, fmt.Errorf("invalid app pattern %q: %v", p, err)
+		}
+	}
+	selected := candidates
+	if len(patterns) > 0 {
+		selected = nil
+		matched := make([]bool, len(patterns))
+		for _, app := range candidates {
+			hit := false
+			for i, p := range patterns {
+				if matchApp(p, app) {
+					matched[i] = true
+					hit = true
+				}
+			}
+			if hit {
+				selected = append(selected, app)
+			}
+		}
+		for i, p := range patterns {
+			if !matched[i] {
+				return nil, fmt.Errorf("no app matches %q", p)
+			}
+		}
+	}
+	if len(excludes) == 0 {
+		return selected, nil
+	}
+	kept := make([]string, 0, len(selected))
+	for _, app := range selected {
+		excluded := false
+		for _, p := range excludes {
+			if matchApp(p, app) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			kept = append(kept, app)
+		}
+	}
+	return kept, nil
+}
+
+func matchApp(pattern, app string) bool {
+	ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(app))
+	return ok
+}
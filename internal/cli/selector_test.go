@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"appstract/internal/bootstrap"
+	"appstract/internal/manifest"
+	"appstract/internal/updater"
+)
+
+func TestSelectApps(t *testing.T) {
+	apps := []string{"7zip", "Git", "python", "python2", "pypy"}
+	cases := []struct {
+		patterns, excludes []string
+		want               string
+		err                string
+	}{
+		{nil, nil, "7zip,Git,python,python2,pypy", ""},
+		{[]string{"git"}, nil, "Git", ""},
+		{[]string{"py*", "7zip"}, nil, "7zip,python,python2,pypy", ""},
+		{[]string{"py*"}, []string{"python?"}, "python,pypy", ""},
+		{nil, []string{"py*", "GIT"}, "7zip", ""},
+		{[]string{"git", "node"}, nil, "", `no app matches "node"`},
+		{[]string{"[py"}, nil, "", "invalid app pattern"},
+	}
+	for _, tc := range cases {
+		got, err := selectApps(apps, tc.patterns, tc.excludes)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("%v -%v: expected error %q, got %v", tc.patterns, tc.excludes, tc.err, err)
+			}
+			continue
+		}
+		if err != nil || strings.Join(got, ",") != tc.want {
+			t.Fatalf("%v -%v: expected %s, got %v %v", tc.patterns, tc.excludes, tc.want, got, err)
+		}
+	}
+}
+
+func TestExecuteUpdateSelectsApps(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	for _, app := range []string{"git", "python", "python2", "pypy"} {
+		writeTestManifest(t, filepath.Join(root, "manifests", app+".json"), runManifestContent(app+".exe"))
+	}
+	// python is installed at the latest version, pypy at an older one.
+	writeTestState(t, root, "python", "1.2.3")
+	writeTestState(t, root, "pypy", "1.0.0")
+
+	var updated []string
+	var checkver bool
+	oldUpdate := executeUpdateFromManifest
+	executeUpdateFromManifest = func(updateRoot, app, path string, opts updateOptions) error {
+		updated = append(updated, app)
+		checkver = opts.Checkver
+		return nil
+	}
+	t.Cleanup(func() { executeUpdateFromManifest = oldUpdate })
+	oldCheck := checkAppVersion
+	checkAppVersion = func(manager *updater.Manager, app string, man *manifest.Manifest) (updater.CheckResult, error) {
+		if app == "pypy" {
+			return updater.CheckResult{}, &updater.Error{Code: updater.ErrCodeNetCheckverHTTP, Stage: "checkver", Err: fmt.Errorf("checkver failed")}
+		}
+		state, _ := updater.ReadState(root, app)
+		return updater.CheckResult{App: app, CurrentVersion: state.CurrentVersion, LatestVersion: "1.2.3", Outdated: state.CurrentVersion != "1.2.3"}, nil
+	}
+	t.Cleanup(func() { checkAppVersion = oldCheck })
+
+	var out, errOut strings.Builder
+	if code := Execute([]string{"update", "--root", root, "--exclude", "python2", "py*", "git"}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("expected code 0, got %d, err=%s", code, errOut.String())
+	}
+	if strings.Join(updated, ",") != "git,pypy,python" {
+		t.Fatalf("unexpected updated apps: %v", updated)
+	}
+
+	// python is current; pypy's check fails, so it is reported rather than
+	// taken for outdated; python2 is not installed yet.
+	updated = nil
+	out.Reset()
+	errOut.Reset()
+	if code := Execute([]string{"update", "--root", root, "--only-outdated", "py*"}, &out, &errOut, ""); code != exitCodeFor(&updater.Error{Code: updater.ErrCodeNetCheckverHTTP}) {
+		t.Fatalf("expected the failed check in the exit code, got %d, err=%s", code, errOut.String())
+	}
+	if strings.Join(updated, ",") != "python2" || !checkver {
+		t.Fatalf("expected a checkver update of python2 only, got %v checkver=%v", updated, checkver)
+	}
+	if !strings.Contains(out.String(), "skip python: 1.2.3 is the latest version") || !strings.Contains(errOut.String(), "check failed: pypy") {
+		t.Fatalf("expected python skipped and pypy reported: %s %s", out.String(), errOut.String())
+	}
+}
+
+func writeTestState(t *testing.T, root, app, version string) {
+	t.Helper()
+	appDir := filepath.Join(root, "apps", app)
+	if err := os.MkdirAll(appDir, 0o755); err != nil {
+		t.Fatalf("create app dir: %v", err)
+	}
+	writeTestManifest(t, filepath.Join(appDir, "runtime.json"), `{"current_version":"`+version+`"}`)
+}
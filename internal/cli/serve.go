@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"appstract/internal/server"
+	"appstract/internal/updater"
+)
+
+const defaultServeListen = "127.0.0.1:8765"
+
+func executeServe(args []string, stdout, stderr io.Writer, envHome string) (code int) {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	rootFlag := fs.String("root", "", "Appstract root directory")
+	outputFlag := fs.String("output", "", "Output level: silent|default|debug|json")
+	listen := fs.String("listen", defaultServeListen, "Loopback address to listen on")
+	tokenFlag := fs.String("token", "", "Bearer token clients must send (default: generated into serve.token)")
+	schedule := fs.Bool("schedule", false, "Also run scheduled update checks, as the daemon command does")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			printCommandUsage("serve", stdout)
+			return 0
+		}
+		return 1
+	}
+	if fs.NArg() != 0 {
+		printCommandUsage("serve", stderr)
+		return 1
+	}
+	if err := checkLoopbackAddr(*listen); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	root, executablePath, err := resolveRoot(envHome, *rootFlag)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	outputLevel, err := resolveOutputLevel(root, *outputFlag)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	output := newCommandOutput(outputLevel, stdout, stderr)
+	defer output.finish("serve", &code)
+	if err := ensureWorkspaceReady(root, executablePath); err != nil {
+		output.printError("%v", err)
+		return 1
+	}
+
+	token := *tokenFlag
+	tokenPath := filepath.Join(root, "serve.token")
+	if token == "" {
+		if token, err = writeServeToken(tokenPath); err != nil {
+			output.printError("create token: %v", err)
+			return 1
+		}
+		output.printDefault("token written to %s", tokenPath)
+	}
+
+	srv := server.New(root, token)
+	srv.NewManager = func() (*updater.Manager, error) {
+		return newManager(root, updateOptions{})
+	}
+	srv.Launch = runLaunch
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		output.printError("listen on %s: %v", *listen, err)
+		return 1
+	}
+	httpServer := &http.Server{Handler: srv.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	served := make(chan error, 1)
+	go func() { served <- httpServer.Serve(listener) }()
+	addr := listener.Addr().String()
+	output.setSummary("listen", addr)
+	output.printDefault("[ok] serving on http://%s/v1", addr)
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+	scheduled := make(chan struct{})
+	if *schedule {
+		sched := newScheduler(root, output, srv.ScheduledUpdate)
+		go func() {
+			defer close(scheduled)
+			sched.Run(ctx)
+		}()
+		output.printDefault("[ok] scheduler started")
+	} else {
+		close(scheduled)
+	}
+	select {
+	case err := <-served:
+		cancel()
+		<-scheduled
+		output.printError("serve: %v", err)
+		return 1
+	case <-ctx.Done():
+	}
+	output.printDefault("shutting down, waiting for running updates...")
+	<-scheduled
+	// Streams first, or Shutdown waits on them; updates last, once no
+	// request can start another.
+	srv.Close()
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutdownCancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		output.printError("shutdown: %v", err)
+		return 1
+	}
+	srv.Wait()
+	output.printDefault("[ok] server stopped")
+	return 0
+}
+
+// checkLoopbackAddr refuses listen addresses other machines could reach; the
+// API can install and launch programs.
+func checkLoopbackAddr(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid --listen %q: %v", addr, err)
+	}
+	if strings.EqualFold(host, "localhost") {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("invalid --listen %q: only loopback addresses are allowed", addr)
+}
+
+// writeServeToken generates a random token and writes it where local
+// clients of the same user can read it.
+func writeServeToken(path string) (string, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b[:])
+	if err := os.WriteFile(path, []byte(token+"\n"), 0o600); err != nil {
+		return "", err
+	}
+	// WriteFile keeps the mode of an existing file.
+	if err := os.Chmod(path, 0o600); err != nil {
+		return "", err
+	}
+	return token, nil
+}
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"appstract/internal/bootstrap"
+)
+
+func TestExecuteServeRejectsNonLoopbackListen(t *testing.T) {
+	var out strings.Builder
+	var errOut strings.Builder
+	if code := Execute([]string{"serve", "--root", t.TempDir(), "--listen", "0.0.0.0:8765"}, &out, &errOut, ""); code != 1 {
+		t.Fatalf("expected non-loopback listen to fail, got %d", code)
+	}
+	if !strings.Contains(errOut.String(), "only loopback addresses") {
+		t.Fatalf("unexpected error output: %q", errOut.String())
+	}
+}
+
+func TestExecuteServeWritesTokenAndStops(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	oldContext := interruptContext
+	interruptContext = func() (context.Context, context.CancelFunc) {
+		return context.WithTimeout(context.Background(), 50*time.Millisecond)
+	}
+	t.Cleanup(func() { interruptContext = oldContext })
+
+	var out strings.Builder
+	var errOut strings.Builder
+	if code := Execute([]string{"serve", "--root", root, "--listen", "127.0.0.1:0", "--output", "json"}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("serve failed: %d %s", code, errOut.String())
+	}
+	events := decodeEvents(t, out.String())
+	last := events[len(events)-1]
+	if last["type"] != "summary" || !strings.HasPrefix(last["listen"].(string), "127.0.0.1:") {
+		t.Fatalf("unexpected summary: %v", last)
+	}
+	info, err := os.Stat(filepath.Join(root, "serve.token"))
+	if err != nil {
+		t.Fatalf("expected a generated token file: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected token file mode 0600, got %v", info.Mode().Perm())
+	}
+}
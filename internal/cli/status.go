@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"appstract/internal/updater"
+)
+
+func executeStatus(args []string, stdout, stderr io.Writer, envHome string) (code int) {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	rootFlag := fs.String("root", "", "Appstract root directory")
+	outputFlag := fs.String("output", "", "Output level: silent|default|debug|json")
+	var excludes stringsFlag
+	fs.Var(&excludes, "exclude", "Skip apps matching this name or glob; repeatable")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			printCommandUsage("status", stdout)
+			return 0
+		}
+		return 1
+	}
+
+	root, executablePath, err := resolveRoot(envHome, *rootFlag)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	outputLevel, err := resolveOutputLevel(root, *outputFlag)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	output := newCommandOutput(outputLevel, stdout, stderr)
+	defer output.finish("status", &code)
+	if err := ensureWorkspaceReady(root, executablePath); err != nil {
+		output.printError("%v", err)
+		return 1
+	}
+
+	apps, err := updater.ListApps(root)
+	if err != nil {
+		output.printError("%v", err)
+		return 1
+	}
+	if apps, err = selectApps(apps, fs.Args(), excludes); err != nil {
+		output.printError("%v", err)
+		return 1
+	}
+	statuses := make([]updater.AppStatus, 0, len(apps))
+	for _, name := range apps {
+		st, err := updater.ReadStatus(root, name)
+		if err != nil {
+			output.printError("%s: %v", name, err)
+			return 1
+		}
+		statuses = append(statuses, st)
+	}
+	output.setSummary("apps", len(statuses))
+	if output.jsonMode() {
+		output.printResult("status", statuses)
+		return 0
+	}
+	if len(statuses) == 0 {
+		output.printDefault("no apps in %s", root)
+		return 0
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "APP\tCURRENT\tPENDING\tMANIFEST\tLAST CHECK\tSTATE")
+	for _, st := range statuses {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", st.App, orDash(st.CurrentVersion), orDash(st.PendingVersion),
+			orDash(st.ManifestVersion), orDash(st.LastCheckAt), statusState(st))
+	}
+	if err := tw.Flush(); err != nil {
+		output.printError("%v", err)
+		return 1
+	}
+	for _, st := range statuses {
+		if st.LastErrorMsg != "" && !st.Locked {
+			output.printDefault("%s: %s", st.App, st.LastErrorMsg)
+		}
+	}
+	return 0
+}
+
+// statusState sums up what an app is doing or how its last update ended.
+func statusState(st updater.AppStatus) string {
+	switch {
+	case st.Locked && st.LockPID > 0:
+		return fmt.Sprintf("updating (pid %d)", st.LockPID)
+	case st.Locked:
+		return "updating"
+	case !st.Installed:
+		return "not installed"
+	case st.LastErrorCode != "" && st.ConsecutiveFailures > 1:
+		return fmt.Sprintf("failed: %s (%d in a row)", st.LastErrorCode, st.ConsecutiveFailures)
+	case st.LastErrorCode != "":
+		return "failed: " + st.LastErrorCode
+	case st.PendingVersion != "":
+		return "switch pending"
+	}
+	return "ok"
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
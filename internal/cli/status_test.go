@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"appstract/internal/bootstrap"
+	"appstract/internal/updater"
+)
+
+func TestExecuteStatus(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	for app, state := range map[string]string{
+		"chrome": `{"current_version":"1.2.3","pending_version":"1.3.0","last_check_at":"2026-10-18T10:00:00Z"}`,
+		"git":    `{"current_version":"2.0","last_error_code":"NET_DOWNLOAD","last_error_message":"connection reset","consecutive_failures":3}`,
+	} {
+		appDir := filepath.Join(root, "apps", app)
+		os.MkdirAll(filepath.Join(appDir, "current"), 0o755)
+		os.WriteFile(filepath.Join(appDir, "runtime.json"), []byte(state), 0o644)
+		writeTestManifest(t, filepath.Join(root, "manifests", app+".json"), runManifestContent(app+".exe"))
+	}
+	// A background update of git holds the lock.
+	os.WriteFile(filepath.Join(root, "apps", "git", ".lock"), []byte(`{"pid":`+strconv.Itoa(os.Getpid())+`}`), 0o644)
+
+	var out, errOut strings.Builder
+	if code := Execute([]string{"status", "--root", root}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("expected code 0, got %d, err=%s", code, errOut.String())
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[1], "switch pending") || !strings.Contains(lines[2], "updating (pid ") {
+		t.Fatalf("unexpected status table:\n%s", out.String())
+	}
+
+	os.Remove(filepath.Join(root, "apps", "git", ".lock"))
+	out.Reset()
+	if code := Execute([]string{"status", "--root", root, "git"}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("expected code 0, got %d, err=%s", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "failed: NET_DOWNLOAD (3 in a row)") || !strings.Contains(out.String(), "git: connection reset") {
+		t.Fatalf("expected the failed background update shown:\n%s", out.String())
+	}
+
+	out.Reset()
+	if code := Execute([]string{"status", "--root", root, "--output", "json", "chrome"}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("expected code 0, got %d, err=%s", code, errOut.String())
+	}
+	result := eventsOfType(decodeEvents(t, out.String()), "result")
+	if len(result) != 1 {
+		t.Fatalf("expected one result event: %s", out.String())
+	}
+	b, _ := json.Marshal(result[0]["data"])
+	var statuses []updater.AppStatus
+	json.Unmarshal(b, &statuses)
+	if len(statuses) != 1 || statuses[0].PendingVersion != "1.3.0" || statuses[0].ManifestVersion != "1.2.3" {
+		t.Fatalf("unexpected json status: %s", b)
+	}
+
+	if code := Execute([]string{"status", "--root", root, "missing"}, &out, &errOut, ""); code != 1 {
+		t.Fatalf("expected an unknown app to fail, got %d", code)
+	}
+}
+
+func TestExecuteUpdateBackgroundStagesOneApp(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	for _, app := range []string{"chrome", "git"} {
+		writeTestManifest(t, filepath.Join(root, "manifests", app+".json"), runManifestContent(app+".exe"))
+	}
+	var updated []string
+	var stageOnly bool
+	oldUpdate := executeUpdateFromManifest
+	executeUpdateFromManifest = func(updateRoot, app, path string, opts updateOptions) error {
+		updated = append(updated, app)
+		stageOnly = opts.StageOnly
+		return nil
+	}
+	t.Cleanup(func() { executeUpdateFromManifest = oldUpdate })
+
+	var out, errOut strings.Builder
+	if code := Execute([]string{"update", "--root", root, "--app", "Git", "--background"}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("expected code 0, got %d, err=%s", code, errOut.String())
+	}
+	if strings.Join(updated, ",") != "git" || !stageOnly {
+		t.Fatalf("expected a stage-only update of git, got %v stageOnly=%v", updated, stageOnly)
+	}
+
+	if code := Execute([]string{"update", "--root", root, "--background"}, &out, &errOut, ""); code != 1 {
+		t.Fatalf("expected --background without --app to fail, got %d", code)
+	}
+	if code := Execute([]string{"update", "--root", root, "--app", "missing"}, &out, &errOut, ""); code != 1 {
+		t.Fatalf("expected an unknown --app to fail, got %d", code)
+	}
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"fmt"
+	"net"
+	neturl "net/url"
+	"time"
+
+	"appstract/internal/config"
+	"appstract/internal/manifest"
+	"appstract/internal/updater"
+)
+
+// probeConnectivity is the quick reachability check run before the
+// background update of `run`; tests replace it.
+var probeConnectivity = func(addr string) error {
+	conn, err := net.DialTimeout("tcp", addr, 3*time.Second)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// maxUpdateBackoff caps the wait after repeated failed updates.
+const maxUpdateBackoff = 24 * time.Hour
+
+// heldUpdateSkip says why config.yaml keeps run from updating app, as it
+// keeps the scheduler: a hold, or a pin the current version already meets
+// or the manifest does not offer. Empty means no hold or pin applies.
+func heldUpdateSkip(root, app string, man *manifest.Manifest, cfg config.Config) string {
+	if hold, ok := cfg.Held(app); ok {
+		return "held: " + hold
+	}
+	pin, ok := cfg.Pinned(app)
+	if !ok {
+		return ""
+	}
+	if state, err := updater.ReadState(root, app); err == nil && state.CurrentVersion == pin {
+		return "pinned to " + pin
+	}
+	if man.Version != pin {
+		return fmt.Sprintf("pinned to %s, manifest offers %s", pin, man.Version)
+	}
+	return ""
+}
+
+// backgroundUpdateSkip says why run should not start the background update
+// of app at now; empty means go ahead. An app checked within
+// check_ttl_seconds, or within the backoff after its failed updates, is
+// skipped, as is one whose download host does not answer.
+func backgroundUpdateSkip(manager *updater.Manager, app string, man *manifest.Manifest, cfg config.Config, now time.Time) string {
+	state, err := updater.ReadState(manager.Root, app)
+	if err != nil {
+		// The update itself reports a broken state file.
+		return ""
+	}
+	if last, err := time.Parse(time.RFC3339, state.LastCheckAt); err == nil {
+		wait := time.Duration(cfg.CheckTTLSeconds) * time.Second
+		backoff := updateBackoff(state.ConsecutiveFailures)
+		if backoff > wait {
+			wait = backoff
+		}
+		if next := last.Add(wait); now.Before(next) {
+			if backoff > 0 {
+				return fmt.Sprintf("%d failed update(s) in a row, retrying after %s", state.ConsecutiveFailures, next.Local().Format(time.RFC3339))
+			}
+			return fmt.Sprintf("checked at %s, next check after %s", last.Local().Format(time.RFC3339), next.Local().Format(time.RFC3339))
+		}
+	}
+	if addr := probeAddr(manager, man); addr != "" {
+		if err := probeConnectivity(addr); err != nil {
+			return fmt.Sprintf("offline: %v", err)
+		}
+	}
+	return ""
+}
+
+// updateBackoff is the wait after failures updates failed in a row: a
+// minute after the first, doubling up to maxUpdateBackoff.
+func updateBackoff(failures int) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+	backoff := time.Minute
+	for i := 1; i < failures && backoff < maxUpdateBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxUpdateBackoff {
+		return maxUpdateBackoff
+	}
+	return backoff
+}
+
+// probeAddr is the host:port the update of man downloads from, resolved
+// for the architecture the update of manager would pick.
+func probeAddr(manager *updater.Manager, man *manifest.Manifest) string {
+	_, artifact, err := man.ResolveArtifactFor(manager.HostArch(), manager.Architecture)
+	if err != nil {
+		return ""
+	}
+	u, err := neturl.Parse(artifact.URL)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
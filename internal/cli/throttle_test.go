@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"appstract/internal/bootstrap"
+	"appstract/internal/config"
+	"appstract/internal/manifest"
+	"appstract/internal/updater"
+)
+
+func TestBackgroundUpdateSkip(t *testing.T) {
+	root := t.TempDir()
+	man, err := manifest.ParseBytes([]byte(runManifestContent("chrome.exe")))
+	if err != nil {
+		t.Fatalf("parse manifest: %v", err)
+	}
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	cfg := config.Config{CheckTTLSeconds: 3600}
+	var probed string
+	old := probeConnectivity
+	probeConnectivity = func(addr string) error {
+		probed = addr
+		return errors.New("no route to host")
+	}
+	t.Cleanup(func() { probeConnectivity = old })
+
+	cases := []struct {
+		name  string
+		state string
+		want  string
+	}{
+		{"never checked and offline", `{"current_version":"1.0"}`, "offline: no route to host"},
+		{"checked within the ttl", `{"current_version":"1.0","last_check_at":"2026-10-18T11:30:00Z"}`, "next check after"},
+		{"ttl passed and offline", `{"current_version":"1.0","last_check_at":"2026-10-18T10:00:00Z"}`, "offline"},
+		// Five failures back off 16 minutes, less than the ttl.
+		{"backoff shorter than the ttl", `{"current_version":"1.0","last_check_at":"2026-10-18T10:00:00Z","consecutive_failures":5}`, "offline"},
+		// Eight failures back off two hours and eight minutes.
+		{"backing off", `{"current_version":"1.0","last_check_at":"2026-10-18T10:00:00Z","consecutive_failures":8}`, "8 failed update(s) in a row"},
+	}
+	manager := updater.NewManager(root)
+	manager.GOARCH = "amd64"
+	statePath := filepath.Join(root, "apps", "chrome", "runtime.json")
+	os.MkdirAll(filepath.Dir(statePath), 0o755)
+	for _, tc := range cases {
+		if err := os.WriteFile(statePath, []byte(tc.state), 0o644); err != nil {
+			t.Fatalf("write state: %v", err)
+		}
+		if got := backgroundUpdateSkip(manager, "chrome", man, cfg, now); !strings.Contains(got, tc.want) {
+			t.Fatalf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+	if probed != "example.com:443" {
+		t.Fatalf("expected the artifact host probed, got %q", probed)
+	}
+
+	// The probe follows the architecture the update resolves, not the one
+	// appstract was built for.
+	multi, err := manifest.ParseBytes([]byte(`{
+		"version": "1.2.3",
+		"architecture": {
+			"64bit": {"url": "https://x64.example.com/app.zip", "hash": "` + strings.Repeat("a", 64) + `"},
+			"arm64": {"url": "https://arm.example.com/app.zip", "hash": "` + strings.Repeat("b", 64) + `"}
+		},
+		"bin": "chrome.exe"
+	}`))
+	if err != nil {
+		t.Fatalf("parse manifest: %v", err)
+	}
+	if err := os.WriteFile(statePath, []byte(`{"current_version":"1.0"}`), 0o644); err != nil {
+		t.Fatalf("write state: %v", err)
+	}
+	manager.GOARCH = "arm64"
+	backgroundUpdateSkip(manager, "chrome", multi, config.Config{}, now)
+	if probed != "arm.example.com:443" {
+		t.Fatalf("expected the arm64 host probed, got %q", probed)
+	}
+	manager.Architecture = "64bit"
+	backgroundUpdateSkip(manager, "chrome", multi, config.Config{}, now)
+	if probed != "x64.example.com:443" {
+		t.Fatalf("expected the overridden architecture probed, got %q", probed)
+	}
+}
+
+func TestHeldUpdateSkip(t *testing.T) {
+	root := t.TempDir()
+	man, err := manifest.ParseBytes([]byte(runManifestContent("chrome.exe")))
+	if err != nil {
+		t.Fatalf("parse manifest: %v", err)
+	}
+	writeTestState(t, root, "chrome", "1.0.0")
+	cases := []struct {
+		cfg  config.Config
+		want string
+	}{
+		{config.Config{}, ""},
+		{config.Config{Holds: map[string]string{"chrome": "breaks extensions"}}, "held: breaks extensions"},
+		{config.Config{Pins: map[string]string{"chrome": "1.0.0"}}, "pinned to 1.0.0"},
+		{config.Config{Pins: map[string]string{"chrome": "1.1.0"}}, "pinned to 1.1.0, manifest offers 1.2.3"},
+		{config.Config{Pins: map[string]string{"chrome": "1.2.3"}}, ""},
+	}
+	for _, tc := range cases {
+		if got := heldUpdateSkip(root, "chrome", man, tc.cfg); got != tc.want {
+			t.Fatalf("%+v: expected %q, got %q", tc.cfg, tc.want, got)
+		}
+	}
+}
+
+func TestUpdateBackoff(t *testing.T) {
+	for failures, want := range map[int]time.Duration{
+		0:  0,
+		1:  time.Minute,
+		4:  8 * time.Minute,
+		11: 1024 * time.Minute,
+		12: maxUpdateBackoff,
+		99: maxUpdateBackoff,
+	} {
+		if got := updateBackoff(failures); got != want {
+			t.Fatalf("updateBackoff(%d) = %v, want %v", failures, got, want)
+		}
+	}
+}
+
+func TestExecuteRunUpdateFlags(t *testing.T) {
+	root := t.TempDir()
+	if err := bootstrap.InitLayout(root); err != nil {
+		t.Fatalf("init layout failed: %v", err)
+	}
+	current := filepath.Join(root, "apps", "chrome", "current")
+	os.MkdirAll(current, 0o755)
+	os.WriteFile(filepath.Join(current, "chrome.exe"), []byte(""), 0o644)
+	os.WriteFile(filepath.Join(root, "manifests", "chrome.json"), []byte(runManifestContent("chrome.exe")), 0o644)
+	// Checked a minute ago and offline: only --force-update starts the
+	// background update.
+	lastCheck := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	os.WriteFile(filepath.Join(root, "apps", "chrome", "runtime.json"), []byte(`{"current_version":"1.2.3","last_check_at":"`+lastCheck+`"}`), 0o644)
+	stubProbeConnectivity(t, errors.New("offline"))
+
+	oldLaunch := runLaunch
+	runLaunch = func(path string) error { return nil }
+	t.Cleanup(func() { runLaunch = oldLaunch })
+	started := make(chan struct{}, 1)
+	oldStart := startBackgroundUpdate
+	startBackgroundUpdate = func(runRoot, app string) (int, error) {
+		started <- struct{}{}
+		return 4242, nil
+	}
+	t.Cleanup(func() { startBackgroundUpdate = oldStart })
+
+	for _, tc := range []struct {
+		flag    string
+		started bool
+	}{
+		{"", false},
+		{"--no-update", false},
+		{"--force-update", true},
+	} {
+		args := []string{"run", "--root", root}
+		if tc.flag != "" {
+			args = append(args, tc.flag)
+		}
+		var out, errOut strings.Builder
+		if code := Execute(append(args, "chrome"), &out, &errOut, ""); code != 0 {
+			t.Fatalf("%q: expected code 0, got %d, err=%s", tc.flag, code, errOut.String())
+		}
+		select {
+		case <-started:
+			if !tc.started {
+				t.Fatalf("%q: unexpected background update", tc.flag)
+			}
+		default:
+			if tc.started {
+				t.Fatalf("%q: background update was not started", tc.flag)
+			}
+		}
+		if tc.flag == "" && !strings.Contains(out.String(), "background update skipped: checked at") {
+			t.Fatalf("expected the skip reported: %s", out.String())
+		}
+	}
+
+	// A hold wins over --force-update.
+	os.WriteFile(filepath.Join(root, "config.yaml"), []byte("hold.chrome: \"waiting for 2.0\"\n"), 0o644)
+	var out, errOut strings.Builder
+	if code := Execute([]string{"run", "--root", root, "--force-update", "chrome"}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("expected code 0, got %d, err=%s", code, errOut.String())
+	}
+	if len(started) != 0 || !strings.Contains(out.String(), "background update skipped: held: waiting for 2.0") {
+		t.Fatalf("expected the held app skipped: %s", out.String())
+	}
+
+	// An unreadable config.yaml does not fail a run whose app already started.
+	os.Remove(filepath.Join(root, "config.yaml"))
+	os.Mkdir(filepath.Join(root, "config.yaml"), 0o755)
+	out.Reset()
+	errOut.Reset()
+	if code := Execute([]string{"run", "--root", root, "--output", "default", "chrome"}, &out, &errOut, ""); code != 0 {
+		t.Fatalf("expected a config error after launch to be a warning, got %d, err=%s", code, errOut.String())
+	}
+	if !strings.Contains(errOut.String(), "background update failed") {
+		t.Fatalf("expected a warning about the config: %s", errOut.String())
+	}
+
+	out.Reset()
+	if code := Execute([]string{"run", "--root", root, "--no-update", "--force-update", "chrome"}, &out, &errOut, ""); code != 1 {
+		t.Fatalf("expected conflicting flags to fail, got %d", code)
+	}
+}
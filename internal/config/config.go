@@ -6,17 +6,141 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Config struct {
-	KeepVersions int
-	OutputLevel  OutputLevel
+	KeepVersions    int
+	OutputLevel     OutputLevel
+	ExtractMaxBytes int64
+	ExtractMaxFiles int
+	ExtractMaxRatio int
+	Architecture    string
+	// EnvBackend is "files" or "registry"; the registry backend also keeps
+	// the generated files.
+	EnvBackend string
+	// Event log retention per app; zero disables a rule.
+	LogRetentionDays    int
+	LogRetentionBytes   int64
+	LogCompactAfterDays int
+	// Scheduled checks: an app is checked once its last check is older
+	// than CheckTTLSeconds plus up to CheckJitterSeconds, outside QuietHours.
+	CheckTTLSeconds    int
+	CheckJitterSeconds int
+	QuietHours         QuietHours
+	Buckets            []Bucket
+	// Holds maps lower-cased app names to the reason scheduled updates skip
+	// them; Pins maps them to the only version scheduled updates may install.
+	Holds map[string]string
+	Pins  map[string]string
+}
+
+// Bucket is a named manifest collection, stored in config.yaml as
+// "bucket.<name>: <path-or-url>".
+type Bucket struct {
+	Name   string
+	Source string
+}
+
+const (
+	bucketKeyPrefix = "bucket."
+	holdKeyPrefix   = "hold."
+	pinKeyPrefix    = "pin."
+)
+
+// BucketKey returns the config.yaml key that registers a bucket.
+func BucketKey(name string) string {
+	return bucketKeyPrefix + name
+}
+
+// FindBucket looks a registered bucket up by name.
+func (c Config) FindBucket(name string) (Bucket, bool) {
+	for _, b := range c.Buckets {
+		if b.Name == name {
+			return b, true
+		}
+	}
+	return Bucket{}, false
+}
+
+// Held reports whether app is held back from scheduled updates, and why.
+func (c Config) Held(app string) (string, bool) {
+	reason, ok := c.Holds[strings.ToLower(app)]
+	return reason, ok
+}
+
+// Pinned returns the version app is pinned to, if any.
+func (c Config) Pinned(app string) (string, bool) {
+	version, ok := c.Pins[strings.ToLower(app)]
+	return version, ok
+}
+
+// QuietHours is a daily window in local time, such as 22:00-07:00, in
+// which no scheduled check starts. A zero value has no quiet hours.
+type QuietHours struct {
+	// Start and End are minutes after midnight; End may be before Start
+	// for windows that span midnight.
+	Start, End int
+}
+
+// ParseQuietHours parses "HH:MM-HH:MM". An empty value means none.
+func ParseQuietHours(raw string) (QuietHours, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return QuietHours{}, true
+	}
+	from, to, ok := strings.Cut(raw, "-")
+	if !ok {
+		return QuietHours{}, false
+	}
+	start, ok1 := parseClock(from)
+	end, ok2 := parseClock(to)
+	if !ok1 || !ok2 {
+		return QuietHours{}, false
+	}
+	return QuietHours{Start: start, End: end}, true
+}
+
+func parseClock(raw string) (int, bool) {
+	h, m, ok := strings.Cut(strings.TrimSpace(raw), ":")
+	if !ok {
+		return 0, false
+	}
+	hour, err1 := strconv.Atoi(h)
+	minute, err2 := strconv.Atoi(m)
+	if err1 != nil || err2 != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, false
+	}
+	return hour*60 + minute, true
+}
+
+// Contains reports whether t falls in the window, by its own clock.
+func (q QuietHours) Contains(t time.Time) bool {
+	if q.Start == q.End {
+		return false
+	}
+	minute := t.Hour()*60 + t.Minute()
+	if q.Start < q.End {
+		return minute >= q.Start && minute < q.End
+	}
+	return minute >= q.Start || minute < q.End
 }
 
 func Default() Config {
 	return Config{
-		KeepVersions: 2,
-		OutputLevel:  OutputLevelDefault,
+		KeepVersions:    2,
+		OutputLevel:     OutputLevelDefault,
+		ExtractMaxBytes: 8 << 30,
+		ExtractMaxFiles: 200000,
+		ExtractMaxRatio: 200,
+		EnvBackend:      "files",
+
+		LogRetentionDays:    30,
+		LogRetentionBytes:   64 << 20,
+		LogCompactAfterDays: 7,
+
+		CheckTTLSeconds:    3600,
+		CheckJitterSeconds: 300,
 	}
 }
 
@@ -26,6 +150,9 @@ const (
 	OutputLevelSilent  OutputLevel = "silent"
 	OutputLevelDefault OutputLevel = "default"
 	OutputLevelDebug   OutputLevel = "debug"
+	// OutputLevelJSON prints newline-delimited JSON events instead of text,
+	// for programs that drive appstract.
+	OutputLevelJSON OutputLevel = "json"
 )
 
 func ParseOutputLevel(raw string) (OutputLevel, bool) {
@@ -36,11 +163,30 @@ func ParseOutputLevel(raw string) (OutputLevel, bool) {
 		return OutputLevelDefault, true
 	case string(OutputLevelDebug), "verbose", "trace":
 		return OutputLevelDebug, true
+	case string(OutputLevelJSON), "ndjson":
+		return OutputLevelJSON, true
 	default:
 		return OutputLevelDefault, false
 	}
 }
 
+// ParseArchitecture maps an architecture override to the manifest key it
+// selects. "auto" (or empty) yields "" so the runtime architecture is used.
+func ParseArchitecture(raw string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "", "auto":
+		return "", true
+	case "64bit", "x64", "amd64":
+		return "64bit", true
+	case "32bit", "x86", "386":
+		return "32bit", true
+	case "arm64", "aarch64":
+		return "arm64", true
+	default:
+		return "", false
+	}
+}
+
 func Load(root string) (Config, error) {
 	cfg := Default()
 	path := filepath.Join(root, "config.yaml")
@@ -66,11 +212,80 @@ func Load(root string) (Config, error) {
 		}
 		key := strings.TrimSpace(parts[0])
 		val := strings.Trim(strings.TrimSpace(parts[1]), `"'`)
+		if name, ok := strings.CutPrefix(key, bucketKeyPrefix); ok {
+			if name != "" && val != "" {
+				cfg.Buckets = append(cfg.Buckets, Bucket{Name: name, Source: val})
+			}
+			continue
+		}
+		if app, ok := strings.CutPrefix(key, holdKeyPrefix); ok {
+			if app != "" && !isFalse(val) {
+				if cfg.Holds == nil {
+					cfg.Holds = map[string]string{}
+				}
+				cfg.Holds[strings.ToLower(app)] = val
+			}
+			continue
+		}
+		if app, ok := strings.CutPrefix(key, pinKeyPrefix); ok {
+			if app != "" && val != "" {
+				if cfg.Pins == nil {
+					cfg.Pins = map[string]string{}
+				}
+				cfg.Pins[strings.ToLower(app)] = val
+			}
+			continue
+		}
 		switch key {
 		case "keep_versions":
 			if n, convErr := strconv.Atoi(val); convErr == nil && n >= 0 {
 				cfg.KeepVersions = n
 			}
+		case "extract_max_bytes":
+			if n, convErr := strconv.ParseInt(val, 10, 64); convErr == nil && n >= 0 {
+				cfg.ExtractMaxBytes = n
+			}
+		case "extract_max_files":
+			if n, convErr := strconv.Atoi(val); convErr == nil && n >= 0 {
+				cfg.ExtractMaxFiles = n
+			}
+		case "extract_max_ratio":
+			if n, convErr := strconv.Atoi(val); convErr == nil && n >= 0 {
+				cfg.ExtractMaxRatio = n
+			}
+		case "log_retention_days":
+			if n, convErr := strconv.Atoi(val); convErr == nil && n >= 0 {
+				cfg.LogRetentionDays = n
+			}
+		case "log_retention_bytes":
+			if n, convErr := strconv.ParseInt(val, 10, 64); convErr == nil && n >= 0 {
+				cfg.LogRetentionBytes = n
+			}
+		case "log_compact_after_days":
+			if n, convErr := strconv.Atoi(val); convErr == nil && n >= 0 {
+				cfg.LogCompactAfterDays = n
+			}
+		case "check_ttl_seconds":
+			if n, convErr := strconv.Atoi(val); convErr == nil && n >= 0 {
+				cfg.CheckTTLSeconds = n
+			}
+		case "check_jitter_seconds":
+			if n, convErr := strconv.Atoi(val); convErr == nil && n >= 0 {
+				cfg.CheckJitterSeconds = n
+			}
+		case "quiet_hours":
+			if q, ok := ParseQuietHours(val); ok {
+				cfg.QuietHours = q
+			}
+		case "architecture":
+			if arch, ok := ParseArchitecture(val); ok {
+				cfg.Architecture = arch
+			}
+		case "env_backend":
+			switch backend := strings.ToLower(val); backend {
+			case "files", "registry":
+				cfg.EnvBackend = backend
+			}
 		case "output_level":
 			if level, ok := ParseOutputLevel(val); ok {
 				cfg.OutputLevel = level
@@ -91,3 +306,11 @@ func Load(root string) (Config, error) {
 	}
 	return cfg, nil
 }
+
+func isFalse(val string) bool {
+	switch strings.ToLower(val) {
+	case "", "false", "no", "off", "0":
+		return true
+	}
+	return false
+}
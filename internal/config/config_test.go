@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestLoadDefaultsWhenMissing(t *testing.T) {
@@ -75,3 +76,171 @@ func TestOutputLevelOverridesLegacyLogLevel(t *testing.T) {
 		t.Fatalf("expected output_level=silent, got %s", cfg.OutputLevel)
 	}
 }
+
+func TestLoadExtractLimits(t *testing.T) {
+	root := t.TempDir()
+	content := "extract_max_bytes: 1048576\nextract_max_files: 0\nextract_max_ratio: bad\n"
+	if err := os.WriteFile(filepath.Join(root, "config.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatalf("write config.yaml failed: %v", err)
+	}
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.ExtractMaxBytes != 1048576 || cfg.ExtractMaxFiles != 0 {
+		t.Fatalf("unexpected extract limits: bytes=%d files=%d", cfg.ExtractMaxBytes, cfg.ExtractMaxFiles)
+	}
+	if cfg.ExtractMaxRatio != Default().ExtractMaxRatio {
+		t.Fatalf("expected invalid extract_max_ratio to keep default, got %d", cfg.ExtractMaxRatio)
+	}
+}
+
+func TestLoadArchitectureOverride(t *testing.T) {
+	root := t.TempDir()
+	content := "architecture: x64\n"
+	if err := os.WriteFile(filepath.Join(root, "config.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatalf("write config.yaml failed: %v", err)
+	}
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Architecture != "64bit" {
+		t.Fatalf("expected architecture=64bit, got %q", cfg.Architecture)
+	}
+	if arch, ok := ParseArchitecture("auto"); !ok || arch != "" {
+		t.Fatalf("expected auto to clear override, got %q ok=%v", arch, ok)
+	}
+	if _, ok := ParseArchitecture("mips"); ok {
+		t.Fatal("expected unknown architecture to be rejected")
+	}
+}
+
+func TestSetValueAndRemoveKeyManageBuckets(t *testing.T) {
+	root := t.TempDir()
+	content := "# appstract\nkeep_versions: 3\n"
+	if err := os.WriteFile(filepath.Join(root, "config.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatalf("write config.yaml failed: %v", err)
+	}
+	if err := SetValue(root, BucketKey("main"), `D:\buckets\main`); err != nil {
+		t.Fatalf("SetValue failed: %v", err)
+	}
+	if err := SetValue(root, BucketKey("extras"), "https://example.com/extras.git"); err != nil {
+		t.Fatalf("SetValue failed: %v", err)
+	}
+	if err := SetValue(root, BucketKey("main"), `E:\main`); err != nil {
+		t.Fatalf("SetValue failed: %v", err)
+	}
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.KeepVersions != 3 || len(cfg.Buckets) != 2 {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+	if b, ok := cfg.FindBucket("main"); !ok || b.Source != `E:\main` {
+		t.Fatalf("expected main bucket to be replaced in place, got %+v", cfg.Buckets)
+	}
+	if b, ok := cfg.FindBucket("extras"); !ok || b.Source != "https://example.com/extras.git" {
+		t.Fatalf("unexpected extras bucket: %+v", b)
+	}
+
+	removed, err := RemoveKey(root, BucketKey("main"))
+	if err != nil || !removed {
+		t.Fatalf("RemoveKey failed: removed=%v err=%v", removed, err)
+	}
+	b, err := os.ReadFile(filepath.Join(root, "config.yaml"))
+	if err != nil {
+		t.Fatalf("read config.yaml failed: %v", err)
+	}
+	if string(b) != "# appstract\nkeep_versions: 3\nbucket.extras: \"https://example.com/extras.git\"\n" {
+		t.Fatalf("unexpected config.yaml: %q", b)
+	}
+}
+
+func TestParseOutputLevelJSON(t *testing.T) {
+	for _, raw := range []string{"json", "NDJSON"} {
+		if level, ok := ParseOutputLevel(raw); !ok || level != OutputLevelJSON {
+			t.Fatalf("expected json level for %q, got %s %v", raw, level, ok)
+		}
+	}
+}
+
+func TestLoadEnvBackend(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "config.yaml"), []byte("env_backend: \"Registry\"\n"), 0o644); err != nil {
+		t.Fatalf("write config.yaml failed: %v", err)
+	}
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.EnvBackend != "registry" {
+		t.Fatalf("expected registry backend, got %q", cfg.EnvBackend)
+	}
+}
+
+func TestLoadLogRetention(t *testing.T) {
+	root := t.TempDir()
+	content := "log_retention_days: 0\nlog_retention_bytes: 1048576\nlog_compact_after_days: bad\n"
+	if err := os.WriteFile(filepath.Join(root, "config.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatalf("write config.yaml failed: %v", err)
+	}
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.LogRetentionDays != 0 || cfg.LogRetentionBytes != 1<<20 || cfg.LogCompactAfterDays != 7 {
+		t.Fatalf("unexpected log retention: %d %d %d", cfg.LogRetentionDays, cfg.LogRetentionBytes, cfg.LogCompactAfterDays)
+	}
+}
+
+func TestLoadSchedule(t *testing.T) {
+	root := t.TempDir()
+	content := "check_ttl_seconds: 600\ncheck_jitter_seconds: 0\nquiet_hours: \"22:30-07:00\"\nhold.Aria2: \"broken on this machine\"\nhold.git: false\npin.go: \"1.22.5\"\n"
+	if err := os.WriteFile(filepath.Join(root, "config.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatalf("write config.yaml failed: %v", err)
+	}
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.CheckTTLSeconds != 600 || cfg.CheckJitterSeconds != 0 || cfg.QuietHours != (QuietHours{Start: 22*60 + 30, End: 7 * 60}) {
+		t.Fatalf("unexpected schedule: %+v", cfg)
+	}
+	if reason, ok := cfg.Held("aria2"); !ok || reason != "broken on this machine" {
+		t.Fatalf("expected aria2 held, got %q %v", reason, ok)
+	}
+	if _, ok := cfg.Held("git"); ok {
+		t.Fatalf("expected hold.git: false to be ignored")
+	}
+	if version, ok := cfg.Pinned("Go"); !ok || version != "1.22.5" {
+		t.Fatalf("expected go pinned to 1.22.5, got %q %v", version, ok)
+	}
+}
+
+func TestQuietHoursContains(t *testing.T) {
+	at := func(h, m int) time.Time { return time.Date(2026, 10, 18, h, m, 0, 0, time.Local) }
+	overnight, _ := ParseQuietHours("22:00-07:00")
+	daytime, _ := ParseQuietHours("12:00-13:30")
+	cases := []struct {
+		q    QuietHours
+		t    time.Time
+		want bool
+	}{
+		{overnight, at(23, 0), true},
+		{overnight, at(6, 59), true},
+		{overnight, at(7, 0), false},
+		{daytime, at(13, 0), true},
+		{daytime, at(13, 30), false},
+		{QuietHours{}, at(3, 0), false},
+	}
+	for i, c := range cases {
+		if got := c.q.Contains(c.t); got != c.want {
+			t.Fatalf("case %d: got %v want %v", i, got, c.want)
+		}
+	}
+	if _, ok := ParseQuietHours("25:00-07:00"); ok {
+		t.Fatalf("expected an invalid hour to be rejected")
+	}
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SetValue writes "key: value" into config.yaml, replacing the line that
+// already sets key or appending one. Other lines, comments included, are
+// kept as they are.
+func SetValue(root, key, value string) error {
+	lines, err := readLines(root)
+	if err != nil {
+		return err
+	}
+	// Values are quoted but not escaped: Load only trims quotes, and Windows
+	// paths must keep their backslashes.
+	entry := fmt.Sprintf("%s: \"%s\"", key, value)
+	replaced := false
+	for i, line := range lines {
+		if lineKey(line) == key {
+			lines[i] = entry
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		lines = append(lines, entry)
+	}
+	return writeLines(root, lines)
+}
+
+// RemoveKey drops every line that sets key and reports whether one existed.
+func RemoveKey(root, key string) (bool, error) {
+	lines, err := readLines(root)
+	if err != nil {
+		return false, err
+	}
+	kept := lines[:0]
+	removed := false
+	for _, line := range lines {
+		if lineKey(line) == key {
+			removed = true
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if !removed {
+		return false, nil
+	}
+	return true, writeLines(root, kept)
+}
+
+func lineKey(line string) string {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return ""
+	}
+	key, _, ok := strings.Cut(trimmed, ":")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(key)
+}
+
+func readLines(root string) ([]string, error) {
+	b, err := os.ReadFile(filepath.Join(root, "config.yaml"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	text := strings.TrimRight(string(b), "\n")
+	if text == "" {
+		return nil, nil
+	}
+	return strings.Split(text, "\n"), nil
+}
+
+func writeLines(root string, lines []string) error {
+	content := strings.Join(lines, "\n")
+	if content != "" {
+		content += "\n"
+	}
+	return os.WriteFile(filepath.Join(root, "config.yaml"), []byte(content), 0o644)
+}
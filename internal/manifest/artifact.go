@@ -0,0 +1,173 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// ArtifactItem is one download that belongs to an artifact. Multi-item
+// artifacts follow the Scoop convention of parallel url/hash/extract_dir/
+// extract_to arrays.
+type ArtifactItem struct {
+	URL        string
+	Hash       string
+	ExtractDir string
+	ExtractTo  string
+}
+
+// Items returns every download of the artifact, the primary one first.
+func (a Artifact) Items() []ArtifactItem {
+	items := make([]ArtifactItem, 0, 1+len(a.Extras))
+	items = append(items, ArtifactItem{
+		URL:        a.URL,
+		Hash:       a.Hash,
+		ExtractDir: a.ExtractDir,
+		ExtractTo:  a.ExtractTo,
+	})
+	return append(items, a.Extras...)
+}
+
+// WithItems returns a copy of the artifact whose downloads are replaced by
+// items. The first item becomes the primary download.
+func (a Artifact) WithItems(items []ArtifactItem) Artifact {
+	out := a
+	out.URL, out.Hash, out.ExtractDir, out.ExtractTo = "", "", "", ""
+	out.Extras = nil
+	if len(items) == 0 {
+		return out
+	}
+	out.URL = items[0].URL
+	out.Hash = items[0].Hash
+	out.ExtractDir = items[0].ExtractDir
+	out.ExtractTo = items[0].ExtractTo
+	if len(items) > 1 {
+		out.Extras = append([]ArtifactItem(nil), items[1:]...)
+	}
+	return out
+}
+
+func (a Artifact) IsZero() bool {
+	return a.URL == "" && a.Hash == "" && a.ExtractDir == "" && a.ExtractTo == "" && a.Type == "" && len(a.Extras) == 0
+}
+
+type artifactJSON struct {
+	URL        stringList `json:"url"`
+	Hash       stringList `json:"hash,omitempty"`
+	ExtractDir stringList `json:"extract_dir,omitempty"`
+	ExtractTo  stringList `json:"extract_to,omitempty"`
+	Type       string     `json:"type,omitempty"`
+}
+
+func (a *Artifact) UnmarshalJSON(b []byte) error {
+	var raw artifactJSON
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	count := len(raw.URL)
+	if count == 0 {
+		count = 1
+	}
+	lists := []struct {
+		name   string
+		values stringList
+	}{
+		{name: "hash", values: raw.Hash},
+		{name: "extract_dir", values: raw.ExtractDir},
+		{name: "extract_to", values: raw.ExtractTo},
+	}
+	for _, list := range lists {
+		if len(list.values) > count {
+			return fmt.Errorf("artifact %s has %d entries but url has %d", list.name, len(list.values), len(raw.URL))
+		}
+	}
+	if len(raw.URL) > 1 && len(raw.Hash) > 0 && len(raw.Hash) != len(raw.URL) {
+		return fmt.Errorf("artifact hash has %d entries but url has %d", len(raw.Hash), len(raw.URL))
+	}
+	items := make([]ArtifactItem, count)
+	for i := range items {
+		items[i] = ArtifactItem{
+			URL:        raw.URL.at(i),
+			Hash:       raw.Hash.at(i),
+			ExtractDir: raw.ExtractDir.at(i),
+			ExtractTo:  raw.ExtractTo.at(i),
+		}
+	}
+	*a = Artifact{Type: raw.Type}.WithItems(items)
+	return nil
+}
+
+func (a Artifact) MarshalJSON() ([]byte, error) {
+	var raw artifactJSON
+	raw.Type = a.Type
+	for _, item := range a.Items() {
+		raw.URL = append(raw.URL, item.URL)
+		raw.Hash = append(raw.Hash, item.Hash)
+		raw.ExtractDir = append(raw.ExtractDir, item.ExtractDir)
+		raw.ExtractTo = append(raw.ExtractTo, item.ExtractTo)
+	}
+	raw.Hash = raw.Hash.trimEmpty()
+	raw.ExtractDir = raw.ExtractDir.trimEmpty()
+	raw.ExtractTo = raw.ExtractTo.trimEmpty()
+	return json.Marshal(raw)
+}
+
+// stringList decodes from either a JSON string or an array of strings and
+// encodes back to a plain string when it holds a single value.
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) {
+		*l = nil
+		return nil
+	}
+	if len(b) > 0 && b[0] == '[' {
+		var values []string
+		if err := json.Unmarshal(b, &values); err != nil {
+			return err
+		}
+		*l = values
+		return nil
+	}
+	var value string
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+	*l = stringList{value}
+	return nil
+}
+
+func (l stringList) MarshalJSON() ([]byte, error) {
+	var v any = []string(l)
+	if len(l) == 1 {
+		v = l[0]
+	}
+	// Leave "&" and friends unescaped so rewritten URLs stay readable; an
+	// outer encoder that wants HTML escaping re-applies it.
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+func (l stringList) at(i int) string {
+	if i < len(l) {
+		return l[i]
+	}
+	return ""
+}
+
+// trimEmpty drops the list entirely when every entry is empty so optional
+// fields are omitted on output.
+func (l stringList) trimEmpty() stringList {
+	for _, v := range l {
+		if v != "" {
+			return l
+		}
+	}
+	return nil
+}
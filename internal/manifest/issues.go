@@ -0,0 +1,223 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is one validation finding. Path is the dotted JSON path of the
+// offending field; Line and Column are 1-based and zero when the issue has no
+// position in the source (for example a missing required field).
+type Issue struct {
+	Path     string   `json:"path,omitempty"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+}
+
+func (i Issue) String() string {
+	var b strings.Builder
+	if i.Line > 0 {
+		fmt.Fprintf(&b, "%d:%d: ", i.Line, i.Column)
+	}
+	b.WriteString(string(i.Severity))
+	b.WriteString(": ")
+	b.WriteString(i.Message)
+	if i.Path != "" {
+		b.WriteString(" (")
+		b.WriteString(i.Path)
+		b.WriteString(")")
+	}
+	return b.String()
+}
+
+func errorIssue(path, message string) Issue {
+	return Issue{Path: path, Severity: SeverityError, Message: message}
+}
+
+// HasErrors reports whether any issue is an error rather than a warning.
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func firstError(issues []Issue) error {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return errors.New(issue.Message)
+		}
+	}
+	return nil
+}
+
+// CheckFile reads and checks a manifest file. See Check.
+func CheckFile(path string, opts ParseOptions) (*Manifest, []Issue) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, []Issue{errorIssue("", fmt.Sprintf("read manifest file: %v", err))}
+	}
+	return Check(b, opts)
+}
+
+// Check decodes and validates a manifest, collecting every issue rather than
+// stopping at the first. Outside strict mode the strict-only findings are
+// reported as warnings. The manifest is nil when any error was found.
+func Check(b []byte, opts ParseOptions) (*Manifest, []Issue) {
+	strictSeverity := SeverityWarning
+	if opts.Strict {
+		strictSeverity = SeverityError
+	}
+	var doc any
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		issue := errorIssue("", fmt.Sprintf("decode manifest json: %v", err))
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			issue.Line, issue.Column = lineColumn(b, int(syntaxErr.Offset)-1)
+		} else {
+			issue.Line, issue.Column = lineColumn(b, len(b))
+		}
+		return nil, []Issue{issue}
+	}
+	spans := scanSpans(b)
+
+	issues := unknownFieldIssues(doc, strictSeverity)
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		path, message := describeDecodeError(err)
+		issues = append(issues, errorIssue(path, message))
+		return nil, locateIssues(b, spans, issues)
+	}
+	issues = append(issues, m.validationIssues()...)
+	issues = append(issues, m.strictIssues(strictSeverity)...)
+	issues = locateIssues(b, spans, issues)
+	if HasErrors(issues) {
+		return nil, issues
+	}
+	return &m, issues
+}
+
+// locateIssues fills in line and column from the nearest path that exists in
+// the source, so a missing nested field points at its parent object.
+func locateIssues(b []byte, spans map[string]jsonSpan, issues []Issue) []Issue {
+	for i := range issues {
+		if issues[i].Line > 0 {
+			continue
+		}
+		path := issues[i].Path
+		for {
+			if span, ok := spans[path]; ok {
+				issues[i].Line, issues[i].Column = lineColumn(b, span.keyStart)
+				break
+			}
+			if path == "" {
+				break
+			}
+			path = parentFieldPath(path)
+		}
+	}
+	return issues
+}
+
+func parentFieldPath(path string) string {
+	cut := strings.LastIndexAny(path, ".[")
+	if cut < 0 {
+		return ""
+	}
+	return path[:cut]
+}
+
+type jsonSpan struct {
+	keyStart   int
+	valueStart int
+	valueEnd   int
+}
+
+// scanSpans maps every field path in the document to the byte ranges of its
+// key and value. Array elements are keyed as path[i].
+func scanSpans(b []byte) map[string]jsonSpan {
+	spans := map[string]jsonSpan{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	var walk func(path string, keyStart int) error
+	walk = func(path string, keyStart int) error {
+		valueStart := skipSeparators(b, int(dec.InputOffset()))
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if delim, ok := tok.(json.Delim); ok {
+			switch delim {
+			case '{':
+				for dec.More() {
+					start := skipSeparators(b, int(dec.InputOffset()))
+					keyTok, err := dec.Token()
+					if err != nil {
+						return err
+					}
+					key, _ := keyTok.(string)
+					if err := walk(joinFieldPath(path, key), start); err != nil {
+						return err
+					}
+				}
+			case '[':
+				for i := 0; dec.More(); i++ {
+					start := skipSeparators(b, int(dec.InputOffset()))
+					if err := walk(path+"["+strconv.Itoa(i)+"]", start); err != nil {
+						return err
+					}
+				}
+			}
+			if _, err := dec.Token(); err != nil {
+				return err
+			}
+		}
+		spans[path] = jsonSpan{keyStart: keyStart, valueStart: valueStart, valueEnd: int(dec.InputOffset())}
+		return nil
+	}
+	_ = walk("", skipSeparators(b, 0))
+	return spans
+}
+
+func skipSeparators(b []byte, offset int) int {
+	for offset < len(b) {
+		switch b[offset] {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+func lineColumn(b []byte, offset int) (int, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(b) {
+		offset = len(b)
+	}
+	prefix := b[:offset]
+	line := bytes.Count(prefix, []byte("\n")) + 1
+	lineStart := bytes.LastIndexByte(prefix, '\n') + 1
+	return line, utf8.RuneCount(prefix[lineStart:]) + 1
+}
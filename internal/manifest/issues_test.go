@@ -0,0 +1,81 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckCollectsEveryIssueWithPositions(t *testing.T) {
+	content := "{\n" +
+		"  \"version\": \"\",\n" +
+		"  \"bin\": \"app.exe\",\n" +
+		"  \"architecture\": {\n" +
+		"    \"64bit\": {\"url\": \"http://example.com/a.zip\", \"hash\": \"abc\"}\n" +
+		"  },\n" +
+		"  \"extract\": [{\"action\": \"unpack\"}],\n" +
+		"  \"notes\": \"x\"\n" +
+		"}\n"
+	m, issues := Check([]byte(content), ParseOptions{})
+	if m != nil {
+		t.Fatalf("expected nil manifest when errors are present")
+	}
+	byPath := map[string]Issue{}
+	for _, issue := range issues {
+		byPath[issue.Path] = issue
+	}
+	want := []struct {
+		path     string
+		severity Severity
+		line     int
+		column   int
+	}{
+		{path: "notes", severity: SeverityWarning, line: 8, column: 3},
+		{path: "version", severity: SeverityError, line: 2, column: 3},
+		{path: "extract[0].action", severity: SeverityError, line: 7, column: 16},
+		{path: "architecture.64bit.url", severity: SeverityWarning, line: 5, column: 15},
+		{path: "architecture.64bit.hash", severity: SeverityWarning, line: 5, column: 50},
+	}
+	for _, w := range want {
+		issue, ok := byPath[w.path]
+		if !ok {
+			t.Fatalf("missing issue for %s in %+v", w.path, issues)
+		}
+		if issue.Severity != w.severity || issue.Line != w.line || issue.Column != w.column {
+			t.Fatalf("%s: expected %s at %d:%d, got %+v", w.path, w.severity, w.line, w.column, issue)
+		}
+	}
+}
+
+func TestCheckStrictPromotesWarnings(t *testing.T) {
+	content := `{"version": "1.0", "bin": "app.exe", "notes": "x", "architecture": {"64bit": {"url": "https://example.com/a.zip", "hash": "` + strictHash + `"}}}`
+	m, issues := Check([]byte(content), ParseOptions{})
+	if m == nil || len(issues) != 1 || issues[0].Severity != SeverityWarning {
+		t.Fatalf("expected a single warning in lenient mode, got %+v", issues)
+	}
+	m, issues = Check([]byte(content), ParseOptions{Strict: true})
+	if m != nil || !HasErrors(issues) {
+		t.Fatalf("expected strict mode to reject unknown field, got %+v", issues)
+	}
+}
+
+func TestCheckReportsSyntaxErrorPosition(t *testing.T) {
+	content := "{\n  \"version\": \"1.0\",\n  \"bin\": \"app.exe\" x\n}"
+	_, issues := Check([]byte(content), ParseOptions{})
+	if len(issues) != 1 {
+		t.Fatalf("expected one issue, got %+v", issues)
+	}
+	if issues[0].Line != 3 || !strings.Contains(issues[0].Message, "decode manifest json") {
+		t.Fatalf("expected syntax error on line 3, got %+v", issues[0])
+	}
+}
+
+func TestCheckMissingNestedFieldPointsAtParent(t *testing.T) {
+	content := "{\n  \"version\": \"1.0\",\n  \"bin\": \"app.exe\",\n  \"architecture\": {\n    \"64bit\": {\"url\": \"https://example.com/a.zip\"}\n  }\n}"
+	_, issues := Check([]byte(content), ParseOptions{})
+	if len(issues) != 1 {
+		t.Fatalf("expected one issue, got %+v", issues)
+	}
+	if issues[0].Path != "architecture.64bit" || issues[0].Line != 5 || !strings.Contains(issues[0].Message, "hash is required") {
+		t.Fatalf("unexpected issue: %+v", issues[0])
+	}
+}
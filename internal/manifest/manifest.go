@@ -2,21 +2,30 @@ package manifest
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 type Manifest struct {
-	Version      string       `json:"version"`
-	Description  string       `json:"description,omitempty"`
-	Checkver     Checkver     `json:"checkver,omitempty"`
-	Architecture Architecture `json:"architecture,omitempty"`
-	Autoupdate   Autoupdate   `json:"autoupdate,omitempty"`
-	Bin          string       `json:"bin"`
-	Shortcuts    [][]string   `json:"shortcuts,omitempty"`
-	PreInstall   []string     `json:"pre_install,omitempty"`
-	Hash         string       `json:"hash,omitempty"`
+	Version      string        `json:"version"`
+	Description  string        `json:"description,omitempty"`
+	Checkver     Checkver      `json:"checkver,omitempty"`
+	Architecture Architecture  `json:"architecture,omitempty"`
+	Autoupdate   Autoupdate    `json:"autoupdate,omitempty"`
+	Bin          string        `json:"bin"`
+	Shortcuts    [][]string    `json:"shortcuts,omitempty"`
+	PreInstall   []string      `json:"pre_install,omitempty"`
+	Hash         string        `json:"hash,omitempty"`
+	Extract      []ExtractStep `json:"extract,omitempty"`
+	Depends      []string      `json:"depends,omitempty"`
+	// EnvSet values may reference the app directory as $dir; EnvAddPath
+	// entries are relative to it. The app directory is apps/<app>/current.
+	EnvSet     map[string]string `json:"env_set,omitempty"`
+	EnvAddPath stringList        `json:"env_add_path,omitempty"`
 }
 
 type Checkver struct {
@@ -30,15 +39,92 @@ type Autoupdate struct {
 }
 
 type Architecture struct {
-	X64 Artifact `json:"64bit"`
+	X64   Artifact `json:"64bit"`
+	X86   Artifact `json:"32bit"`
+	ARM64 Artifact `json:"arm64"`
+}
+
+const (
+	Arch64bit = "64bit"
+	Arch32bit = "32bit"
+	ArchARM64 = "arm64"
+)
+
+// Architectures lists every architecture key in declaration order.
+var Architectures = []string{Arch64bit, Arch32bit, ArchARM64}
+
+func (a Architecture) Get(arch string) Artifact {
+	switch arch {
+	case Arch64bit:
+		return a.X64
+	case Arch32bit:
+		return a.X86
+	case ArchARM64:
+		return a.ARM64
+	default:
+		return Artifact{}
+	}
+}
+
+func (a *Architecture) Set(arch string, artifact Artifact) {
+	switch arch {
+	case Arch64bit:
+		a.X64 = artifact
+	case Arch32bit:
+		a.X86 = artifact
+	case ArchARM64:
+		a.ARM64 = artifact
+	}
+}
+
+// CandidateArchitectures returns the architectures a host can run, best match
+// first. A non-empty override pins the choice, e.g. forcing the x64 build on
+// arm64 devices that run it under emulation.
+func CandidateArchitectures(goarch, override string) []string {
+	if override != "" {
+		return []string{override}
+	}
+	switch goarch {
+	case "arm64":
+		return []string{ArchARM64, Arch64bit, Arch32bit}
+	case "386":
+		return []string{Arch32bit}
+	default:
+		return []string{Arch64bit, Arch32bit}
+	}
 }
 
 type Artifact struct {
-	URL        string `json:"url"`
-	Hash       string `json:"hash,omitempty"`
-	ExtractDir string `json:"extract_dir,omitempty"`
+	URL        string         `json:"url"`
+	Hash       string         `json:"hash,omitempty"`
+	ExtractDir string         `json:"extract_dir,omitempty"`
+	ExtractTo  string         `json:"extract_to,omitempty"`
+	Type       string         `json:"type,omitempty"`
+	Extras     []ArtifactItem `json:"-"`
+}
+
+// ExtractStep is one stage of the unpacking pipeline applied to the downloaded
+// artifact. Steps run in order; each consumes the file or directory produced by
+// the previous one.
+type ExtractStep struct {
+	Action     string `json:"action"`
+	Glob       string `json:"glob,omitempty"`
+	Tool       string `json:"tool,omitempty"`
+	Components int    `json:"components,omitempty"`
 }
 
+const (
+	ExtractActionExtract = "extract"
+	ExtractActionSelect  = "select"
+	ExtractActionStrip   = "strip"
+
+	ExtractTool7Zip = "7zip"
+)
+
+// ArtifactTypeRaw marks a single-file artifact (typically a standalone
+// executable) that is placed into the version directory without extraction.
+const ArtifactTypeRaw = "raw"
+
 func ParseFile(path string) (*Manifest, error) {
 	b, err := os.ReadFile(path)
 	if err != nil {
@@ -59,31 +145,229 @@ func ParseBytes(b []byte) (*Manifest, error) {
 }
 
 func (m Manifest) Validate() error {
+	return firstError(m.validationIssues())
+}
+
+// validationIssues collects every structural problem instead of stopping at
+// the first, so callers can report them together.
+func (m Manifest) validationIssues() []Issue {
+	var issues []Issue
 	if m.Version == "" {
-		return errors.New("manifest version is required")
+		issues = append(issues, errorIssue("version", "manifest version is required"))
 	}
 	if m.Bin == "" {
-		return errors.New("manifest bin is required")
+		issues = append(issues, errorIssue("bin", "manifest bin is required"))
+	}
+	declared := m.declaredArchitectures()
+	if len(declared) == 0 {
+		issues = append(issues, errorIssue("architecture", "manifest artifact url is required"))
 	}
-	if _, err := m.ResolveArtifact64(); err != nil {
-		return err
+	for _, arch := range declared {
+		path := m.artifactPath(arch)
+		artifact, err := m.ResolveArtifact(arch)
+		if err != nil {
+			issues = append(issues, errorIssue(path, err.Error()))
+			continue
+		}
+		if artifact.Type != "" && artifact.Type != ArtifactTypeRaw {
+			issues = append(issues, errorIssue(path+".type", fmt.Sprintf("manifest %s artifact type %q is not supported", arch, artifact.Type)))
+		}
+		if artifact.Type == ArtifactTypeRaw && artifact.ExtractDir != "" {
+			issues = append(issues, errorIssue(path+".extract_dir", fmt.Sprintf("manifest %s raw artifact cannot set extract_dir", arch)))
+		}
+		if artifact.Type == ArtifactTypeRaw && len(m.Extract) > 0 {
+			issues = append(issues, errorIssue("extract", fmt.Sprintf("manifest %s raw artifact cannot use an extract pipeline", arch)))
+		}
+		for i, item := range artifact.Items() {
+			if !isRelativeInside(item.ExtractDir) {
+				issues = append(issues, errorIssue(path+".extract_dir", fmt.Sprintf("manifest %s extract_dir[%d] must be a relative path inside the archive: %s", arch, i, item.ExtractDir)))
+			}
+			if !isRelativeInside(item.ExtractTo) {
+				issues = append(issues, errorIssue(path+".extract_to", fmt.Sprintf("manifest %s extract_to[%d] must be a relative path inside the app directory: %s", arch, i, item.ExtractTo)))
+			}
+		}
 	}
-	return nil
+	issues = append(issues, extractStepIssues(m.Extract)...)
+	issues = append(issues, dependsIssues(m.Depends)...)
+	return append(issues, envIssues(m.EnvSet, m.EnvAddPath)...)
+}
+
+func envIssues(set map[string]string, addPath []string) []Issue {
+	var issues []Issue
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "" || strings.ContainsAny(name, "= \t\r\n") || strings.EqualFold(name, "PATH") {
+			issues = append(issues, errorIssue("env_set."+name, fmt.Sprintf("manifest env_set name is not allowed: %q", name)))
+		}
+	}
+	for i, entry := range addPath {
+		if !isRelativeInside(entry) {
+			issues = append(issues, errorIssue("env_add_path["+strconv.Itoa(i)+"]", fmt.Sprintf("manifest env_add_path[%d] must be a relative path inside the app directory: %s", i, entry)))
+		}
+	}
+	return issues
+}
+
+// dependsIssues checks that every dependency names an app the way
+// manifests/<app>.json does, once.
+func dependsIssues(depends []string) []Issue {
+	var issues []Issue
+	seen := map[string]bool{}
+	for i, dep := range depends {
+		path := "depends[" + strconv.Itoa(i) + "]"
+		if !IsAppName(dep) {
+			issues = append(issues, errorIssue(path, fmt.Sprintf("manifest depends[%d] is not a valid app name: %q", i, dep)))
+			continue
+		}
+		key := strings.ToLower(dep)
+		if seen[key] {
+			issues = append(issues, errorIssue(path, fmt.Sprintf("manifest depends[%d] lists %s more than once", i, dep)))
+		}
+		seen[key] = true
+	}
+	return issues
+}
+
+// IsAppName reports whether name can be used as an app name, i.e. as the base
+// name of manifests/<app>.json and apps/<app>.
+func IsAppName(name string) bool {
+	return strings.TrimSpace(name) == name && name != "" && !strings.HasPrefix(name, ".") && !strings.ContainsAny(name, `/\:*?"<>|`)
+}
+
+// artifactPath names the JSON object an architecture resolves from, which is
+// the autoupdate template when no concrete artifact is declared.
+func (m Manifest) artifactPath(arch string) string {
+	if m.Architecture.Get(arch).URL == "" && m.Autoupdate.Architecture.Get(arch).URL != "" {
+		return "autoupdate.architecture." + arch
+	}
+	return "architecture." + arch
+}
+
+// declaredArchitectures returns the architectures that must resolve. Entries
+// under "architecture" take precedence; a manifest that only describes
+// autoupdate templates is validated against those instead.
+func (m Manifest) declaredArchitectures() []string {
+	var declared []string
+	for _, arch := range Architectures {
+		if !m.Architecture.Get(arch).IsZero() {
+			declared = append(declared, arch)
+		}
+	}
+	if len(declared) > 0 {
+		return declared
+	}
+	for _, arch := range Architectures {
+		if !m.Autoupdate.Architecture.Get(arch).IsZero() {
+			declared = append(declared, arch)
+		}
+	}
+	return declared
+}
+
+// CoveredArchitectures reports which architectures resolve to a complete,
+// verifiable artifact.
+func (m Manifest) CoveredArchitectures() []string {
+	var covered []string
+	for _, arch := range Architectures {
+		if _, err := m.ResolveArtifact(arch); err == nil {
+			covered = append(covered, arch)
+		}
+	}
+	return covered
+}
+
+func extractStepIssues(steps []ExtractStep) []Issue {
+	var issues []Issue
+	for i, step := range steps {
+		path := "extract[" + strconv.Itoa(i) + "]"
+		switch step.Action {
+		case ExtractActionExtract:
+			if step.Tool != "" && step.Tool != ExtractTool7Zip {
+				issues = append(issues, errorIssue(path+".tool", fmt.Sprintf("manifest extract[%d] tool %q is not supported", i, step.Tool)))
+			}
+		case ExtractActionSelect:
+			if step.Glob == "" {
+				issues = append(issues, errorIssue(path, fmt.Sprintf("manifest extract[%d] select requires glob", i)))
+			} else if !isRelativeInside(step.Glob) {
+				issues = append(issues, errorIssue(path+".glob", fmt.Sprintf("manifest extract[%d] glob must stay inside the extracted tree: %s", i, step.Glob)))
+			} else if _, err := filepath.Match(step.Glob, ""); err != nil {
+				issues = append(issues, errorIssue(path+".glob", fmt.Sprintf("manifest extract[%d] glob is invalid: %v", i, err)))
+			}
+		case ExtractActionStrip:
+			if step.Components < 0 {
+				issues = append(issues, errorIssue(path+".components", fmt.Sprintf("manifest extract[%d] strip components must not be negative", i)))
+			}
+		default:
+			issues = append(issues, errorIssue(path+".action", fmt.Sprintf("manifest extract[%d] action %q is not supported", i, step.Action)))
+		}
+	}
+	return issues
+}
+
+// isRelativeInside reports whether p is empty or a relative path that does not
+// climb out of the directory it is joined onto.
+func isRelativeInside(p string) bool {
+	if p == "" {
+		return true
+	}
+	if filepath.IsAbs(p) || strings.HasPrefix(p, "/") || strings.HasPrefix(p, `\`) || filepath.VolumeName(p) != "" {
+		return false
+	}
+	clean := filepath.ToSlash(filepath.Clean(filepath.FromSlash(p)))
+	return clean != ".." && !strings.HasPrefix(clean, "../")
 }
 
 func (m Manifest) ResolveArtifact64() (Artifact, error) {
-	artifact := m.Architecture.X64
+	return m.ResolveArtifact(Arch64bit)
+}
+
+func (m Manifest) ResolveArtifact(arch string) (Artifact, error) {
+	artifact := m.Architecture.Get(arch)
 	if artifact.URL == "" {
-		artifact = m.Autoupdate.Architecture.X64
+		artifact = m.Autoupdate.Architecture.Get(arch)
 	}
 	if artifact.URL == "" {
-		return Artifact{}, errors.New("manifest 64bit artifact url is required")
+		return Artifact{}, fmt.Errorf("manifest %s artifact url is required", arch)
 	}
-	if artifact.Hash == "" && m.Hash != "" {
+	// The top-level hash predates per-architecture artifacts and only ever
+	// described the 64bit download; other builds must carry their own.
+	if artifact.Hash == "" && arch == Arch64bit && m.Hash != "" {
 		artifact.Hash = m.Hash
 	}
 	if artifact.Hash == "" {
-		return Artifact{}, errors.New("manifest 64bit artifact hash is required")
+		return Artifact{}, fmt.Errorf("manifest %s artifact hash is required", arch)
+	}
+	for i, item := range artifact.Extras {
+		if item.URL == "" {
+			return Artifact{}, fmt.Errorf("manifest %s artifact url[%d] is required", arch, i+1)
+		}
+		if item.Hash == "" {
+			return Artifact{}, fmt.Errorf("manifest %s artifact hash[%d] is required", arch, i+1)
+		}
 	}
 	return artifact, nil
 }
+
+// ResolveArtifactFor picks the first architecture from CandidateArchitectures
+// that resolves, returning the chosen architecture alongside the artifact.
+func (m Manifest) ResolveArtifactFor(goarch, override string) (string, Artifact, error) {
+	candidates := CandidateArchitectures(goarch, override)
+	var firstErr error
+	for _, arch := range candidates {
+		artifact, err := m.ResolveArtifact(arch)
+		if err == nil {
+			return arch, artifact, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if len(candidates) == 1 {
+		return "", Artifact{}, firstErr
+	}
+	return "", Artifact{}, fmt.Errorf("manifest has no usable artifact for %s (tried %s): %w", goarch, strings.Join(candidates, ", "), firstErr)
+}
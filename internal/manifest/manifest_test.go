@@ -1,6 +1,7 @@
 package manifest
 
 import (
+	stdjson "encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
@@ -93,6 +94,214 @@ func TestParseBytesWithArchitectureHash(t *testing.T) {
 	}
 }
 
+func TestParseBytesArtifactType(t *testing.T) {
+	raw := `{
+		"version": "2.0.0",
+		"architecture": {"64bit": {"url": "https://example.com/tool.exe", "hash": "abc", "type": "raw"}},
+		"bin": "tool.exe"
+	}`
+	m, err := ParseBytes([]byte(raw))
+	if err != nil {
+		t.Fatalf("ParseBytes failed: %v", err)
+	}
+	if m.Architecture.X64.Type != ArtifactTypeRaw {
+		t.Fatalf("unexpected artifact type: %q", m.Architecture.X64.Type)
+	}
+
+	unknown := `{
+		"version": "2.0.0",
+		"architecture": {"64bit": {"url": "https://example.com/tool.exe", "hash": "abc", "type": "msi"}},
+		"bin": "tool.exe"
+	}`
+	if _, err := ParseBytes([]byte(unknown)); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Fatalf("expected unsupported artifact type error, got: %v", err)
+	}
+}
+
+func TestParseBytesExtractPipeline(t *testing.T) {
+	valid := `{
+		"version": "2.0.0",
+		"architecture": {"64bit": {"url": "https://example.com/bundle.zip", "hash": "abc"}},
+		"extract": [
+			{"action": "extract"},
+			{"action": "select", "glob": "*setup*.exe"},
+			{"action": "extract", "tool": "7zip"},
+			{"action": "select", "glob": "$PLUGINSDIR/app-64.7z"},
+			{"action": "extract"},
+			{"action": "strip"}
+		],
+		"bin": "app.exe"
+	}`
+	m, err := ParseBytes([]byte(valid))
+	if err != nil {
+		t.Fatalf("ParseBytes failed: %v", err)
+	}
+	if len(m.Extract) != 6 || m.Extract[2].Tool != ExtractTool7Zip {
+		t.Fatalf("unexpected extract steps: %#v", m.Extract)
+	}
+
+	cases := map[string]string{
+		`[{"action": "unpack"}]`:                 "not supported",
+		`[{"action": "select"}]`:                 "requires glob",
+		`[{"action": "select", "glob": "../x"}]`: "inside the extracted tree",
+		`[{"action": "select", "glob": "[x"}]`:   "glob is invalid",
+	}
+	for steps, want := range cases {
+		raw := `{
+			"version": "2.0.0",
+			"architecture": {"64bit": {"url": "https://example.com/bundle.zip", "hash": "abc"}},
+			"extract": ` + steps + `,
+			"bin": "app.exe"
+		}`
+		if _, err := ParseBytes([]byte(raw)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("steps %s: expected %q error, got: %v", steps, want, err)
+		}
+	}
+}
+
+func TestResolveArtifactForRuntime(t *testing.T) {
+	json := `{
+		"version": "3.1.0",
+		"architecture": {
+			"64bit": {"url": "https://example.com/app-x64.zip", "hash": "aa"},
+			"32bit": {"url": "https://example.com/app-x86.zip", "hash": "bb"},
+			"arm64": {"url": "https://example.com/app-arm64.zip", "hash": "cc"}
+		},
+		"bin": "app.exe"
+	}`
+	m, err := ParseBytes([]byte(json))
+	if err != nil {
+		t.Fatalf("ParseBytes failed: %v", err)
+	}
+	if got := m.CoveredArchitectures(); strings.Join(got, ",") != "64bit,32bit,arm64" {
+		t.Fatalf("unexpected covered architectures: %v", got)
+	}
+
+	cases := []struct {
+		goarch   string
+		override string
+		wantArch string
+		wantURL  string
+	}{
+		{goarch: "amd64", wantArch: Arch64bit, wantURL: "https://example.com/app-x64.zip"},
+		{goarch: "386", wantArch: Arch32bit, wantURL: "https://example.com/app-x86.zip"},
+		{goarch: "arm64", wantArch: ArchARM64, wantURL: "https://example.com/app-arm64.zip"},
+		{goarch: "arm64", override: Arch64bit, wantArch: Arch64bit, wantURL: "https://example.com/app-x64.zip"},
+	}
+	for _, tc := range cases {
+		arch, artifact, err := m.ResolveArtifactFor(tc.goarch, tc.override)
+		if err != nil {
+			t.Fatalf("%s/%s: ResolveArtifactFor failed: %v", tc.goarch, tc.override, err)
+		}
+		if arch != tc.wantArch || artifact.URL != tc.wantURL {
+			t.Fatalf("%s/%s: got %s %s", tc.goarch, tc.override, arch, artifact.URL)
+		}
+	}
+}
+
+func TestResolveArtifactForFallsBackToEmulatedX64(t *testing.T) {
+	json := `{
+		"version": "3.1.0",
+		"architecture": {"64bit": {"url": "https://example.com/app-x64.zip", "hash": "aa"}},
+		"bin": "app.exe"
+	}`
+	m, err := ParseBytes([]byte(json))
+	if err != nil {
+		t.Fatalf("ParseBytes failed: %v", err)
+	}
+	arch, _, err := m.ResolveArtifactFor("arm64", "")
+	if err != nil || arch != Arch64bit {
+		t.Fatalf("expected arm64 host to fall back to 64bit, got %q (%v)", arch, err)
+	}
+	if _, _, err := m.ResolveArtifactFor("arm64", ArchARM64); err == nil || !strings.Contains(err.Error(), "arm64 artifact url") {
+		t.Fatalf("expected forced arm64 to fail, got: %v", err)
+	}
+}
+
+func TestTopLevelHashOnlyCoversLegacy64bit(t *testing.T) {
+	json := `{
+		"version": "3.1.0",
+		"architecture": {
+			"64bit": {"url": "https://example.com/app-x64.zip"},
+			"arm64": {"url": "https://example.com/app-arm64.zip"}
+		},
+		"hash": "aa",
+		"bin": "app.exe"
+	}`
+	var m Manifest
+	if err := stdjson.Unmarshal([]byte(json), &m); err != nil {
+		t.Fatalf("decode manifest: %v", err)
+	}
+	if artifact, err := m.ResolveArtifact(Arch64bit); err != nil || artifact.Hash != "aa" {
+		t.Fatalf("expected 64bit to fall back to the top-level hash, got %q (%v)", artifact.Hash, err)
+	}
+	if _, err := m.ResolveArtifact(ArchARM64); err == nil || !strings.Contains(err.Error(), "arm64 artifact hash") {
+		t.Fatalf("expected arm64 without its own hash to fail, got: %v", err)
+	}
+	if _, err := ParseBytes([]byte(json)); err == nil {
+		t.Fatalf("expected validation to reject the arm64 artifact without a hash")
+	}
+}
+
+func TestParseBytesMultipleArtifactItems(t *testing.T) {
+	raw := `{
+		"version": "4.0",
+		"architecture": {
+			"64bit": {
+				"url": ["https://example.com/editor.zip", "https://example.com/lang.zip"],
+				"hash": ["aa", "bb"],
+				"extract_dir": ["editor-4.0", ""],
+				"extract_to": ["", "lang"]
+			}
+		},
+		"bin": "editor.exe"
+	}`
+	m, err := ParseBytes([]byte(raw))
+	if err != nil {
+		t.Fatalf("ParseBytes failed: %v", err)
+	}
+	items := m.Architecture.X64.Items()
+	if len(items) != 2 {
+		t.Fatalf("expected 2 artifact items, got %d", len(items))
+	}
+	if items[0].URL != "https://example.com/editor.zip" || items[0].ExtractDir != "editor-4.0" {
+		t.Fatalf("unexpected primary item: %#v", items[0])
+	}
+	if items[1].Hash != "bb" || items[1].ExtractTo != "lang" {
+		t.Fatalf("unexpected add-on item: %#v", items[1])
+	}
+
+	encoded, err := stdjson.Marshal(m.Architecture.X64)
+	if err != nil {
+		t.Fatalf("marshal artifact: %v", err)
+	}
+	if !strings.Contains(string(encoded), `"url":["https://example.com/editor.zip","https://example.com/lang.zip"]`) {
+		t.Fatalf("expected url array on output, got %s", encoded)
+	}
+	single, err := stdjson.Marshal(Artifact{URL: "https://example.com/a.zip", Hash: "aa"})
+	if err != nil {
+		t.Fatalf("marshal single artifact: %v", err)
+	}
+	if string(single) != `{"url":"https://example.com/a.zip","hash":"aa"}` {
+		t.Fatalf("expected single artifact to stay scalar, got %s", single)
+	}
+}
+
+func TestParseBytesMultipleArtifactItemsErrors(t *testing.T) {
+	cases := map[string]string{
+		`"url": ["https://example.com/a.zip", "https://example.com/b.zip"], "hash": ["aa", "bb", "cc"]`: "hash has 3 entries",
+		`"url": ["https://example.com/a.zip", "https://example.com/b.zip"], "hash": ["aa"]`:             "hash has 1 entries",
+		`"url": ["https://example.com/a.zip", ""], "hash": ["aa", "bb"]`:                                "url[1] is required",
+		`"url": "https://example.com/a.zip", "hash": "aa", "extract_to": "../outside"`:                  "extract_to[0]",
+	}
+	for artifact, want := range cases {
+		raw := `{"version": "1.0", "architecture": {"64bit": {` + artifact + `}}, "bin": "app.exe"}`
+		if _, err := ParseBytes([]byte(raw)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("artifact %s: expected %q error, got: %v", artifact, want, err)
+		}
+	}
+}
+
 func TestParseFile(t *testing.T) {
 	root := t.TempDir()
 	path := filepath.Join(root, "app.json")
@@ -122,3 +331,55 @@ func TestParseFileMissing(t *testing.T) {
 		t.Fatal("expected error for missing file")
 	}
 }
+
+func TestParseBytesDepends(t *testing.T) {
+	valid := `{
+		"version": "1.0.0",
+		"architecture": {"64bit": {"url": "https://example.com/tool.zip", "hash": "abc"}},
+		"bin": "tool.exe",
+		"depends": ["jre", "vcredist"]
+	}`
+	m, err := ParseBytes([]byte(valid))
+	if err != nil {
+		t.Fatalf("ParseBytes failed: %v", err)
+	}
+	if strings.Join(m.Depends, ",") != "jre,vcredist" {
+		t.Fatalf("unexpected depends: %v", m.Depends)
+	}
+
+	for _, depends := range []string{`["../jre"]`, `[""]`, `["jre", "JRE"]`} {
+		raw := strings.Replace(valid, `["jre", "vcredist"]`, depends, 1)
+		if _, err := ParseBytes([]byte(raw)); err == nil || !strings.Contains(err.Error(), "depends[") {
+			t.Fatalf("expected depends error for %s, got: %v", depends, err)
+		}
+	}
+}
+
+func TestParseBytesEnv(t *testing.T) {
+	valid := `{
+		"version": "1.0.0",
+		"architecture": {"64bit": {"url": "https://example.com/jre.zip", "hash": "abc"}},
+		"bin": "bin/java.exe",
+		"env_set": {"JAVA_HOME": "$dir"},
+		"env_add_path": "bin"
+	}`
+	m, err := ParseBytes([]byte(valid))
+	if err != nil {
+		t.Fatalf("ParseBytes failed: %v", err)
+	}
+	if m.EnvSet["JAVA_HOME"] != "$dir" || len(m.EnvAddPath) != 1 || m.EnvAddPath[0] != "bin" {
+		t.Fatalf("unexpected env fields: %v %v", m.EnvSet, m.EnvAddPath)
+	}
+
+	cases := map[string]string{
+		`"env_add_path": "bin"`:             `"env_add_path": ["../outside"]`,
+		`"env_set": {"JAVA_HOME": "$dir"}`:  `"env_set": {"PATH": "x"}`,
+		`"env_set": {"JAVA_HOME": "$dir"},`: `"env_set": {"A=B": "x"},`,
+	}
+	for from, to := range cases {
+		raw := strings.Replace(valid, from, to, 1)
+		if _, err := ParseBytes([]byte(raw)); err == nil || !strings.Contains(err.Error(), "env_") {
+			t.Fatalf("expected env error for %s, got: %v", to, err)
+		}
+	}
+}
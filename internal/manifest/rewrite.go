@@ -0,0 +1,216 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FieldEdit sets the value at a dotted JSON path such as
+// "architecture.64bit.hash". Value is marshalled as JSON.
+type FieldEdit struct {
+	Path  string
+	Value any
+}
+
+// RewriteSource records the result of an autoupdate run into the manifest
+// source: version, every rendered architecture's downloads and hashes, and the
+// top-level hash when the manifest uses one. Only the affected values are
+// rewritten so hand formatting elsewhere survives.
+func RewriteSource(src []byte, version string, arch Architecture) ([]byte, error) {
+	spans := scanSpans(src)
+	edits := []FieldEdit{{Path: "version", Value: version}}
+	primaryHash := ""
+	for _, name := range Architectures {
+		artifact := arch.Get(name)
+		if artifact.IsZero() {
+			continue
+		}
+		var urls, hashes, dirs, tos stringList
+		for _, item := range artifact.Items() {
+			urls = append(urls, item.URL)
+			hashes = append(hashes, item.Hash)
+			dirs = append(dirs, item.ExtractDir)
+			tos = append(tos, item.ExtractTo)
+		}
+		if primaryHash == "" {
+			primaryHash = artifact.Hash
+		}
+		prefix := "architecture." + name
+		edits = append(edits, FieldEdit{Path: prefix + ".url", Value: urls}, FieldEdit{Path: prefix + ".hash", Value: hashes})
+		if _, ok := spans[prefix+".extract_dir"]; ok || dirs.trimEmpty() != nil {
+			edits = append(edits, FieldEdit{Path: prefix + ".extract_dir", Value: dirs})
+		}
+		if _, ok := spans[prefix+".extract_to"]; ok || tos.trimEmpty() != nil {
+			edits = append(edits, FieldEdit{Path: prefix + ".extract_to", Value: tos})
+		}
+	}
+	if _, ok := spans["hash"]; ok && primaryHash != "" {
+		edits = append(edits, FieldEdit{Path: "hash", Value: primaryHash})
+	}
+	out, err := SetFields(src, edits)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ParseBytes(out); err != nil {
+		return nil, fmt.Errorf("rewritten manifest is invalid: %w", err)
+	}
+	return out, nil
+}
+
+// SetFields applies edits to a JSON document, replacing existing values in
+// place and inserting missing members next to their siblings with matching
+// indentation. Parent objects are created as needed.
+func SetFields(src []byte, edits []FieldEdit) ([]byte, error) {
+	out := append([]byte(nil), src...)
+	for _, edit := range edits {
+		next, err := setField(out, edit.Path, edit.Value)
+		if err != nil {
+			return nil, fmt.Errorf("set %s: %w", edit.Path, err)
+		}
+		out = next
+	}
+	return out, nil
+}
+
+func setField(src []byte, path string, value any) ([]byte, error) {
+	spans := scanSpans(src)
+	if span, ok := spans[path]; ok {
+		if list, isList := value.(stringList); isList && src[span.valueStart] == '[' && arrayLen(spans, path) == len(list) && len(list) > 1 {
+			// Replace elements one by one, last first, so a multi-line
+			// array keeps its layout.
+			out := src
+			for i := len(list) - 1; i >= 0; i-- {
+				elem := spans[path+"["+strconv.Itoa(i)+"]"]
+				encoded, err := encodeJSONValue(list[i], "", "")
+				if err != nil {
+					return nil, err
+				}
+				out = splice(out, elem.valueStart, elem.valueEnd, encoded)
+			}
+			return out, nil
+		}
+		encoded, err := encodeJSONValue(value, lineIndent(src, span.keyStart), detectIndentUnit(src))
+		if err != nil {
+			return nil, err
+		}
+		return splice(src, span.valueStart, span.valueEnd, encoded), nil
+	}
+
+	parent, key := "", path
+	if cut := strings.LastIndex(path, "."); cut >= 0 {
+		parent, key = path[:cut], path[cut+1:]
+	}
+	parentSpan, ok := spans[parent]
+	if !ok {
+		// Build the missing parent around the value and insert that instead.
+		return setField(src, parent, map[string]any{key: value})
+	}
+	if src[parentSpan.valueStart] != '{' {
+		return nil, fmt.Errorf("%s is not an object", parent)
+	}
+	unit := detectIndentUnit(src)
+	closeAt := parentSpan.valueEnd - 1
+	var last *jsonSpan
+	prefix := parent + "."
+	if parent == "" {
+		prefix = ""
+	}
+	for p, span := range spans {
+		if p == "" || !strings.HasPrefix(p, prefix) || strings.ContainsAny(p[len(prefix):], ".[") {
+			continue
+		}
+		if last == nil || span.valueEnd > last.valueEnd {
+			s := span
+			last = &s
+		}
+	}
+
+	var insert bytes.Buffer
+	at := closeAt
+	if last != nil {
+		at = last.valueEnd
+		indent := lineIndent(src, last.keyStart)
+		sep := " "
+		if bytes.ContainsRune(src[parentSpan.valueStart:last.keyStart], '\n') {
+			sep = "\n" + indent
+		}
+		encoded, err := encodeJSONValue(value, indent, unit)
+		if err != nil {
+			return nil, err
+		}
+		insert.WriteString(",")
+		insert.WriteString(sep)
+		fmt.Fprintf(&insert, "%q: ", key)
+		insert.Write(encoded)
+	} else {
+		parentIndent := lineIndent(src, parentSpan.keyStart)
+		indent := parentIndent + unit
+		encoded, err := encodeJSONValue(value, indent, unit)
+		if err != nil {
+			return nil, err
+		}
+		insert.WriteString("\n" + indent)
+		fmt.Fprintf(&insert, "%q: ", key)
+		insert.Write(encoded)
+		insert.WriteString("\n" + parentIndent)
+		// Drop any whitespace already sitting inside the empty braces.
+		return splice(src, parentSpan.valueStart+1, closeAt, insert.Bytes()), nil
+	}
+	return splice(src, at, at, insert.Bytes()), nil
+}
+
+func splice(src []byte, start, end int, repl []byte) []byte {
+	out := make([]byte, 0, len(src)-(end-start)+len(repl))
+	out = append(out, src[:start]...)
+	out = append(out, repl...)
+	return append(out, src[end:]...)
+}
+
+func arrayLen(spans map[string]jsonSpan, path string) int {
+	n := 0
+	for {
+		if _, ok := spans[path+"["+strconv.Itoa(n)+"]"]; !ok {
+			return n
+		}
+		n++
+	}
+}
+
+// encodeJSONValue marshals v without HTML escaping (URLs keep their "&") and,
+// for nested objects, indents continuation lines relative to indent.
+func encodeJSONValue(v any, indent, unit string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if _, isMap := v.(map[string]any); isMap && unit != "" {
+		enc.SetIndent(indent, unit)
+	}
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+func lineIndent(src []byte, offset int) string {
+	start := bytes.LastIndexByte(src[:offset], '\n') + 1
+	end := start
+	for end < len(src) && (src[end] == ' ' || src[end] == '\t') {
+		end++
+	}
+	return string(src[start:end])
+}
+
+// detectIndentUnit guesses the indentation step from the first indented line,
+// falling back to two spaces.
+func detectIndentUnit(src []byte) string {
+	for _, line := range bytes.Split(src, []byte("\n")) {
+		trimmed := bytes.TrimLeft(line, " \t")
+		if len(trimmed) > 0 && len(trimmed) < len(line) {
+			return string(line[:len(line)-len(trimmed)])
+		}
+	}
+	return "  "
+}
@@ -0,0 +1,82 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRewriteSourcePreservesFormatting(t *testing.T) {
+	src := `{
+    "version": "1.0.0",
+    "description": "Example tool",
+    "checkver": {"github": "https://github.com/o/r", "regex": "v(?P<version>[\\d.]+)", "replace": "${version}"},
+    "architecture": {
+        "64bit": {
+            "url": "https://example.com/tool-1.0.0.zip",
+            "hash": "` + strictHash + `",
+            "extract_dir": "tool-1.0.0"
+        }
+    },
+    "autoupdate": {
+        "architecture": {
+            "64bit": {"url": "https://example.com/tool-$version.zip", "extract_dir": "tool-$version"},
+            "arm64": {"url": "https://example.com/tool-$version-arm64.zip?a=1&b=2"}
+        }
+    },
+    "bin": "tool.exe"
+}
+`
+	newHash := strings.Repeat("b", 64)
+	armHash := strings.Repeat("c", 64)
+	var arch Architecture
+	arch.Set(Arch64bit, Artifact{URL: "https://example.com/tool-2.0.0.zip", Hash: newHash, ExtractDir: "tool-2.0.0"})
+	arch.Set(ArchARM64, Artifact{URL: "https://example.com/tool-2.0.0-arm64.zip?a=1&b=2", Hash: armHash})
+
+	out, err := RewriteSource([]byte(src), "2.0.0", arch)
+	if err != nil {
+		t.Fatalf("RewriteSource failed: %v", err)
+	}
+	want := strings.NewReplacer(
+		`"version": "1.0.0"`, `"version": "2.0.0"`,
+		`"url": "https://example.com/tool-1.0.0.zip"`, `"url": "https://example.com/tool-2.0.0.zip"`,
+		strictHash, newHash,
+		`"extract_dir": "tool-1.0.0"
+        }`, `"extract_dir": "tool-2.0.0"
+        },
+        "arm64": {
+            "url": "https://example.com/tool-2.0.0-arm64.zip?a=1&b=2",
+            "hash": "`+armHash+`"
+        }`,
+	).Replace(src)
+	if string(out) != want {
+		t.Fatalf("unexpected rewrite:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestRewriteSourceUpdatesTopLevelHashAndInsertsMissingHash(t *testing.T) {
+	src := `{"version": "1.0", "bin": "a.exe", "hash": "` + strictHash + `", "architecture": {"64bit": {"url": "https://example.com/a-1.0.zip"}}}`
+	newHash := strings.Repeat("d", 64)
+	var arch Architecture
+	arch.Set(Arch64bit, Artifact{URL: "https://example.com/a-1.1.zip", Hash: newHash})
+
+	out, err := RewriteSource([]byte(src), "1.1", arch)
+	if err != nil {
+		t.Fatalf("RewriteSource failed: %v", err)
+	}
+	want := `{"version": "1.1", "bin": "a.exe", "hash": "` + newHash + `", "architecture": {"64bit": {"url": "https://example.com/a-1.1.zip", "hash": "` + newHash + `"}}}`
+	if string(out) != want {
+		t.Fatalf("unexpected rewrite:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestSetFieldsKeepsMultiLineArrays(t *testing.T) {
+	src := "{\n  \"url\": [\n    \"https://a/1\",\n    \"https://b/1\"\n  ]\n}"
+	out, err := SetFields([]byte(src), []FieldEdit{{Path: "url", Value: stringList{"https://a/2", "https://b/2"}}})
+	if err != nil {
+		t.Fatalf("SetFields failed: %v", err)
+	}
+	want := "{\n  \"url\": [\n    \"https://a/2\",\n    \"https://b/2\"\n  ]\n}"
+	if string(out) != want {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+}
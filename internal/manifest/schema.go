@@ -0,0 +1,100 @@
+package manifest
+
+import "encoding/json"
+
+// JSONSchema returns a JSON Schema (draft 2020-12) describing the manifest
+// format, for editors and CI linters. It mirrors what strict parsing accepts.
+func JSONSchema() ([]byte, error) {
+	return json.MarshalIndent(manifestSchema(), "", "  ")
+}
+
+type schemaObject = map[string]any
+
+func manifestSchema() schemaObject {
+	str := schemaObject{"type": "string"}
+	strList := schemaObject{
+		"oneOf": []any{
+			str,
+			schemaObject{"type": "array", "items": str},
+		},
+	}
+	artifact := schemaObject{
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": schemaObject{
+			"url":         strList,
+			"hash":        strList,
+			"extract_dir": strList,
+			"extract_to":  strList,
+			"type":        schemaObject{"type": "string", "enum": []any{ArtifactTypeRaw}},
+		},
+	}
+	architecture := schemaObject{
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties":           schemaObject{},
+	}
+	for _, arch := range Architectures {
+		architecture["properties"].(schemaObject)[arch] = schemaObject{"$ref": "#/$defs/artifact"}
+	}
+	extractStep := schemaObject{
+		"type":                 "object",
+		"additionalProperties": false,
+		"required":             []any{"action"},
+		"properties": schemaObject{
+			"action": schemaObject{
+				"type": "string",
+				"enum": []any{ExtractActionExtract, ExtractActionSelect, ExtractActionStrip},
+			},
+			"glob":       str,
+			"tool":       schemaObject{"type": "string", "enum": []any{ExtractTool7Zip}},
+			"components": schemaObject{"type": "integer", "minimum": 0},
+		},
+	}
+	return schemaObject{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"title":                "Appstract manifest",
+		"type":                 "object",
+		"additionalProperties": false,
+		"required":             []any{"version", "bin"},
+		"properties": schemaObject{
+			"version":     schemaObject{"type": "string", "minLength": 1},
+			"description": str,
+			"checkver": schemaObject{
+				"type":                 "object",
+				"additionalProperties": false,
+				"properties": schemaObject{
+					"github":  schemaObject{"type": "string", "pattern": "^https://github\\.com/"},
+					"regex":   str,
+					"replace": str,
+				},
+			},
+			"architecture": schemaObject{"$ref": "#/$defs/architecture"},
+			"autoupdate": schemaObject{
+				"type":                 "object",
+				"additionalProperties": false,
+				"properties": schemaObject{
+					"architecture": schemaObject{"$ref": "#/$defs/architecture"},
+				},
+			},
+			"bin": schemaObject{"type": "string", "minLength": 1},
+			"shortcuts": schemaObject{
+				"type":  "array",
+				"items": schemaObject{"type": "array", "items": str},
+			},
+			"pre_install": schemaObject{"type": "array", "items": str},
+			"hash":        schemaObject{"type": "string", "pattern": "^([Ss][Hh][Aa]256:)?[0-9a-fA-F]{64}$"},
+			"extract":     schemaObject{"type": "array", "items": schemaObject{"$ref": "#/$defs/extractStep"}},
+			"env_set":     schemaObject{"type": "object", "additionalProperties": str},
+			"env_add_path": schemaObject{
+				"oneOf": []any{str, schemaObject{"type": "array", "items": str}},
+			},
+			"depends": schemaObject{"type": "array", "items": schemaObject{"type": "string", "minLength": 1}, "uniqueItems": true},
+		},
+		"$defs": schemaObject{
+			"artifact":     artifact,
+			"architecture": architecture,
+			"extractStep":  extractStep,
+		},
+	}
+}
@@ -0,0 +1,218 @@
+package manifest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseOptions tunes how a manifest is decoded. Strict mode rejects unknown
+// fields and applies the deeper checks in ValidateStrict.
+type ParseOptions struct {
+	Strict bool
+}
+
+func ParseFileWithOptions(path string, opts ParseOptions) (*Manifest, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest file: %w", err)
+	}
+	return ParseBytesWithOptions(b, opts)
+}
+
+func ParseBytesWithOptions(b []byte, opts ParseOptions) (*Manifest, error) {
+	if !opts.Strict {
+		return ParseBytes(b)
+	}
+	m, issues := Check(b, opts)
+	if err := firstError(issues); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func describeDecodeError(err error) (string, string) {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return typeErr.Field, fmt.Sprintf("decode manifest json: field %s: expected %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value)
+	}
+	return "", fmt.Sprintf("decode manifest json: %v", err)
+}
+
+// unknownFieldIssues walks the raw document against the Manifest type and
+// reports every key that no field accepts, with its full path. The decoder's
+// DisallowUnknownFields is not enough on its own because Artifact decodes
+// itself and the option does not propagate into custom unmarshalers.
+func unknownFieldIssues(doc any, severity Severity) []Issue {
+	var issues []Issue
+	walkKnownFields(doc, reflect.TypeOf(Manifest{}), "", func(path string) {
+		issues = append(issues, Issue{Path: path, Severity: severity, Message: "manifest has unknown field " + path})
+	})
+	return issues
+}
+
+func walkKnownFields(v any, t reflect.Type, path string, report func(string)) {
+	if t == reflect.TypeOf(Artifact{}) {
+		t = reflect.TypeOf(artifactJSON{})
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return
+		}
+		fields := jsonFieldTypes(t)
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fieldType, ok := fields[key]
+			if !ok {
+				report(joinFieldPath(path, key))
+				continue
+			}
+			walkKnownFields(obj[key], fieldType, joinFieldPath(path, key), report)
+		}
+	case reflect.Slice:
+		arr, ok := v.([]any)
+		if !ok {
+			return
+		}
+		for i, elem := range arr {
+			walkKnownFields(elem, t.Elem(), path+"["+strconv.Itoa(i)+"]", report)
+		}
+	}
+}
+
+func jsonFieldTypes(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func joinFieldPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+var (
+	sha256Pattern      = regexp.MustCompile(`^(?i:sha256:)?[0-9a-fA-F]{64}$`)
+	templateRefPattern = regexp.MustCompile(`\$\{(\w+)\}|\$(\w+)`)
+)
+
+// ValidateStrict applies the checks that plain parsing tolerates for
+// compatibility: URL schemes, hash format, checkver regex and replace
+// references, and paths that would escape the app directory.
+func (m Manifest) ValidateStrict() error {
+	return firstError(m.strictIssues(SeverityError))
+}
+
+func (m Manifest) strictIssues(severity Severity) []Issue {
+	var issues []Issue
+	if !isRelativeInside(m.Bin) {
+		issues = append(issues, Issue{Path: "bin", Severity: severity, Message: "manifest bin must stay inside the app directory: " + m.Bin})
+	}
+	if m.Hash != "" && !sha256Pattern.MatchString(m.Hash) {
+		issues = append(issues, Issue{Path: "hash", Severity: severity, Message: "manifest hash is not a sha256 digest: " + m.Hash})
+	}
+	for _, arch := range Architectures {
+		issues = append(issues, artifactStrictIssues("architecture."+arch, m.Architecture.Get(arch), true, severity)...)
+		issues = append(issues, artifactStrictIssues("autoupdate.architecture."+arch, m.Autoupdate.Architecture.Get(arch), false, severity)...)
+	}
+	return append(issues, m.Checkver.strictIssues(severity)...)
+}
+
+func artifactStrictIssues(path string, artifact Artifact, checkHash bool, severity Severity) []Issue {
+	if artifact.IsZero() {
+		return nil
+	}
+	var issues []Issue
+	items := artifact.Items()
+	for i, item := range items {
+		suffix := ""
+		if len(items) > 1 {
+			suffix = "[" + strconv.Itoa(i) + "]"
+		}
+		if item.URL != "" && !strings.HasPrefix(strings.ToLower(item.URL), "https://") {
+			issues = append(issues, Issue{
+				Path:     path + ".url" + suffix,
+				Severity: severity,
+				Message:  fmt.Sprintf("manifest %s.url%s must use https: %s", path, suffix, item.URL),
+			})
+		}
+		if checkHash && item.Hash != "" && !sha256Pattern.MatchString(item.Hash) {
+			issues = append(issues, Issue{
+				Path:     path + ".hash" + suffix,
+				Severity: severity,
+				Message:  fmt.Sprintf("manifest %s.hash%s is not a sha256 digest: %s", path, suffix, item.Hash),
+			})
+		}
+	}
+	return issues
+}
+
+func (c Checkver) strictIssues(severity Severity) []Issue {
+	var issues []Issue
+	if c.GitHub != "" && !strings.HasPrefix(strings.ToLower(c.GitHub), "https://github.com/") {
+		issues = append(issues, Issue{Path: "checkver.github", Severity: severity, Message: "manifest checkver.github must be an https://github.com/ repository url: " + c.GitHub})
+	}
+	if c.Regex == "" {
+		if c.Replace != "" {
+			issues = append(issues, Issue{Path: "checkver.replace", Severity: severity, Message: "manifest checkver.replace requires checkver.regex"})
+		}
+		return issues
+	}
+	re, err := regexp.Compile(c.Regex)
+	if err != nil {
+		return append(issues, Issue{Path: "checkver.regex", Severity: severity, Message: fmt.Sprintf("manifest checkver.regex is invalid: %v", err)})
+	}
+	groups := map[string]bool{}
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			groups[name] = true
+		}
+	}
+	for _, ref := range templateRefPattern.FindAllStringSubmatch(c.Replace, -1) {
+		name := ref[1]
+		if name == "" {
+			name = ref[2]
+		}
+		if !referencesCapture(name, groups) {
+			issues = append(issues, Issue{Path: "checkver.replace", Severity: severity, Message: fmt.Sprintf("manifest checkver.replace references unknown capture group %q", name)})
+		}
+	}
+	return issues
+}
+
+// referencesCapture mirrors the placeholder forms accepted when rendering
+// templates: $name, ${name} and $matchName.
+func referencesCapture(name string, groups map[string]bool) bool {
+	if groups[name] {
+		return true
+	}
+	rest, ok := strings.CutPrefix(name, "match")
+	if !ok || rest == "" {
+		return false
+	}
+	return groups[strings.ToLower(rest[:1])+rest[1:]]
+}
@@ -0,0 +1,120 @@
+package manifest
+
+import (
+	stdjson "encoding/json"
+	"strings"
+	"testing"
+)
+
+const strictHash = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func TestParseBytesStrictAcceptsValidManifest(t *testing.T) {
+	content := `{
+		"version": "1.2.3",
+		"checkver": {"github": "https://github.com/owner/repo", "regex": "v(?P<version>[\\d.]+)", "replace": "${version}"},
+		"architecture": {"64bit": {"url": "https://example.com/app.zip", "hash": "sha256:` + strictHash + `"}},
+		"autoupdate": {"architecture": {"64bit": {"url": "https://example.com/app-$version.zip"}}},
+		"bin": "bin/app.exe"
+	}`
+	if _, err := ParseBytesWithOptions([]byte(content), ParseOptions{Strict: true}); err != nil {
+		t.Fatalf("expected strict parse to succeed: %v", err)
+	}
+}
+
+func TestParseBytesStrictRejections(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "unknown top-level field",
+			content: `{"version": "1.0", "bin": "app.exe", "pre_instal": ["echo"]}`,
+			want:    "unknown field pre_instal",
+		},
+		{
+			name:    "unknown nested artifact field",
+			content: `{"version": "1.0", "bin": "app.exe", "architecture": {"64bit": {"url": "https://example.com/a.zip", "extractdir": "a"}}}`,
+			want:    "unknown field architecture.64bit.extractdir",
+		},
+		{
+			name:    "unknown field in extract step",
+			content: `{"version": "1.0", "bin": "app.exe", "architecture": {"64bit": {"url": "https://example.com/a.zip"}}, "extract": [{"action": "strip", "depth": 2}]}`,
+			want:    "unknown field extract[0].depth",
+		},
+		{
+			name:    "wrong field type",
+			content: `{"version": "1.0", "bin": "app.exe", "pre_install": "echo", "architecture": {"64bit": {"url": "https://example.com/a.zip"}}}`,
+			want:    "field pre_install",
+		},
+		{
+			name:    "plain http url",
+			content: `{"version": "1.0", "bin": "app.exe", "architecture": {"64bit": {"url": "http://example.com/a.zip", "hash": "HASH"}}}`,
+			want:    "architecture.64bit.url must use https",
+		},
+		{
+			name:    "malformed hash",
+			content: `{"version": "1.0", "bin": "app.exe", "architecture": {"64bit": {"url": "https://example.com/a.zip", "hash": "abc"}}}`,
+			want:    "architecture.64bit.hash is not a sha256 digest",
+		},
+		{
+			name:    "invalid checkver regex",
+			content: `{"version": "1.0", "bin": "app.exe", "checkver": {"github": "https://github.com/o/r", "regex": "v(["}, "architecture": {"64bit": {"url": "https://example.com/a.zip", "hash": "HASH"}}}`,
+			want:    "checkver.regex is invalid",
+		},
+		{
+			name:    "replace references missing group",
+			content: `{"version": "1.0", "bin": "app.exe", "checkver": {"github": "https://github.com/o/r", "regex": "v(?P<version>[\\d.]+)", "replace": "${build}"}, "architecture": {"64bit": {"url": "https://example.com/a.zip", "hash": "HASH"}}}`,
+			want:    `unknown capture group "build"`,
+		},
+		{
+			name:    "bin escapes app directory",
+			content: `{"version": "1.0", "bin": "../other/app.exe", "architecture": {"64bit": {"url": "https://example.com/a.zip", "hash": "HASH"}}}`,
+			want:    "bin must stay inside",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseBytesWithOptions([]byte(strings.ReplaceAll(tc.content, "HASH", strictHash)), ParseOptions{Strict: true})
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("expected error containing %q, got: %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestParseBytesNonStrictToleratesUnknownFields(t *testing.T) {
+	content := `{"version": "1.0", "bin": "app.exe", "notes": "x", "architecture": {"64bit": {"url": "http://example.com/a.zip", "hash": "abc"}}}`
+	if _, err := ParseBytesWithOptions([]byte(content), ParseOptions{}); err != nil {
+		t.Fatalf("expected lenient parse to succeed: %v", err)
+	}
+}
+
+func TestReferencesCaptureAcceptsMatchPrefix(t *testing.T) {
+	groups := map[string]bool{"version": true}
+	for name, want := range map[string]bool{"version": true, "matchVersion": true, "match": false, "build": false} {
+		if got := referencesCapture(name, groups); got != want {
+			t.Fatalf("referencesCapture(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestJSONSchemaIsValidJSON(t *testing.T) {
+	b, err := JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema failed: %v", err)
+	}
+	var doc map[string]any
+	if err := stdjson.Unmarshal(b, &doc); err != nil {
+		t.Fatalf("schema is not valid json: %v", err)
+	}
+	props, ok := doc["properties"].(map[string]any)
+	if !ok {
+		t.Fatalf("schema has no properties: %s", b)
+	}
+	for _, key := range []string{"version", "bin", "architecture", "autoupdate", "extract", "pre_install"} {
+		if _, ok := props[key]; !ok {
+			t.Fatalf("schema missing property %q", key)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Package scheduler runs the periodic update checks of `appstract daemon`
+// and `appstract serve --schedule`.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+	"time"
+
+	"appstract/internal/config"
+	"appstract/internal/updater"
+)
+
+// Decision is what one pass decided for one installed app.
+type Decision struct {
+	App          string
+	ManifestPath string
+	Due          bool
+	// Reason explains a skipped app.
+	Reason string
+	// Checkver is false for pinned apps, which only ever move to the
+	// version their manifest names.
+	Checkver bool
+}
+
+// Scheduler checks every installed app whose last check is older than the
+// configured TTL. Apps are checked one at a time; a TTL of zero turns
+// scheduled checks off.
+type Scheduler struct {
+	Root string
+	// Config is read on every pass, so edits to config.yaml apply without
+	// a restart.
+	Config   func() (config.Config, error)
+	Interval time.Duration
+	Now      func() time.Time
+	// Update checks one app and installs what it finds. It is expected to
+	// defer the switch of a running app.
+	Update func(app, manifestPath string, checkver bool) error
+	// OnSkip and OnResult report on each pass, OnError a pass that could
+	// not run, such as one that found config.yaml unreadable; any may be nil.
+	OnSkip   func(app, reason string)
+	OnResult func(app string, err error)
+	OnError  func(err error)
+
+	// attempted holds when each app was last handed to Update, for checks
+	// that fail before they can record LastCheckAt.
+	attempted map[string]time.Time
+}
+
+// New returns a scheduler for root that reads root/config.yaml and looks
+// for due apps once a minute.
+func New(root string) *Scheduler {
+	return &Scheduler{
+		Root:     root,
+		Config:   func() (config.Config, error) { return config.Load(root) },
+		Interval: time.Minute,
+		Now:      time.Now,
+	}
+}
+
+// Run checks due apps now and then on every interval until ctx is done. A
+// failed pass goes to OnError and the next one runs as usual, so a passing
+// problem such as a half-edited config.yaml does not stop the schedule.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		if err := s.RunOnce(ctx); err != nil && s.OnError != nil {
+			s.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce runs one pass: it plans, then updates the due apps in turn.
+func (s *Scheduler) RunOnce(ctx context.Context) error {
+	cfg, err := s.Config()
+	if err != nil {
+		return err
+	}
+	now := s.Now()
+	if cfg.CheckTTLSeconds <= 0 || cfg.QuietHours.Contains(now) {
+		return nil
+	}
+	decisions, err := s.Plan(cfg, now)
+	if err != nil {
+		return err
+	}
+	for _, d := range decisions {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if !d.Due && d.Reason == "" {
+			continue
+		}
+		// A skipped app counts as checked too, so it is reported once
+		// per TTL rather than on every pass.
+		if s.attempted == nil {
+			s.attempted = map[string]time.Time{}
+		}
+		s.attempted[d.App] = now
+		if !d.Due {
+			if s.OnSkip != nil {
+				s.OnSkip(d.App, d.Reason)
+			}
+			continue
+		}
+		err := s.Update(d.App, d.ManifestPath, d.Checkver)
+		if s.OnResult != nil {
+			s.OnResult(d.App, err)
+		}
+	}
+	return nil
+}
+
+// Plan decides for every installed app with a manifest whether it is due at
+// now. Apps that are not due yet get no reason; held and pinned apps do.
+func (s *Scheduler) Plan(cfg config.Config, now time.Time) ([]Decision, error) {
+	apps, err := updater.ListApps(s.Root)
+	if err != nil {
+		return nil, err
+	}
+	ttl := time.Duration(cfg.CheckTTLSeconds) * time.Second
+	var decisions []Decision
+	for _, app := range apps {
+		st, err := updater.ReadStatus(s.Root, app)
+		if err != nil || !st.Installed || st.ManifestVersion == "" {
+			continue
+		}
+		d := Decision{
+			App:          app,
+			ManifestPath: filepath.Join(s.Root, "manifests", app+".json"),
+			Checkver:     true,
+		}
+		last, _ := time.Parse(time.RFC3339, st.LastCheckAt)
+		if attempted := s.attempted[app]; attempted.After(last) {
+			last = attempted
+		}
+		next := last.Add(ttl + jitter(app, last, cfg.CheckJitterSeconds))
+		pin, pinned := cfg.Pinned(app)
+		hold, isHeld := cfg.Held(app)
+		switch {
+		case now.Before(next):
+		case st.Locked:
+			d.Reason = "update already running"
+		case isHeld:
+			d.Reason = "held: " + hold
+		case pinned && st.CurrentVersion == pin:
+			d.Reason = "pinned to " + pin
+		case pinned && st.ManifestVersion != pin:
+			d.Reason = fmt.Sprintf("pinned to %s, manifest offers %s", pin, st.ManifestVersion)
+		default:
+			d.Due = true
+			d.Checkver = !pinned
+		}
+		decisions = append(decisions, d)
+	}
+	return decisions, nil
+}
+
+// jitter spreads checks of different apps over up to maxSeconds. It only
+// depends on the app and its last check, so every pass agrees on it.
+func jitter(app string, last time.Time, maxSeconds int) time.Duration {
+	if maxSeconds <= 0 {
+		return 0
+	}
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s|%d", app, last.Unix())
+	return time.Duration(h.Sum32()%uint32(maxSeconds+1)) * time.Second
+}
@@ -0,0 +1,152 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"appstract/internal/config"
+)
+
+func writeApp(t *testing.T, root, app, manifestVersion, currentVersion, lastCheck string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(root, "apps", app, "current"), 0o755); err != nil {
+		t.Fatalf("create app: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "manifests"), 0o755); err != nil {
+		t.Fatalf("create manifests: %v", err)
+	}
+	manifestJSON := `{"version":"` + manifestVersion + `","architecture":{"64bit":{"url":"https://example.invalid/` + app + `.zip","hash":"` + strings.Repeat("a", 64) + `"}},"bin":"` + app + `.exe"}`
+	if err := os.WriteFile(filepath.Join(root, "manifests", app+".json"), []byte(manifestJSON), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	state := `{"current_version":"` + currentVersion + `","last_check_at":"` + lastCheck + `"}`
+	if err := os.WriteFile(filepath.Join(root, "apps", app, "runtime.json"), []byte(state), 0o644); err != nil {
+		t.Fatalf("write state: %v", err)
+	}
+}
+
+func TestSchedulerChecksDueAppsOnly(t *testing.T) {
+	root := t.TempDir()
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.Local)
+	hourAgo := now.Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+	writeApp(t, root, "never", "2.0", "1.0", "")
+	writeApp(t, root, "fresh", "2.0", "1.0", now.Add(-10*time.Minute).UTC().Format(time.RFC3339))
+	writeApp(t, root, "stale", "2.0", "1.0", hourAgo)
+	writeApp(t, root, "held", "2.0", "1.0", hourAgo)
+	writeApp(t, root, "pinnedcurrent", "2.0", "1.0", hourAgo)
+	writeApp(t, root, "pinnednext", "2.0", "1.0", hourAgo)
+
+	cfg := config.Default()
+	cfg.CheckJitterSeconds = 0
+	cfg.Holds = map[string]string{"held": "waiting for a fix"}
+	cfg.Pins = map[string]string{"pinnedcurrent": "1.0", "pinnednext": "2.0"}
+	s := New(root)
+	s.Config = func() (config.Config, error) { return cfg, nil }
+	s.Now = func() time.Time { return now }
+	var updated, skipped []string
+	s.Update = func(app, manifestPath string, checkver bool) error {
+		updated = append(updated, app+":"+map[bool]string{true: "checkver", false: "manifest"}[checkver])
+		return nil
+	}
+	s.OnSkip = func(app, reason string) { skipped = append(skipped, app+": "+reason) }
+
+	if err := s.RunOnce(context.Background()); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if got := strings.Join(updated, ","); got != "never:checkver,pinnednext:manifest,stale:checkver" {
+		t.Fatalf("unexpected updates: %s", got)
+	}
+	if got := strings.Join(skipped, "; "); got != "held: held: waiting for a fix; pinnedcurrent: pinned to 1.0" {
+		t.Fatalf("unexpected skips: %s", got)
+	}
+
+	// Attempts count as checks, even though Update recorded nothing here.
+	updated, skipped = nil, nil
+	if err := s.RunOnce(context.Background()); err != nil || len(updated) != 0 || len(skipped) != 0 {
+		t.Fatalf("expected nothing due on the next pass, got %v %v (%v)", updated, skipped, err)
+	}
+}
+
+func TestSchedulerHonorsQuietHoursAndJitter(t *testing.T) {
+	root := t.TempDir()
+	now := time.Date(2026, 10, 18, 23, 0, 0, 0, time.Local)
+	writeApp(t, root, "a", "2.0", "1.0", "")
+
+	cfg := config.Default()
+	cfg.QuietHours, _ = config.ParseQuietHours("22:00-07:00")
+	s := New(root)
+	s.Config = func() (config.Config, error) { return cfg, nil }
+	s.Now = func() time.Time { return now }
+	calls := 0
+	s.Update = func(app, manifestPath string, checkver bool) error {
+		calls++
+		return nil
+	}
+	if err := s.RunOnce(context.Background()); err != nil || calls != 0 {
+		t.Fatalf("expected no checks in quiet hours, got %d (%v)", calls, err)
+	}
+
+	last := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	for _, app := range []string{"a", "b", "c"} {
+		if d := jitter(app, last, 300); d < 0 || d > 300*time.Second || d != jitter(app, last, 300) {
+			t.Fatalf("jitter for %s out of range or unstable: %v", app, d)
+		}
+	}
+	if jitter("a", last, 0) != 0 {
+		t.Fatalf("expected no jitter when disabled")
+	}
+}
+
+func TestSchedulerRunSurvivesFailedPass(t *testing.T) {
+	root := t.TempDir()
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.Local)
+	writeApp(t, root, "app", "2.0", "1.0", "")
+
+	cfg := config.Default()
+	cfg.CheckJitterSeconds = 0
+	s := New(root)
+	s.Interval = 10 * time.Millisecond
+	s.Now = func() time.Time { return now }
+	// The first pass finds config.yaml mid-edit.
+	calls := 0
+	s.Config = func() (config.Config, error) {
+		calls++
+		if calls == 1 {
+			return config.Config{}, errors.New("config.yaml: unexpected end of line")
+		}
+		return cfg, nil
+	}
+	var passErrs []error
+	s.OnError = func(err error) { passErrs = append(passErrs, err) }
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updated := make(chan string, 1)
+	s.Update = func(app, manifestPath string, checkver bool) error {
+		updated <- app
+		cancel()
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	select {
+	case app := <-updated:
+		if app != "app" {
+			t.Fatalf("unexpected update of %s", app)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the next pass to run after a failed one")
+	}
+	<-done
+	if len(passErrs) != 1 {
+		t.Fatalf("expected the failed pass reported once, got %v", passErrs)
+	}
+}
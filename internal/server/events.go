@@ -0,0 +1,107 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// event is one Server-Sent Event: message, progress, stage or done.
+type event struct {
+	Type string
+	App  string
+	Data any
+}
+
+// broker fans events out to the connected streams. A stream that falls
+// behind loses events rather than stalling the update that produces them.
+type broker struct {
+	mu     sync.Mutex
+	subs   map[chan event]string
+	closed bool
+}
+
+// subscribe returns a channel receiving the events of app, or of every app
+// when app is empty, and the function that unsubscribes it.
+func (b *broker) subscribe(app string) (<-chan event, func()) {
+	ch := make(chan event, 64)
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+	if b.subs == nil {
+		b.subs = map[chan event]string{}
+	}
+	b.subs[ch] = strings.ToLower(app)
+	b.mu.Unlock()
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subs, ch)
+		b.mu.Unlock()
+	}
+}
+
+// close ends every stream, now and from then on.
+func (b *broker) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for ch := range b.subs {
+		close(ch)
+		delete(b.subs, ch)
+	}
+}
+
+func (b *broker) publish(e event) {
+	app := strings.ToLower(e.App)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch, filter := range b.subs {
+		if filter != "" && filter != app {
+			continue
+		}
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// handleEvents streams events until the client disconnects; ?app= limits
+// the stream to one app.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "", "streaming is not supported")
+		return
+	}
+	events, unsubscribe := s.events.subscribe(r.URL.Query().Get("app"))
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	// The comment tells clients the subscription is in place.
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			b, err := json.Marshal(e.Data)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, b)
+			flusher.Flush()
+		}
+	}
+}
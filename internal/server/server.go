@@ -0,0 +1,459 @@
+// Package server implements the local HTTP/JSON control API that
+// `appstract serve` exposes to tray apps and launchers.
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"appstract/internal/manifest"
+	"appstract/internal/updater"
+)
+
+// Server serves the control API for one workspace. Every request must carry
+// the token as "Authorization: Bearer <token>"; the event stream also
+// accepts it as the token query parameter, since browser EventSource cannot
+// set headers.
+//
+// Updates, rollbacks and launches of the same app are serialized: a request
+// for an app that is busy gets 409 instead of waiting.
+type Server struct {
+	Root  string
+	Token string
+	// NewManager returns an updater configured for Root. The server sets
+	// its OnMessage, OnProgress and OnEvent callbacks.
+	NewManager func() (*updater.Manager, error)
+	// Launch starts an app binary for run requests.
+	Launch func(path string) error
+
+	// update is what an update request runs; tests replace it.
+	update func(m *updater.Manager, app, manifestPath string) error
+
+	mu      sync.Mutex
+	busy    map[string]string
+	events  broker
+	pending sync.WaitGroup
+}
+
+// New returns a server for root that authenticates with token.
+func New(root, token string) *Server {
+	return &Server{
+		Root:  root,
+		Token: token,
+		NewManager: func() (*updater.Manager, error) {
+			return updater.NewManager(root), nil
+		},
+		update: func(m *updater.Manager, app, manifestPath string) error {
+			return m.UpdateFromManifest(app, manifestPath)
+		},
+	}
+}
+
+// Handler returns the API routes behind token authentication.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/apps", s.handleList)
+	mux.HandleFunc("GET /v1/apps/{app}", s.handleStatus)
+	mux.HandleFunc("POST /v1/apps/{app}/update", s.handleUpdate)
+	mux.HandleFunc("POST /v1/apps/{app}/run", s.handleRun)
+	mux.HandleFunc("POST /v1/apps/{app}/rollback", s.handleRollback)
+	mux.HandleFunc("GET /v1/log", s.handleLog)
+	mux.HandleFunc("GET /v1/events", s.handleEvents)
+	return s.authenticate(mux)
+}
+
+// Close ends the open event streams, which http.Server.Shutdown would
+// otherwise wait for. Call it before Shutdown, and Wait after.
+func (s *Server) Close() {
+	s.events.close()
+}
+
+// Wait waits for the updates started by update requests to finish. No
+// request may arrive meanwhile, so call it once the listener is shut down.
+func (s *Server) Wait() {
+	s.pending.Wait()
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok && r.URL.Path == "/v1/events" {
+			token, ok = r.URL.Query().Get("token"), true
+		}
+		if !ok || s.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "", "missing or invalid token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// acquire marks app busy with op. The returned release must be called once
+// the operation is over; ok is false when another operation holds the app.
+func (s *Server) acquire(app, op string) (release func(), holder string, ok bool) {
+	key := strings.ToLower(app)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if held := s.busy[key]; held != "" {
+		return nil, held, false
+	}
+	if s.busy == nil {
+		s.busy = map[string]string{}
+	}
+	s.busy[key] = op
+	return func() {
+		s.mu.Lock()
+		delete(s.busy, key)
+		s.mu.Unlock()
+	}, "", true
+}
+
+func (s *Server) operation(app string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.busy[strings.ToLower(app)]
+}
+
+// appStatus is updater.AppStatus plus the operation this server is running
+// for the app, if any.
+type appStatus struct {
+	updater.AppStatus
+	Operation string `json:"operation,omitempty"`
+}
+
+func (s *Server) status(app string) (appStatus, error) {
+	st, err := updater.ReadStatus(s.Root, app)
+	return appStatus{AppStatus: st, Operation: s.operation(app)}, err
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	apps, err := updater.ListApps(s.Root)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, updater.ErrCodeFilesystem, err.Error())
+		return
+	}
+	list := make([]appStatus, 0, len(apps))
+	for _, app := range apps {
+		st, err := s.status(app)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, updater.ErrCodeFilesystem, err.Error())
+			return
+		}
+		list = append(list, st)
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	app, ok := s.knownApp(w, r)
+	if !ok {
+		return
+	}
+	st, err := s.status(app)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, updater.ErrCodeFilesystem, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+// handleUpdate starts an update in the background and answers 202; progress
+// and the outcome arrive on the event stream. ?checkver=true resolves the
+// latest version first.
+func (s *Server) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	app, ok := s.knownApp(w, r)
+	if !ok {
+		return
+	}
+	manifestPath := filepath.Join(s.Root, "manifests", app+".json")
+	if _, err := os.Stat(manifestPath); err != nil {
+		writeError(w, http.StatusNotFound, updater.ErrCodeManifestInvalid, fmt.Sprintf("app %s has no manifest", app))
+		return
+	}
+	checkver, _ := strconv.ParseBool(r.URL.Query().Get("checkver"))
+	manager, ok := s.manager(w, app)
+	if !ok {
+		return
+	}
+	manager.UseCheckver = checkver
+	release, holder, ok := s.acquire(app, "update")
+	if !ok {
+		writeBusy(w, app, holder)
+		return
+	}
+	s.pending.Add(1)
+	go func() {
+		defer s.pending.Done()
+		defer release()
+		err := s.update(manager, app, manifestPath)
+		s.events.publish(doneEvent(app, "update", err))
+	}()
+	writeJSON(w, http.StatusAccepted, map[string]any{"app": app, "operation": "update"})
+}
+
+// ScheduledUpdate runs a scheduler check of app under the same per-app lock
+// as update requests, streaming its progress like they do. A running app is
+// not stopped; its switch is deferred. It fails with LOCK_BUSY when the app
+// is busy.
+func (s *Server) ScheduledUpdate(app, manifestPath string, checkver bool) error {
+	manager, err := s.NewManager()
+	if err != nil {
+		return err
+	}
+	s.observe(manager, app)
+	manager.UseCheckver = checkver
+	manager.DeferSwitchIfRunning = true
+	release, holder, ok := s.acquire(app, "update")
+	if !ok {
+		return &updater.Error{Code: updater.ErrCodeLockBusy, Stage: "lock", App: app, Retryable: true, Err: fmt.Errorf("%s in progress", holder)}
+	}
+	defer release()
+	err = s.update(manager, app, manifestPath)
+	s.events.publish(doneEvent(app, "update", err))
+	return err
+}
+
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	app, ok := s.knownApp(w, r)
+	if !ok {
+		return
+	}
+	release, holder, ok := s.acquire(app, "run")
+	if !ok {
+		writeBusy(w, app, holder)
+		return
+	}
+	defer release()
+	man, err := manifest.ParseFile(filepath.Join(s.Root, "manifests", app+".json"))
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, updater.ErrCodeManifestInvalid, err.Error())
+		return
+	}
+	manager, ok := s.manager(w, app)
+	if !ok {
+		return
+	}
+	// A staged pending version is switched to first, as `appstract run`
+	// does; if that fails the current version is launched.
+	applied, _ := manager.ApplyPending(app, man.Bin)
+	binPath := filepath.Join(s.Root, "apps", app, "current", man.Bin)
+	if _, err := os.Stat(binPath); err != nil {
+		writeError(w, http.StatusConflict, "", fmt.Sprintf("app %s is not installed: %v", app, err))
+		return
+	}
+	if s.Launch == nil {
+		writeError(w, http.StatusNotImplemented, "", "launching is not available")
+		return
+	}
+	if err := s.Launch(binPath); err != nil {
+		writeError(w, http.StatusInternalServerError, "", fmt.Sprintf("launch %s: %v", app, err))
+		return
+	}
+	body := map[string]any{"app": app, "bin": binPath}
+	if applied != "" {
+		body["applied_version"] = applied
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+// handleRollback switches current back to the version in the optional JSON
+// body {"version": "..."}, or to the previous version, and answers with the
+// new status.
+func (s *Server) handleRollback(w http.ResponseWriter, r *http.Request) {
+	app, ok := s.knownApp(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Version string `json:"version"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "", fmt.Sprintf("decode request: %v", err))
+			return
+		}
+	}
+	manager, ok := s.manager(w, app)
+	if !ok {
+		return
+	}
+	release, holder, ok := s.acquire(app, "rollback")
+	if !ok {
+		writeBusy(w, app, holder)
+		return
+	}
+	err := manager.Rollback(app, body.Version)
+	release()
+	s.events.publish(doneEvent(app, "rollback", err))
+	if err != nil {
+		writeUpdaterError(w, err)
+		return
+	}
+	st, err := s.status(app)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, updater.ErrCodeFilesystem, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+// handleLog answers with the events matching the query: app, since (RFC3339),
+// stage, event (a name or pattern), errors_only, tx and limit, which keeps
+// only the newest events.
+func (s *Server) handleLog(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := updater.EventFilter{
+		App:   q.Get("app"),
+		TxID:  q.Get("tx"),
+		Stage: q.Get("stage"),
+		Event: q.Get("event"),
+	}
+	if filter.App != "" && !manifest.IsAppName(filter.App) {
+		writeError(w, http.StatusBadRequest, "", fmt.Sprintf("invalid app name: %s", filter.App))
+		return
+	}
+	filter.ErrorsOnly, _ = strconv.ParseBool(q.Get("errors_only"))
+	if raw := q.Get("since"); raw != "" {
+		since, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "", fmt.Sprintf("invalid since %q: want an RFC3339 time", raw))
+			return
+		}
+		filter.Since = since
+	}
+	limit := 0
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "", fmt.Sprintf("invalid limit %q", raw))
+			return
+		}
+		limit = n
+	}
+	events, err := (&updater.EventReader{Root: s.Root, Filter: filter}).Read()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, updater.ErrCodeFilesystem, err.Error())
+		return
+	}
+	if limit > 0 && len(events) > limit {
+		events = events[len(events)-limit:]
+	}
+	if events == nil {
+		events = []updater.StageEvent{}
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+// manager builds an updater whose callbacks feed the event stream.
+func (s *Server) manager(w http.ResponseWriter, app string) (*updater.Manager, bool) {
+	manager, err := s.NewManager()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "", err.Error())
+		return nil, false
+	}
+	s.observe(manager, app)
+	return manager, true
+}
+
+// observe points the callbacks of manager at the event stream.
+func (s *Server) observe(manager *updater.Manager, app string) {
+	manager.OnMessage = func(level updater.MessageLevel, msg string) {
+		name := "info"
+		if level == updater.MessageLevelDebug {
+			name = "debug"
+		}
+		s.events.publish(event{Type: "message", App: app, Data: map[string]any{"app": app, "level": name, "message": msg}})
+	}
+	manager.OnProgress = func(p updater.DownloadProgress) {
+		s.events.publish(event{Type: "progress", App: app, Data: map[string]any{
+			"app":        app,
+			"url":        p.URL,
+			"downloaded": p.Downloaded,
+			"total":      p.Total,
+			"done":       p.Done,
+		}})
+	}
+	manager.OnEvent = func(e updater.StageEvent) {
+		s.events.publish(event{Type: "stage", App: app, Data: e})
+	}
+}
+
+// knownApp validates the {app} path value and answers 404 for apps that
+// have neither a manifest nor an install.
+func (s *Server) knownApp(w http.ResponseWriter, r *http.Request) (string, bool) {
+	app := r.PathValue("app")
+	if !manifest.IsAppName(app) {
+		writeError(w, http.StatusBadRequest, "", fmt.Sprintf("invalid app name: %s", app))
+		return "", false
+	}
+	for _, path := range []string{
+		filepath.Join(s.Root, "manifests", app+".json"),
+		filepath.Join(s.Root, "apps", app),
+	} {
+		if _, err := os.Stat(path); err == nil {
+			return app, true
+		}
+	}
+	writeError(w, http.StatusNotFound, "", fmt.Sprintf("unknown app: %s", app))
+	return "", false
+}
+
+func doneEvent(app, op string, err error) event {
+	data := map[string]any{"app": app, "operation": op, "ok": err == nil}
+	if err != nil {
+		data["message"] = err.Error()
+		if ue, ok := updater.AsError(err); ok {
+			data["code"] = ue.Code
+			data["retryable"] = ue.Retryable
+			if ue.Reason != "" {
+				data["reason"] = ue.Reason
+			}
+		}
+	}
+	return event{Type: "done", App: app, Data: data}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	body := map[string]any{"error": msg}
+	if code != "" {
+		body["code"] = code
+	}
+	writeJSON(w, status, body)
+}
+
+func writeBusy(w http.ResponseWriter, app, holder string) {
+	writeError(w, http.StatusConflict, updater.ErrCodeLockBusy, fmt.Sprintf("%s is busy: %s in progress", app, holder))
+}
+
+// writeUpdaterError answers with the code of an updater.Error; a busy lock
+// held by another process is a conflict like a busy app here.
+func writeUpdaterError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var ue *updater.Error
+	if errors.As(err, &ue) {
+		switch ue.Code {
+		case updater.ErrCodeLockBusy, updater.ErrCodeSwitchRollback:
+			status = http.StatusConflict
+		case updater.ErrCodeManifestInvalid:
+			status = http.StatusUnprocessableEntity
+		}
+		writeError(w, status, ue.Code, err.Error())
+		return
+	}
+	writeError(w, status, "", err.Error())
+}
@@ -0,0 +1,253 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"appstract/internal/updater"
+)
+
+const testToken = "secret"
+
+func newTestServer(t *testing.T) (*Server, *httptest.Server, string) {
+	t.Helper()
+	root := t.TempDir()
+	for _, dir := range []string{"manifests", filepath.Join("apps", "demo", "1.0"), filepath.Join("apps", "demo", "current")} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatalf("create %s: %v", dir, err)
+		}
+	}
+	manifestJSON := `{"version":"2.0","architecture":{"64bit":{"url":"https://example.invalid/demo.zip","hash":"` + strings.Repeat("a", 64) + `"}},"bin":"demo.exe"}`
+	if err := os.WriteFile(filepath.Join(root, "manifests", "demo.json"), []byte(manifestJSON), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "apps", "demo", "current", "demo.exe"), []byte("bin"), 0o644); err != nil {
+		t.Fatalf("write bin: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "apps", "demo", "runtime.json"), []byte(`{"current_version":"1.0"}`), 0o644); err != nil {
+		t.Fatalf("write state: %v", err)
+	}
+	s := New(root, testToken)
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(func() {
+		s.Close()
+		ts.Close()
+		s.Wait()
+	})
+	return s, ts, root
+}
+
+func do(t *testing.T, ts *httptest.Server, method, path, body string) (*http.Response, map[string]any) {
+	t.Helper()
+	req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	var decoded map[string]any
+	_ = json.NewDecoder(resp.Body).Decode(&decoded)
+	return resp, decoded
+}
+
+func TestServerRequiresToken(t *testing.T) {
+	_, ts, _ := newTestServer(t)
+	for _, header := range []string{"", "Bearer wrong", testToken} {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/apps", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("authorization %q: expected 401, got %d", header, resp.StatusCode)
+		}
+	}
+}
+
+func TestServerListAndStatus(t *testing.T) {
+	_, ts, _ := newTestServer(t)
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/apps", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	var list []map[string]any
+	json.NewDecoder(resp.Body).Decode(&list)
+	resp.Body.Close()
+	if len(list) != 1 || list[0]["app"] != "demo" || list[0]["current_version"] != "1.0" || list[0]["manifest_version"] != "2.0" {
+		t.Fatalf("unexpected list: %v", list)
+	}
+
+	resp, status := do(t, ts, http.MethodGet, "/v1/apps/demo", "")
+	if resp.StatusCode != http.StatusOK || status["installed"] != true || status["locked"] != false {
+		t.Fatalf("unexpected status %d: %v", resp.StatusCode, status)
+	}
+	if resp, _ := do(t, ts, http.MethodGet, "/v1/apps/missing", ""); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown app, got %d", resp.StatusCode)
+	}
+}
+
+func TestServerUpdateStreamsProgressAndLocksApp(t *testing.T) {
+	s, ts, _ := newTestServer(t)
+	release := make(chan struct{})
+	s.update = func(m *updater.Manager, app, manifestPath string) error {
+		m.OnMessage(updater.MessageLevelDefault, "update start: app="+app)
+		m.OnProgress(updater.DownloadProgress{AppName: app, URL: "https://example.invalid/demo.zip", Downloaded: 5, Total: 10})
+		<-release
+		return &updater.Error{Code: updater.ErrCodePkgVerify, Stage: "verify", Err: os.ErrInvalid}
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/events?app=demo&token="+testToken, nil)
+	stream, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("open event stream: %v", err)
+	}
+	defer stream.Body.Close()
+	lines := bufio.NewScanner(stream.Body)
+	if !lines.Scan() || lines.Text() != ": connected" {
+		t.Fatalf("expected the connected comment, got %q", lines.Text())
+	}
+
+	if resp, body := do(t, ts, http.MethodPost, "/v1/apps/demo/update", ""); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d %v", resp.StatusCode, body)
+	}
+	resp, body := do(t, ts, http.MethodPost, "/v1/apps/demo/update", "")
+	if resp.StatusCode != http.StatusConflict || body["code"] != updater.ErrCodeLockBusy {
+		t.Fatalf("expected a busy app to answer 409, got %d %v", resp.StatusCode, body)
+	}
+	if resp, _ := do(t, ts, http.MethodPost, "/v1/apps/demo/run", ""); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected run during update to answer 409, got %d", resp.StatusCode)
+	}
+	close(release)
+
+	var got []string
+	deadline := time.AfterFunc(5*time.Second, func() { stream.Body.Close() })
+	defer deadline.Stop()
+	var done map[string]any
+	for lines.Scan() {
+		line := lines.Text()
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			got = append(got, name)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok && got[len(got)-1] == "done" {
+			json.Unmarshal([]byte(data), &done)
+			break
+		}
+	}
+	if strings.Join(got, ",") != "message,progress,done" {
+		t.Fatalf("unexpected event sequence: %v", got)
+	}
+	if done["ok"] != false || done["code"] != updater.ErrCodePkgVerify || done["operation"] != "update" {
+		t.Fatalf("unexpected done event: %v", done)
+	}
+}
+
+func TestServerRunAndRollback(t *testing.T) {
+	s, ts, root := newTestServer(t)
+	var launched string
+	s.Launch = func(path string) error {
+		launched = path
+		return nil
+	}
+	if resp, body := do(t, ts, http.MethodPost, "/v1/apps/demo/run", ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("run failed: %d %v", resp.StatusCode, body)
+	}
+	if launched != filepath.Join(root, "apps", "demo", "current", "demo.exe") {
+		t.Fatalf("unexpected launch path: %q", launched)
+	}
+
+	// 1.0 is the only installed version, so there is nothing to roll back to.
+	resp, body := do(t, ts, http.MethodPost, "/v1/apps/demo/rollback", `{"version":"0.9"}`)
+	if resp.StatusCode != http.StatusConflict || body["code"] != updater.ErrCodeSwitchRollback {
+		t.Fatalf("expected rollback to a missing version to fail, got %d %v", resp.StatusCode, body)
+	}
+	if resp, _ := do(t, ts, http.MethodPost, "/v1/apps/demo/rollback", `{`); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected a malformed body to answer 400, got %d", resp.StatusCode)
+	}
+	resp, body = do(t, ts, http.MethodPost, "/v1/apps/demo/rollback", `{"version":"../other/1.0"}`)
+	if resp.StatusCode != http.StatusUnprocessableEntity || body["code"] != updater.ErrCodeManifestInvalid {
+		t.Fatalf("expected a version outside the app to be rejected, got %d %v", resp.StatusCode, body)
+	}
+}
+
+func TestServerLog(t *testing.T) {
+	_, ts, root := newTestServer(t)
+	logDir := filepath.Join(root, "apps", "demo", "logs")
+	os.MkdirAll(logDir, 0o755)
+	var b strings.Builder
+	for _, e := range []updater.StageEvent{
+		{Timestamp: "2026-10-18T10:00:00Z", App: "demo", Stage: "update", Event: "UPDATE_BEGIN"},
+		{Timestamp: "2026-10-18T10:00:02Z", App: "demo", Stage: "verify", Event: "PKG_VERIFY_FAILED", ErrorCode: updater.ErrCodePkgVerify},
+	} {
+		line, _ := json.Marshal(e)
+		b.Write(append(line, '\n'))
+	}
+	os.WriteFile(filepath.Join(logDir, "events-20261018.log"), []byte(b.String()), 0o644)
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/log?app=demo&errors_only=true", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("log failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var events []updater.StageEvent
+	json.NewDecoder(resp.Body).Decode(&events)
+	if len(events) != 1 || events[0].Event != "PKG_VERIFY_FAILED" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	if resp, _ := do(t, ts, http.MethodGet, "/v1/log?app=..%2Fdemo", ""); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected an invalid app to answer 400, got %d", resp.StatusCode)
+	}
+	if resp, _ := do(t, ts, http.MethodGet, "/v1/log?since=yesterday", ""); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected an invalid since to answer 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestServerScheduledUpdateDefersSwitchAndSharesLock(t *testing.T) {
+	s, _, root := newTestServer(t)
+	manifestPath := filepath.Join(root, "manifests", "demo.json")
+	var got *updater.Manager
+	s.update = func(m *updater.Manager, app, manifestPath string) error {
+		got = m
+		return nil
+	}
+	if err := s.ScheduledUpdate("demo", manifestPath, true); err != nil {
+		t.Fatalf("scheduled update failed: %v", err)
+	}
+	if got == nil || !got.DeferSwitchIfRunning || !got.UseCheckver {
+		t.Fatalf("expected a deferring checkver update, got %+v", got)
+	}
+
+	release, _, _ := s.acquire("demo", "rollback")
+	defer release()
+	err := s.ScheduledUpdate("demo", manifestPath, false)
+	if ue, ok := updater.AsError(err); !ok || ue.Code != updater.ErrCodeLockBusy {
+		t.Fatalf("expected LOCK_BUSY while the app is busy, got %v", err)
+	}
+}
+
+func TestDoneEventCarriesReason(t *testing.T) {
+	err := &updater.Error{Code: updater.ErrCodePkgExtract, Stage: "extract", Reason: updater.ErrReasonExtractBytes, Err: os.ErrInvalid}
+	data := doneEvent("demo", "update", err).Data.(map[string]any)
+	if data["code"] != updater.ErrCodePkgExtract || data["reason"] != updater.ErrReasonExtractBytes {
+		t.Fatalf("unexpected done event: %v", data)
+	}
+	data = doneEvent("demo", "update", &updater.Error{Code: updater.ErrCodeLockBusy}).Data.(map[string]any)
+	if _, ok := data["reason"]; ok {
+		t.Fatalf("expected no reason, got %v", data)
+	}
+}
@@ -0,0 +1,587 @@
+package updater
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ulikunitz/xz"
+
+	"appstract/internal/manifest"
+)
+
+type archiveFormat int
+
+const (
+	archiveFormatUnknown archiveFormat = iota
+	archiveFormatZip
+	archiveFormatTar
+	archiveFormatGzip
+	archiveFormatBzip2
+	archiveFormatXz
+)
+
+func (f archiveFormat) String() string {
+	switch f {
+	case archiveFormatZip:
+		return "zip"
+	case archiveFormatTar:
+		return "tar"
+	case archiveFormatGzip:
+		return "tar.gz"
+	case archiveFormatBzip2:
+		return "tar.bz2"
+	case archiveFormatXz:
+		return "tar.xz"
+	default:
+		return "unknown"
+	}
+}
+
+// ExtractLimits bounds what a single archive may expand to. Zero disables the
+// corresponding check.
+type ExtractLimits struct {
+	MaxBytes int64
+	MaxFiles int
+	MaxRatio int
+}
+
+func DefaultExtractLimits() ExtractLimits {
+	return ExtractLimits{
+		MaxBytes: 8 << 30,
+		MaxFiles: 200000,
+		MaxRatio: 200,
+	}
+}
+
+// ratioCheckFloor keeps tiny archives of highly compressible files (empty
+// configs, zero-filled placeholders) from tripping the ratio limit.
+const ratioCheckFloor = 16 << 20
+
+type extractRejectedError struct {
+	Reason string
+	Detail string
+}
+
+func (e *extractRejectedError) Error() string {
+	return e.Reason + ": " + e.Detail
+}
+
+func rejectExtract(reason, format string, args ...any) error {
+	return &extractRejectedError{Reason: reason, Detail: fmt.Sprintf(format, args...)}
+}
+
+// extractBudget accounts for bytes and entries written during one extraction
+// so limits are enforced on actual output, not on archive headers.
+type extractBudget struct {
+	limits      ExtractLimits
+	archiveSize int64
+	bytes       int64
+	files       int
+}
+
+func newExtractBudget(archivePath string, limits ExtractLimits) *extractBudget {
+	b := &extractBudget{limits: limits}
+	if info, err := os.Stat(archivePath); err == nil {
+		b.archiveSize = info.Size()
+	}
+	return b
+}
+
+func (b *extractBudget) addFile() error {
+	b.files++
+	if b.limits.MaxFiles > 0 && b.files > b.limits.MaxFiles {
+		return rejectExtract(ErrReasonExtractFiles, "archive has more than %d entries", b.limits.MaxFiles)
+	}
+	return nil
+}
+
+func (b *extractBudget) addBytes(n int64) error {
+	b.bytes += n
+	if b.limits.MaxBytes > 0 && b.bytes > b.limits.MaxBytes {
+		return rejectExtract(ErrReasonExtractBytes, "archive expands beyond %d bytes", b.limits.MaxBytes)
+	}
+	if b.limits.MaxRatio > 0 && b.archiveSize > 0 && b.bytes > ratioCheckFloor && b.bytes/b.archiveSize > int64(b.limits.MaxRatio) {
+		return rejectExtract(ErrReasonExtractRatio, "archive compression ratio exceeds %d:1", b.limits.MaxRatio)
+	}
+	return nil
+}
+
+type budgetWriter struct {
+	w      io.Writer
+	budget *extractBudget
+}
+
+func (bw budgetWriter) Write(p []byte) (int, error) {
+	if err := bw.budget.addBytes(int64(len(p))); err != nil {
+		return 0, err
+	}
+	return bw.w.Write(p)
+}
+
+var unzipPackage = unzip
+var untarPackage = untar
+
+var (
+	magicZip   = []byte("PK\x03\x04")
+	magicGzip  = []byte{0x1f, 0x8b}
+	magicBzip2 = []byte("BZh")
+	magicXz    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	magicTar   = []byte("ustar")
+)
+
+const tarMagicOffset = 257
+
+// sniffArchiveFormat detects the container format from magic bytes. Unreadable
+// or unrecognized files report archiveFormatUnknown so callers can fall back.
+func sniffArchiveFormat(path string) archiveFormat {
+	f, err := os.Open(path)
+	if err != nil {
+		return archiveFormatUnknown
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	return detectArchiveFormat(head[:n])
+}
+
+func detectArchiveFormat(head []byte) archiveFormat {
+	switch {
+	case bytes.HasPrefix(head, magicZip):
+		return archiveFormatZip
+	case bytes.HasPrefix(head, magicGzip):
+		return archiveFormatGzip
+	case bytes.HasPrefix(head, magicBzip2):
+		return archiveFormatBzip2
+	case bytes.HasPrefix(head, magicXz):
+		return archiveFormatXz
+	case len(head) >= tarMagicOffset+len(magicTar) && bytes.Equal(head[tarMagicOffset:tarMagicOffset+len(magicTar)], magicTar):
+		return archiveFormatTar
+	default:
+		return archiveFormatUnknown
+	}
+}
+
+func extractArtifact(artifact manifest.Artifact, archivePath, dst string, limits ExtractLimits) error {
+	if artifact.Type == manifest.ArtifactTypeRaw {
+		return placeRawArtifact(archivePath, dst)
+	}
+	return extractPackage(archivePath, dst, limits)
+}
+
+func placeRawArtifact(src, dst string) error {
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return fmt.Errorf("create extract root: %w", err)
+	}
+	target := filepath.Join(dst, filepath.Base(src))
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("open raw artifact: %w", err)
+	}
+	defer in.Close()
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o755)
+	if err != nil {
+		return fmt.Errorf("create raw artifact: %w", err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("copy raw artifact: %w", err)
+	}
+	return out.Close()
+}
+
+func unzip(src, dst string, limits ExtractLimits) error {
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		return fmt.Errorf("open zip: %w", err)
+	}
+	defer r.Close()
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return fmt.Errorf("create extract root: %w", err)
+	}
+
+	budget := newExtractBudget(src, limits)
+	var dirTimes []entryTime
+	for _, f := range r.File {
+		if err := budget.addFile(); err != nil {
+			return err
+		}
+		cleanTarget, err := safeExtractPath(dst, f.Name)
+		if err != nil {
+			return fmt.Errorf("invalid zip path: %s", f.Name)
+		}
+		mode := f.Mode()
+		switch {
+		case f.FileInfo().IsDir():
+			if _, err := ensureDirInsideRoot(dst, cleanTarget); err != nil {
+				return err
+			}
+			dirTimes = append(dirTimes, entryTime{path: cleanTarget, modTime: f.Modified})
+		case mode&os.ModeSymlink != 0:
+			linkTarget, err := readZipLinkTarget(f)
+			if err != nil {
+				return err
+			}
+			if err := createSafeSymlink(dst, cleanTarget, linkTarget); err != nil {
+				return fmt.Errorf("invalid zip symlink %s: %w", f.Name, err)
+			}
+		default:
+			rc, err := f.Open()
+			if err != nil {
+				return fmt.Errorf("open zip entry: %w", err)
+			}
+			err = writeExtractedFile(dst, cleanTarget, rc, mode.Perm(), budget)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+			setEntryTime(cleanTarget, f.Modified)
+		}
+	}
+	applyDirTimes(dirTimes)
+	return nil
+}
+
+func readZipLinkTarget(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", fmt.Errorf("open zip entry: %w", err)
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(io.LimitReader(rc, 4096))
+	if err != nil {
+		return "", fmt.Errorf("read zip symlink: %w", err)
+	}
+	return string(b), nil
+}
+
+func untar(src, dst string, format archiveFormat, limits ExtractLimits) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("open tar: %w", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	switch format {
+	case archiveFormatGzip:
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("open gzip stream: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	case archiveFormatBzip2:
+		r = bzip2.NewReader(f)
+	case archiveFormatXz:
+		xr, err := xz.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("open xz stream: %w", err)
+		}
+		r = xr
+	case archiveFormatTar:
+	default:
+		return fmt.Errorf("unsupported tar format: %s", format)
+	}
+
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return fmt.Errorf("create extract root: %w", err)
+	}
+	budget := newExtractBudget(src, limits)
+	var dirTimes []entryTime
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read tar entry: %w", err)
+		}
+		if err := budget.addFile(); err != nil {
+			return err
+		}
+		targetPath, err := safeExtractPath(dst, hdr.Name)
+		if err != nil {
+			return fmt.Errorf("invalid tar path: %s", hdr.Name)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if _, err := ensureDirInsideRoot(dst, targetPath); err != nil {
+				return err
+			}
+			dirTimes = append(dirTimes, entryTime{path: targetPath, modTime: hdr.ModTime})
+		case tar.TypeReg:
+			if err := writeExtractedFile(dst, targetPath, tr, os.FileMode(hdr.Mode).Perm(), budget); err != nil {
+				return err
+			}
+			setEntryTime(targetPath, hdr.ModTime)
+		case tar.TypeSymlink:
+			if err := createSafeSymlink(dst, targetPath, hdr.Linkname); err != nil {
+				return fmt.Errorf("invalid tar symlink %s: %w", hdr.Name, err)
+			}
+		case tar.TypeLink:
+			linkSource, err := safeExtractPath(dst, hdr.Linkname)
+			if err != nil {
+				return fmt.Errorf("invalid tar hardlink: %s -> %s", hdr.Name, hdr.Linkname)
+			}
+			if err := copyExtractedFile(dst, linkSource, targetPath, budget); err != nil {
+				return err
+			}
+			setEntryTime(targetPath, hdr.ModTime)
+		default:
+			// Device nodes, FIFOs and other special entries are never needed
+			// by portable apps and are skipped.
+		}
+	}
+	applyDirTimes(dirTimes)
+	return nil
+}
+
+// checkExtractedLimits applies ExtractLimits after the fact to trees produced
+// by external extractors (7-Zip) that cannot be metered while writing.
+func checkExtractedLimits(archivePath, dst string, limits ExtractLimits) error {
+	if _, err := os.Stat(dst); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	budget := newExtractBudget(archivePath, limits)
+	return filepath.WalkDir(dst, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == dst {
+			return nil
+		}
+		if err := budget.addFile(); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return budget.addBytes(info.Size())
+	})
+}
+
+// safeExtractPath joins an archive entry name onto dst and rejects names that
+// would resolve outside of it (zip-slip).
+func safeExtractPath(dst, name string) (string, error) {
+	cleanDst := filepath.Clean(dst) + string(os.PathSeparator)
+	cleanTarget := filepath.Clean(filepath.Join(dst, name))
+	if !strings.HasPrefix(cleanTarget, cleanDst) {
+		return "", fmt.Errorf("entry escapes extract root: %s", name)
+	}
+	return cleanTarget, nil
+}
+
+// ensureParentInsideRoot creates the parent directory of target and returns
+// it resolved, once it is known to lie under dst.
+func ensureParentInsideRoot(dst, target string) (string, error) {
+	return ensureDirInsideRoot(dst, filepath.Dir(target))
+}
+
+// ensureDirInsideRoot creates dir after checking that, with the symlinks
+// extracted so far resolved, it lies under dst, and returns it resolved.
+// Lexical checks alone can be bypassed by chaining link entries, and the
+// check has to come first: MkdirAll would follow such links out of dst.
+func ensureDirInsideRoot(dst, dir string) (string, error) {
+	realRoot, err := filepath.EvalSymlinks(dst)
+	if err != nil {
+		return "", fmt.Errorf("resolve extract root: %w", err)
+	}
+	resolved, err := resolveExisting(dir)
+	if err != nil {
+		return "", fmt.Errorf("resolve dir: %w", err)
+	}
+	if !pathWithin(realRoot, resolved) {
+		return "", rejectExtract(ErrReasonExtractLink, "entry %s resolves outside extract root", dir)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create dir: %w", err)
+	}
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", fmt.Errorf("resolve dir: %w", err)
+	}
+	if !pathWithin(realRoot, realDir) {
+		return "", rejectExtract(ErrReasonExtractLink, "entry %s resolves outside extract root", dir)
+	}
+	return realDir, nil
+}
+
+// resolveExisting resolves the symlinks of the deepest existing ancestor of
+// p and appends the components that do not exist yet.
+func resolveExisting(p string) (string, error) {
+	var missing []string
+	for {
+		if _, err := os.Lstat(p); err == nil {
+			resolved, err := filepath.EvalSymlinks(p)
+			if err != nil {
+				return "", err
+			}
+			for i := len(missing) - 1; i >= 0; i-- {
+				resolved = filepath.Join(resolved, missing[i])
+			}
+			return resolved, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return "", fmt.Errorf("no existing ancestor of %s", p)
+		}
+		missing = append(missing, filepath.Base(p))
+		p = parent
+	}
+}
+
+// resolveLinkTarget follows linkTarget from the resolved dir one component
+// at a time, the way the OS will: a component that is already a symlink is
+// resolved before a following ".." applies, which filepath.Join would not
+// do. Every step has to stay under realRoot.
+func resolveLinkTarget(realRoot, dir, linkTarget string) (string, error) {
+	cur := dir
+	for _, part := range strings.Split(filepath.ToSlash(linkTarget), "/") {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			cur = filepath.Dir(cur)
+		default:
+			next := filepath.Join(cur, part)
+			if info, err := os.Lstat(next); err == nil && info.Mode()&os.ModeSymlink != 0 {
+				if next, err = filepath.EvalSymlinks(next); err != nil {
+					return "", fmt.Errorf("resolve link target: %w", err)
+				}
+			}
+			cur = next
+		}
+		if !pathWithin(realRoot, cur) {
+			return "", rejectExtract(ErrReasonExtractLink, "link target escapes extract root: %s", linkTarget)
+		}
+	}
+	return cur, nil
+}
+
+func pathWithin(root, p string) bool {
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator)) && !filepath.IsAbs(rel)
+}
+
+func createSafeSymlink(dst, linkPath, linkTarget string) error {
+	if linkTarget == "" || filepath.IsAbs(linkTarget) || filepath.VolumeName(linkTarget) != "" {
+		return rejectExtract(ErrReasonExtractLink, "link target must be relative: %q", linkTarget)
+	}
+	realParent, err := ensureParentInsideRoot(dst, linkPath)
+	if err != nil {
+		return err
+	}
+	realRoot, err := filepath.EvalSymlinks(dst)
+	if err != nil {
+		return fmt.Errorf("resolve extract root: %w", err)
+	}
+	if _, err := resolveLinkTarget(realRoot, realParent, linkTarget); err != nil {
+		return err
+	}
+	if err := removeExistingEntry(linkPath); err != nil {
+		return err
+	}
+	if err := os.Symlink(linkTarget, linkPath); err != nil {
+		return fmt.Errorf("create symlink: %w", err)
+	}
+	return nil
+}
+
+func removeExistingEntry(target string) error {
+	info, err := os.Lstat(target)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("stat extract target: %w", err)
+	}
+	if info.IsDir() {
+		return nil
+	}
+	if err := os.Remove(target); err != nil {
+		return fmt.Errorf("replace extract target: %w", err)
+	}
+	return nil
+}
+
+func writeExtractedFile(dst, targetPath string, r io.Reader, mode os.FileMode, budget *extractBudget) error {
+	if _, err := ensureParentInsideRoot(dst, targetPath); err != nil {
+		return err
+	}
+	if err := removeExistingEntry(targetPath); err != nil {
+		return err
+	}
+	if mode == 0 {
+		mode = 0o644
+	}
+	out, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return fmt.Errorf("create extracted file: %w", err)
+	}
+	if _, err := io.Copy(budgetWriter{w: out, budget: budget}, r); err != nil {
+		out.Close()
+		var rejected *extractRejectedError
+		if errors.As(err, &rejected) {
+			return err
+		}
+		return fmt.Errorf("extract file: %w", err)
+	}
+	return out.Close()
+}
+
+func copyExtractedFile(dst, sourcePath, targetPath string, budget *extractBudget) error {
+	if _, err := ensureParentInsideRoot(dst, sourcePath); err != nil {
+		return err
+	}
+	in, err := os.Open(sourcePath)
+	if err != nil {
+		return fmt.Errorf("open link source: %w", err)
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return fmt.Errorf("stat link source: %w", err)
+	}
+	return writeExtractedFile(dst, targetPath, in, info.Mode().Perm(), budget)
+}
+
+type entryTime struct {
+	path    string
+	modTime time.Time
+}
+
+func setEntryTime(path string, modTime time.Time) {
+	if modTime.IsZero() {
+		return
+	}
+	_ = os.Chtimes(path, modTime, modTime)
+}
+
+// applyDirTimes runs after all entries are written, deepest first, because
+// creating children bumps the parent directory's mtime.
+func applyDirTimes(dirs []entryTime) {
+	for i := len(dirs) - 1; i >= 0; i-- {
+		setEntryTime(dirs[i].path, dirs[i].modTime)
+	}
+}
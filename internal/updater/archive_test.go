@@ -0,0 +1,346 @@
+package updater
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ulikunitz/xz"
+
+	"appstract/internal/manifest"
+)
+
+func TestDetectArchiveFormat(t *testing.T) {
+	tarData := buildTar(t, []tarEntry{{name: "a.txt", body: "a"}})
+	cases := []struct {
+		name string
+		head []byte
+		want archiveFormat
+	}{
+		{name: "zip", head: buildZip(t, map[string]string{"a.txt": "a"}), want: archiveFormatZip},
+		{name: "tar", head: tarData, want: archiveFormatTar},
+		{name: "gzip", head: []byte{0x1f, 0x8b, 0x08}, want: archiveFormatGzip},
+		{name: "bzip2", head: []byte("BZh91AY"), want: archiveFormatBzip2},
+		{name: "xz", head: []byte{0xfd, '7', 'z', 'X', 'Z', 0x00, 0x00}, want: archiveFormatXz},
+		{name: "exe", head: []byte("MZ\x90\x00"), want: archiveFormatUnknown},
+		{name: "empty", head: nil, want: archiveFormatUnknown},
+	}
+	for _, tc := range cases {
+		if got := detectArchiveFormat(tc.head); got != tc.want {
+			t.Fatalf("%s: detectArchiveFormat=%s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestExtractPackageTarGzIgnoresFileName(t *testing.T) {
+	dir := t.TempDir()
+	tarData := buildTar(t, []tarEntry{
+		{name: "app-1.0/", dir: true},
+		{name: "app-1.0/app.exe", body: "binary"},
+	})
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	if _, err := zw.Write(tarData); err != nil {
+		t.Fatalf("write gzip: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close gzip: %v", err)
+	}
+	archivePath := filepath.Join(dir, "download")
+	if err := os.WriteFile(archivePath, gz.Bytes(), 0o644); err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+
+	dst := filepath.Join(dir, "out")
+	if err := extractPackage(archivePath, dst, DefaultExtractLimits()); err != nil {
+		t.Fatalf("extractPackage failed: %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(dst, "app-1.0", "app.exe"))
+	if err != nil || string(b) != "binary" {
+		t.Fatalf("unexpected extracted content: %q (%v)", b, err)
+	}
+}
+
+func TestExtractPackageTarXz(t *testing.T) {
+	dir := t.TempDir()
+	tarData := buildTar(t, []tarEntry{{name: "tool/tool.exe", body: "xz-binary"}})
+	var buf bytes.Buffer
+	xw, err := xz.NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("create xz writer: %v", err)
+	}
+	if _, err := xw.Write(tarData); err != nil {
+		t.Fatalf("write xz: %v", err)
+	}
+	if err := xw.Close(); err != nil {
+		t.Fatalf("close xz: %v", err)
+	}
+	archivePath := filepath.Join(dir, "tool.tar.xz")
+	if err := os.WriteFile(archivePath, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+
+	dst := filepath.Join(dir, "out")
+	if err := extractPackage(archivePath, dst, DefaultExtractLimits()); err != nil {
+		t.Fatalf("extractPackage failed: %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(dst, "tool", "tool.exe"))
+	if err != nil || string(b) != "xz-binary" {
+		t.Fatalf("unexpected extracted content: %q (%v)", b, err)
+	}
+}
+
+func TestUntarRejectsPathTraversal(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "evil.tar")
+	tarData := buildTar(t, []tarEntry{{name: "../escape.txt", body: "x"}})
+	if err := os.WriteFile(archivePath, tarData, 0o644); err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+	err := untar(archivePath, filepath.Join(dir, "out"), archiveFormatTar, DefaultExtractLimits())
+	if err == nil || !strings.Contains(err.Error(), "invalid tar path") {
+		t.Fatalf("expected invalid tar path error, got: %v", err)
+	}
+	if _, statErr := os.Stat(filepath.Join(dir, "escape.txt")); statErr == nil {
+		t.Fatal("expected traversal entry not to be written")
+	}
+}
+
+func TestUntarRejectsEscapingSymlink(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "evil.tar")
+	tarData := buildTar(t, []tarEntry{{name: "link", link: "../../etc/passwd"}})
+	if err := os.WriteFile(archivePath, tarData, 0o644); err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+	err := untar(archivePath, filepath.Join(dir, "out"), archiveFormatTar, DefaultExtractLimits())
+	if err == nil || !strings.Contains(err.Error(), "invalid tar symlink") {
+		t.Fatalf("expected invalid tar symlink error, got: %v", err)
+	}
+}
+
+func TestUntarRejectsChainedSymlinkEscape(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "evil.tar")
+	// q -> . is harmless on its own, but s -> q/.. resolves q first and so
+	// points at the parent of out, where s/evil/ would then be created.
+	tarData := buildTar(t, []tarEntry{
+		{name: "q", link: "."},
+		{name: "s", link: "q/.."},
+		{name: "s/evil/", dir: true},
+		{name: "s/evil/payload.txt", body: "x"},
+	})
+	if err := os.WriteFile(archivePath, tarData, 0o644); err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+	err := untar(archivePath, filepath.Join(dir, "out"), archiveFormatTar, DefaultExtractLimits())
+	if err == nil || !strings.Contains(err.Error(), ErrReasonExtractLink) {
+		t.Fatalf("expected %s, got: %v", ErrReasonExtractLink, err)
+	}
+	if _, statErr := os.Lstat(filepath.Join(dir, "evil")); statErr == nil {
+		t.Fatal("expected nothing created outside the extract root")
+	}
+
+	// A directory entry below a link that leaves the root is refused before
+	// anything is created.
+	out := filepath.Join(dir, "out2")
+	os.MkdirAll(out, 0o755)
+	if err := os.Symlink("..", filepath.Join(out, "up")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	if _, err := ensureDirInsideRoot(out, filepath.Join(out, "up", "evil2")); err == nil {
+		t.Fatal("expected a dir below an escaping link to be rejected")
+	}
+	if _, statErr := os.Lstat(filepath.Join(dir, "evil2")); statErr == nil {
+		t.Fatal("expected the rejected dir not to be created")
+	}
+}
+
+func TestExtractArtifactRawCopiesFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "tool.exe")
+	if err := os.WriteFile(src, []byte("MZ-raw"), 0o644); err != nil {
+		t.Fatalf("write raw artifact: %v", err)
+	}
+	oldUnzip := unzipPackage
+	unzipPackage = func(src, dst string, limits ExtractLimits) error {
+		t.Fatal("raw artifact must not be unzipped")
+		return nil
+	}
+	t.Cleanup(func() { unzipPackage = oldUnzip })
+
+	dst := filepath.Join(dir, "extracted")
+	artifact := manifest.Artifact{Type: manifest.ArtifactTypeRaw}
+	if err := extractArtifact(artifact, src, dst, DefaultExtractLimits()); err != nil {
+		t.Fatalf("extractArtifact failed: %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(dst, "tool.exe"))
+	if err != nil || string(b) != "MZ-raw" {
+		t.Fatalf("unexpected raw artifact content: %q (%v)", b, err)
+	}
+}
+
+func TestUnzipPreservesModTimeAndSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	modTime := time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)
+	archivePath := filepath.Join(dir, "app.zip")
+	writeZipEntries(t, archivePath, []zipEntry{
+		{name: "app/", dir: true, modTime: modTime},
+		{name: "app/app.exe", body: "binary", modTime: modTime},
+		{name: "app/latest", link: "app.exe", modTime: modTime},
+	})
+
+	dst := filepath.Join(dir, "out")
+	if err := unzip(archivePath, dst, DefaultExtractLimits()); err != nil {
+		t.Fatalf("unzip failed: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(dst, "app", "app.exe"))
+	if err != nil {
+		t.Fatalf("stat extracted file: %v", err)
+	}
+	if !info.ModTime().Equal(modTime) {
+		t.Fatalf("expected mtime %s, got %s", modTime, info.ModTime().UTC())
+	}
+	target, err := os.Readlink(filepath.Join(dst, "app", "latest"))
+	if err != nil || target != "app.exe" {
+		t.Fatalf("expected symlink to app.exe, got %q (%v)", target, err)
+	}
+}
+
+func TestUnzipRejectsEscapingSymlink(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "evil.zip")
+	writeZipEntries(t, archivePath, []zipEntry{
+		{name: "self", link: "."},
+		{name: "self/up", link: ".."},
+	})
+
+	err := unzip(archivePath, filepath.Join(dir, "out"), DefaultExtractLimits())
+	var rejected *extractRejectedError
+	if !errors.As(err, &rejected) || rejected.Reason != ErrReasonExtractLink {
+		t.Fatalf("expected %s rejection, got: %v", ErrReasonExtractLink, err)
+	}
+}
+
+func TestUnzipEnforcesLimits(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "bomb.zip")
+	writeZipEntries(t, archivePath, []zipEntry{
+		{name: "a.bin", body: strings.Repeat("0", 64<<10)},
+		{name: "b.bin", body: "b"},
+		{name: "c.bin", body: "c"},
+	})
+
+	cases := []struct {
+		name   string
+		limits ExtractLimits
+		reason string
+	}{
+		{name: "files", limits: ExtractLimits{MaxFiles: 2}, reason: ErrReasonExtractFiles},
+		{name: "bytes", limits: ExtractLimits{MaxBytes: 1024}, reason: ErrReasonExtractBytes},
+	}
+	for _, tc := range cases {
+		err := unzip(archivePath, filepath.Join(dir, tc.name), tc.limits)
+		var rejected *extractRejectedError
+		if !errors.As(err, &rejected) || rejected.Reason != tc.reason {
+			t.Fatalf("%s: expected %s rejection, got: %v", tc.name, tc.reason, err)
+		}
+	}
+}
+
+func TestExtractBudgetRatio(t *testing.T) {
+	budget := &extractBudget{limits: ExtractLimits{MaxRatio: 100}, archiveSize: 1 << 10}
+	if err := budget.addBytes(64 << 10); err != nil {
+		t.Fatalf("expected small output below ratio floor to pass, got: %v", err)
+	}
+	err := budget.addBytes(ratioCheckFloor)
+	var rejected *extractRejectedError
+	if !errors.As(err, &rejected) || rejected.Reason != ErrReasonExtractRatio {
+		t.Fatalf("expected %s rejection, got: %v", ErrReasonExtractRatio, err)
+	}
+}
+
+type zipEntry struct {
+	name    string
+	body    string
+	dir     bool
+	link    string
+	modTime time.Time
+}
+
+func writeZipEntries(t *testing.T, path string, entries []zipEntry) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name, Method: zip.Deflate, Modified: e.modTime}
+		body := e.body
+		switch {
+		case e.dir:
+			hdr.SetMode(os.ModeDir | 0o755)
+		case e.link != "":
+			hdr.SetMode(os.ModeSymlink | 0o777)
+			body = e.link
+		default:
+			hdr.SetMode(0o644)
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatalf("create zip entry: %v", err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatalf("write zip entry: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("write zip: %v", err)
+	}
+}
+
+type tarEntry struct {
+	name string
+	body string
+	dir  bool
+	link string
+}
+
+func buildTar(t *testing.T, entries []tarEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0o644, Size: int64(len(e.body)), Typeflag: tar.TypeReg}
+		switch {
+		case e.dir:
+			hdr.Typeflag = tar.TypeDir
+			hdr.Mode = 0o755
+			hdr.Size = 0
+		case e.link != "":
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Linkname = e.link
+			hdr.Size = 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("write tar header: %v", err)
+		}
+		if hdr.Size > 0 {
+			if _, err := tw.Write([]byte(e.body)); err != nil {
+				t.Fatalf("write tar body: %v", err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+	return buf.Bytes()
+}
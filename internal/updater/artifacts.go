@@ -0,0 +1,129 @@
+package updater
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"appstract/internal/manifest"
+)
+
+// stagedArchivePaths assigns each artifact item a download path in staging.
+// The primary item keeps the historical location; add-ons get their own
+// sub-directories so identical file names cannot collide.
+func stagedArchivePaths(staging string, items []manifest.ArtifactItem) []string {
+	paths := make([]string, len(items))
+	for i, item := range items {
+		name := archiveFileNameFromURL(item.URL)
+		if i == 0 {
+			paths[i] = filepath.Join(staging, name)
+			continue
+		}
+		paths[i] = filepath.Join(staging, "parts", strconv.Itoa(i), name)
+	}
+	return paths
+}
+
+// downloadItems fetches every item, concurrently when there is more than one,
+// and returns the first failure in item order.
+func (m *Manager) downloadItems(appName string, items []manifest.ArtifactItem, paths []string) error {
+	errs := make([]error, len(items))
+	var wg sync.WaitGroup
+	for i, item := range items {
+		m.report(MessageLevelDefault, "downloading package: %s", filepath.Base(paths[i]))
+		_ = m.logEvent(appName, "download", "PKG_DOWNLOAD_BEGIN", "", item.URL)
+		if len(items) == 1 {
+			errs[i] = m.download(appName, item.URL, paths[i])
+			break
+		}
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			errs[i] = m.download(appName, url, paths[i])
+		}(i, item.URL)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			if len(items) > 1 {
+				return fmt.Errorf("download %s: %w", items[i].URL, err)
+			}
+			return err
+		}
+		m.report(MessageLevelDefault, "[ok] download complete: %s", filepath.Base(paths[i]))
+		_ = m.logEvent(appName, "download", "PKG_DOWNLOAD_DONE", "", paths[i])
+	}
+	return nil
+}
+
+// mergeArtifactItems lays the primary item and every add-on out in a single
+// directory, honouring each item's extract_dir and extract_to, and returns
+// that directory as the new version source.
+func (m *Manager) mergeArtifactItems(appName string, artifact manifest.Artifact, archivePaths []string, primarySource, staging string) (string, error) {
+	merged := filepath.Join(staging, "merged")
+	if err := os.RemoveAll(merged); err != nil {
+		return "", fmt.Errorf("cleanup merge dir: %w", err)
+	}
+	items := artifact.Items()
+	for i, item := range items {
+		source := primarySource
+		if i > 0 {
+			extracted := filepath.Join(filepath.Dir(archivePaths[i]), "extracted")
+			if err := extractArtifact(artifact, archivePaths[i], extracted, m.ExtractLimits); err != nil {
+				return "", fmt.Errorf("extract %s: %w", filepath.Base(archivePaths[i]), err)
+			}
+			source = extracted
+			if item.ExtractDir != "" {
+				source = filepath.Join(extracted, item.ExtractDir)
+			}
+		}
+		target := merged
+		if item.ExtractTo != "" {
+			var err error
+			target, err = safeExtractPath(merged, item.ExtractTo)
+			if err != nil {
+				return "", fmt.Errorf("invalid extract_to %q: %w", item.ExtractTo, err)
+			}
+		}
+		if err := mergeTree(source, target); err != nil {
+			return "", fmt.Errorf("merge %s: %w", filepath.Base(archivePaths[i]), err)
+		}
+		_ = m.logEvent(appName, "extract", "PKG_EXTRACT_MERGED", "", fmt.Sprintf("item=%d target=%s", i, target))
+	}
+	return merged, nil
+}
+
+// mergeTree moves the contents of src into dst, creating directories as
+// needed. Files already present in dst are replaced so later items win.
+func mergeTree(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return fmt.Errorf("source extract directory missing: %w", err)
+	}
+	if !info.IsDir() {
+		if err := os.MkdirAll(dst, 0o755); err != nil {
+			return err
+		}
+		return os.Rename(src, filepath.Join(dst, filepath.Base(src)))
+	}
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		if err := removeExistingEntry(target); err != nil {
+			return err
+		}
+		return os.Rename(p, target)
+	})
+}
@@ -0,0 +1,118 @@
+package updater
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"appstract/internal/manifest"
+)
+
+func TestUpdate_MergesMultipleArtifactItems(t *testing.T) {
+	root := t.TempDir()
+	appName := "editor"
+
+	mainZip := buildZip(t, map[string]string{
+		"editor-4.0/editor.exe":      "binary",
+		"editor-4.0/lang/en-US.json": "en",
+	})
+	langZip := buildZip(t, map[string]string{
+		"pack/de-DE.json": "de",
+	})
+	pluginZip := buildZip(t, map[string]string{
+		"plugin.dll": "plugin",
+	})
+	var requests int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		switch r.URL.Path {
+		case "/editor.zip":
+			_, _ = w.Write(mainZip)
+		case "/lang/pack.zip":
+			_, _ = w.Write(langZip)
+		case "/plugin/pack.zip":
+			_, _ = w.Write(pluginZip)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	man := &manifest.Manifest{
+		Version: "4.0",
+		Architecture: manifest.Architecture{
+			X64: manifest.Artifact{}.WithItems([]manifest.ArtifactItem{
+				{URL: server.URL + "/editor.zip", Hash: sha256Hex(mainZip), ExtractDir: "editor-4.0"},
+				{URL: server.URL + "/lang/pack.zip", Hash: sha256Hex(langZip), ExtractDir: "pack", ExtractTo: "lang"},
+				{URL: server.URL + "/plugin/pack.zip", Hash: sha256Hex(pluginZip), ExtractTo: "plugins/extra"},
+			}),
+		},
+		Bin: "editor.exe",
+	}
+
+	mgr := NewManager(root)
+	mgr.Client = server.Client()
+	mgr.PromptSwitch = true
+	mgr.confirm = func(appName, version string) (bool, error) { return false, nil }
+	if err := mgr.Update(appName, man); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 3 {
+		t.Fatalf("expected 3 downloads, got %d", got)
+	}
+
+	versionDir := filepath.Join(root, "apps", appName, "4.0")
+	for rel, want := range map[string]string{
+		"editor.exe":               "binary",
+		"lang/en-US.json":          "en",
+		"lang/de-DE.json":          "de",
+		"plugins/extra/plugin.dll": "plugin",
+	} {
+		b, err := os.ReadFile(filepath.Join(versionDir, filepath.FromSlash(rel)))
+		if err != nil || string(b) != want {
+			t.Fatalf("%s: expected %q, got %q (%v)", rel, want, b, err)
+		}
+	}
+}
+
+func TestUpdate_MultipleArtifactItemsVerifyEachHash(t *testing.T) {
+	root := t.TempDir()
+	mainZip := buildZip(t, map[string]string{"app.exe": "binary"})
+	addonZip := buildZip(t, map[string]string{"addon.txt": "addon"})
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/addon.zip" {
+			_, _ = w.Write(addonZip)
+			return
+		}
+		_, _ = w.Write(mainZip)
+	}))
+	defer server.Close()
+
+	man := &manifest.Manifest{
+		Version: "1.0",
+		Architecture: manifest.Architecture{
+			X64: manifest.Artifact{}.WithItems([]manifest.ArtifactItem{
+				{URL: server.URL + "/app.zip", Hash: sha256Hex(mainZip)},
+				{URL: server.URL + "/addon.zip", Hash: strings.Repeat("0", 64)},
+			}),
+		},
+		Bin: "app.exe",
+	}
+	mgr := NewManager(root)
+	mgr.Client = server.Client()
+	err := mgr.Update("app", man)
+	if err == nil || !strings.Contains(err.Error(), "sha256 mismatch") {
+		t.Fatalf("expected add-on hash mismatch, got: %v", err)
+	}
+	state, err := loadState(filepath.Join(root, "apps", "app", "runtime.json"))
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	if state.LastErrorCode != ErrCodePkgVerify {
+		t.Fatalf("expected %s, got %s", ErrCodePkgVerify, state.LastErrorCode)
+	}
+}
@@ -0,0 +1,99 @@
+package updater
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"appstract/internal/manifest"
+)
+
+// AutoupdateResult is what a manifest should say after moving to the latest
+// release: the new version and every rendered architecture with its hashes
+// filled in.
+type AutoupdateResult struct {
+	PreviousVersion string
+	Version         string
+	Architecture    manifest.Architecture
+}
+
+func (r AutoupdateResult) Changed() bool {
+	return r.Version != r.PreviousVersion
+}
+
+// ResolveAutoupdate discovers the latest version, renders the autoupdate
+// templates and downloads every artifact once to compute its sha256. Nothing
+// is installed; the downloads go to a temporary directory that is removed
+// afterwards. An unchanged version returns early without downloading.
+func (m *Manager) ResolveAutoupdate(man *manifest.Manifest) (AutoupdateResult, error) {
+	result := AutoupdateResult{PreviousVersion: man.Version, Version: man.Version}
+	if man.Checkver.GitHub == "" || man.Checkver.Regex == "" || man.Checkver.Replace == "" {
+		return result, newError(ErrCodeManifestInvalid, "checkver", false, "manifest has no checkver github/regex/replace to discover versions")
+	}
+	version, captures, err := m.DiscoverLatest(man)
+	if err != nil {
+		return result, err
+	}
+	result.Version = version
+	if !result.Changed() {
+		return result, nil
+	}
+	rendered, err := renderAutoupdateArchitecture(man, captures)
+	if err != nil {
+		return result, &Error{Code: ErrCodeCheckverResolve, Stage: "checkver", Version: version, Err: fmt.Errorf("checkver found newer version %s but %w", version, err)}
+	}
+
+	scratch, err := os.MkdirTemp("", "appstract-autoupdate-")
+	if err != nil {
+		return result, newError(ErrCodeFilesystem, "download", false, "create autoupdate scratch dir: %w", err)
+	}
+	defer os.RemoveAll(scratch)
+
+	hashes := map[string]string{}
+	for _, arch := range manifest.Architectures {
+		artifact := rendered.Get(arch)
+		if artifact.IsZero() {
+			continue
+		}
+		items := artifact.Items()
+		for i := range items {
+			url := items[i].URL
+			if hash, ok := hashes[url]; ok {
+				items[i].Hash = hash
+				continue
+			}
+			dst := filepath.Join(scratch, strconv.Itoa(len(hashes)), archiveFileNameFromURL(url))
+			m.report(MessageLevelDefault, "downloading %s artifact: %s", arch, filepath.Base(dst))
+			if err := m.download("", url, dst); err != nil {
+				return result, withTarget(wrapError(ErrCodePkgDownload, "download", fmt.Errorf("download %s: %w", url, err)), "", version)
+			}
+			hash, err := fileSHA256(dst)
+			if err != nil {
+				return result, wrapError(ErrCodeFilesystem, "verify", err)
+			}
+			m.report(MessageLevelDebug, "sha256 %s = %s", url, hash)
+			hashes[url] = hash
+			items[i].Hash = hash
+		}
+		rendered.Set(arch, artifact.WithItems(items))
+	}
+	result.Architecture = rendered
+	return result, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open file for hash: %w", err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hash file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,74 @@
+package updater
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"appstract/internal/manifest"
+)
+
+func TestResolveAutoupdateHashesRenderedArtifacts(t *testing.T) {
+	payload := []byte("tool 2.0.0 payload")
+	var downloads int32
+	files := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&downloads, 1)
+		if r.URL.Path != "/tool-2.0.0.zip" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(payload)
+	}))
+	defer files.Close()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"tag_name":"v2.0.0","assets":[{"browser_download_url":"%s/tool-2.0.0.zip"}]}`, files.URL)
+	}))
+	defer api.Close()
+
+	man := &manifest.Manifest{
+		Version: "1.0.0",
+		Checkver: manifest.Checkver{
+			GitHub:  "https://github.com/owner/tool",
+			Regex:   `tool-(?P<version>[\d.]+)\.zip`,
+			Replace: "${version}",
+		},
+		Autoupdate: manifest.Autoupdate{
+			Architecture: manifest.Architecture{
+				X64: manifest.Artifact{URL: files.URL + "/tool-$version.zip", ExtractDir: "tool-$version"},
+				// Shares the download with 64bit; it must be fetched once.
+				X86: manifest.Artifact{URL: files.URL + "/tool-$version.zip"},
+			},
+		},
+	}
+	mgr := NewManager(t.TempDir())
+	mgr.Client = files.Client()
+	mgr.GitHubAPIBase = api.URL
+
+	result, err := mgr.ResolveAutoupdate(man)
+	if err != nil {
+		t.Fatalf("ResolveAutoupdate failed: %v", err)
+	}
+	if !result.Changed() || result.Version != "2.0.0" || result.PreviousVersion != "1.0.0" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if result.Architecture.X64.Hash != sha256Hex(payload) || result.Architecture.X86.Hash != sha256Hex(payload) {
+		t.Fatalf("expected computed hashes, got %+v", result.Architecture)
+	}
+	if result.Architecture.X64.ExtractDir != "tool-2.0.0" {
+		t.Fatalf("expected rendered extract_dir, got %q", result.Architecture.X64.ExtractDir)
+	}
+	if got := atomic.LoadInt32(&downloads); got != 1 {
+		t.Fatalf("expected a single download, got %d", got)
+	}
+}
+
+func TestResolveAutoupdateRequiresCheckver(t *testing.T) {
+	mgr := NewManager(t.TempDir())
+	_, err := mgr.ResolveAutoupdate(&manifest.Manifest{Version: "1.0"})
+	if err == nil || !strings.Contains(err.Error(), "checkver") {
+		t.Fatalf("expected checkver error, got: %v", err)
+	}
+}
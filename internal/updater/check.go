@@ -0,0 +1,89 @@
+package updater
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"appstract/internal/manifest"
+)
+
+// CheckResult is the version an update of an app would install, next to
+// the version it has.
+type CheckResult struct {
+	App            string `json:"app"`
+	CurrentVersion string `json:"current_version,omitempty"`
+	LatestVersion  string `json:"latest_version"`
+	// URL is the artifact the update would download for this host.
+	URL      string `json:"url,omitempty"`
+	Outdated bool   `json:"outdated"`
+}
+
+// Check finds the version an update would install — the newest checkver
+// release, or else the manifest version — without downloading or switching
+// anything. Unlike Update it reports a newer release even when its hash is
+// not known yet. An installed app found up to date gets LastCheckAt, so
+// throttled updates skip it; an outdated one does not, so they still run.
+func (m *Manager) Check(appName string, man *manifest.Manifest) (CheckResult, error) {
+	result := CheckResult{App: appName, LatestVersion: man.Version}
+	state, err := ReadState(m.Root, appName)
+	if err != nil {
+		return result, withTarget(wrapError(ErrCodeFilesystem, "state", err), appName, man.Version)
+	}
+	result.CurrentVersion = state.CurrentVersion
+
+	latest := *man
+	version, captures, err := m.DiscoverLatest(man)
+	if err != nil {
+		return result, withTarget(err, appName, man.Version)
+	}
+	if version != "" && version != man.Version {
+		rendered, err := renderAutoupdateArchitecture(man, captures)
+		if err != nil {
+			err = &Error{Code: ErrCodeCheckverResolve, Stage: "checkver", Err: fmt.Errorf("checkver found newer version %s but %w", version, err)}
+			return result, withTarget(err, appName, version)
+		}
+		latest.Version = version
+		latest.Architecture = rendered
+	}
+	result.LatestVersion = latest.Version
+	result.URL = artifactURL(&latest, m.HostArch(), m.Architecture)
+	result.Outdated = result.CurrentVersion != "" && result.CurrentVersion != result.LatestVersion
+	if !result.Outdated {
+		m.stampCheck(appName)
+	}
+	return result, nil
+}
+
+// artifactURL is the first artifact URL among the candidate architectures,
+// falling back to autoupdate as ResolveArtifact does.
+func artifactURL(man *manifest.Manifest, goarch, override string) string {
+	for _, arch := range manifest.CandidateArchitectures(goarch, override) {
+		if artifact := man.Architecture.Get(arch); artifact.URL != "" {
+			return artifact.URL
+		}
+		if artifact := man.Autoupdate.Architecture.Get(arch); artifact.URL != "" {
+			return artifact.URL
+		}
+	}
+	return ""
+}
+
+// stampCheck sets LastCheckAt of an installed app. An app that is being
+// updated is left alone; the update records its own check.
+func (m *Manager) stampCheck(appName string) {
+	lockPath := filepath.Join(m.Root, "apps", appName, ".lock")
+	if acquireLock(lockPath) != nil {
+		return
+	}
+	defer releaseLock(lockPath)
+	statePath := filepath.Join(m.Root, "apps", appName, "runtime.json")
+	state, err := loadState(statePath)
+	if err != nil || state.CurrentVersion == "" {
+		return
+	}
+	state.LastCheckAt = m.now().UTC().Format(time.RFC3339)
+	if err := saveState(statePath, state); err != nil {
+		m.report(MessageLevelDebug, "record check of %s failed: %v", appName, err)
+	}
+}
@@ -0,0 +1,75 @@
+package updater
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"appstract/internal/manifest"
+)
+
+func TestCheck(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"tag_name":"release-1.37.0","assets":[{"name":"aria2-1.37.0-win-64bit-build1.zip","browser_download_url":"https://github.com/aria2/aria2/releases/download/release-1.37.0/aria2-1.37.0-win-64bit-build1.zip"}]}`)
+	}))
+	defer api.Close()
+	root := t.TempDir()
+	appDir := filepath.Join(root, "apps", "aria2")
+	if err := os.MkdirAll(appDir, 0o755); err != nil {
+		t.Fatalf("create app dir: %v", err)
+	}
+	if err := saveState(filepath.Join(appDir, "runtime.json"), RuntimeState{CurrentVersion: "1.36.0-1"}); err != nil {
+		t.Fatalf("write state: %v", err)
+	}
+	mgr := NewManager(root)
+	mgr.GitHubAPIBase = api.URL
+	mgr.GOARCH = "amd64"
+	checkedAt := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	mgr.Now = func() time.Time { return checkedAt }
+	man := &manifest.Manifest{
+		Version: "1.36.0-1",
+		Architecture: manifest.Architecture{
+			X64: manifest.Artifact{URL: "https://example.com/aria2-1.36.0.zip", Hash: "abc"},
+		},
+		Checkver: manifest.Checkver{
+			GitHub:  "https://github.com/aria2/aria2",
+			Regex:   "aria2-(?<version>[\\d.]+)-win-64bit-build(?<build>[\\d]+)\\.zip",
+			Replace: "${version}-${build}",
+		},
+		Autoupdate: manifest.Autoupdate{
+			Architecture: manifest.Architecture{
+				X64: manifest.Artifact{URL: "https://example.com/aria2-$matchVersion.zip"},
+			},
+		},
+	}
+
+	// The newer release has no known hash yet; Check still reports it.
+	result, err := mgr.Check("aria2", man)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if !result.Outdated || result.LatestVersion != "1.37.0-1" || result.URL != "https://example.com/aria2-1.37.0.zip" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	state, err := ReadState(root, "aria2")
+	if err != nil || state.LastCheckAt != "" {
+		t.Fatalf("expected an outdated app left due for update, got %+v %v", state, err)
+	}
+	if entries, _ := os.ReadDir(appDir); len(entries) != 1 {
+		t.Fatalf("expected nothing but runtime.json in the app dir, got %d entries", len(entries))
+	}
+
+	// Without checkver the manifest version is what an update installs.
+	man.Checkver = manifest.Checkver{}
+	result, err = mgr.Check("aria2", man)
+	if err != nil || result.Outdated || result.URL != "https://example.com/aria2-1.36.0.zip" {
+		t.Fatalf("expected aria2 up to date with the manifest, got %+v %v", result, err)
+	}
+	if state, err := ReadState(root, "aria2"); err != nil || state.LastCheckAt != "2026-10-18T09:00:00Z" {
+		t.Fatalf("expected the check of an up-to-date app recorded, got %+v %v", state, err)
+	}
+}
@@ -0,0 +1,24 @@
+package updater
+
+import (
+	"appstract/internal/appenv"
+)
+
+// syncEnvironment regenerates the published environment after current or
+// the installed versions changed. The switch already happened, so a failure
+// is logged and reported but does not fail the update.
+func (m *Manager) syncEnvironment(appName string) {
+	if len(m.EnvBackends) == 0 {
+		return
+	}
+	env, err := appenv.Regenerate(m.Root, m.EnvBackends...)
+	if err != nil {
+		m.report(MessageLevelDefault, "environment update failed: %v", err)
+		_ = m.logEvent(appName, "env", "ENV_SYNC_FAILED", ErrCodeEnvSync, err.Error())
+		return
+	}
+	for _, conflict := range env.Conflicts {
+		m.report(MessageLevelDefault, "environment conflict: %s", conflict)
+	}
+	_ = m.logEvent(appName, "env", "ENV_SYNC_DONE", "", "environment files regenerated")
+}
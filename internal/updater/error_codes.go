@@ -1,6 +1,11 @@
 package updater
 
 const (
+	ErrCodeManifestInvalid = "MANIFEST_INVALID"
+	ErrCodeCheckverResolve = "CHECKVER_RESOLVE"
+	ErrCodeLockBusy        = "LOCK_BUSY"
+	ErrCodeFilesystem      = "FS_IO"
+
 	ErrCodeNetCheckverRequest = "NET_CHECKVER_REQUEST"
 	ErrCodeNetCheckverHTTP    = "NET_CHECKVER_HTTP"
 	ErrCodeNetDownload        = "NET_DOWNLOAD"
@@ -8,6 +13,14 @@ const (
 	ErrCodePkgDownload = "PKG_DOWNLOAD"
 	ErrCodePkgVerify   = "PKG_VERIFY"
 	ErrCodePkgExtract  = "PKG_EXTRACT"
+	ErrCodePkgInstall  = "PKG_INSTALL"
+
+	// Sub-reasons carried by ErrCodePkgExtract failures when an archive is
+	// rejected rather than merely unreadable.
+	ErrReasonExtractBytes = "EXTRACT_LIMIT_BYTES"
+	ErrReasonExtractFiles = "EXTRACT_LIMIT_FILES"
+	ErrReasonExtractRatio = "EXTRACT_LIMIT_RATIO"
+	ErrReasonExtractLink  = "EXTRACT_UNSAFE_LINK"
 
 	ErrCodeScriptPreInstall = "SCRIPT_PREINSTALL"
 
@@ -16,4 +29,6 @@ const (
 	ErrCodeSwitchCurrent     = "SWITCH_CURRENT"
 	ErrCodeSwitchHealthcheck = "SWITCH_HEALTHCHECK"
 	ErrCodeSwitchRollback    = "SWITCH_ROLLBACK"
+
+	ErrCodeEnvSync = "ENV_SYNC"
 )
@@ -0,0 +1,111 @@
+package updater
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error is the error type returned by Manager operations. Code is one of the
+// ErrCode* constants and stays stable across releases, Stage names the part
+// of the update transaction that failed (the same names the event log uses)
+// and Retryable tells callers whether trying again later can succeed without
+// changing the manifest or the machine. Reason, when set, is one of the
+// ErrReason* constants and tells apart failures that share a code.
+type Error struct {
+	Code      string
+	Stage     string
+	Reason    string
+	App       string
+	Version   string
+	Retryable bool
+	Err       error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Code
+	}
+	return e.Code + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// AsError returns the outermost *Error in err's chain.
+func AsError(err error) (*Error, bool) {
+	var ue *Error
+	if errors.As(err, &ue) {
+		return ue, true
+	}
+	return nil, false
+}
+
+func newError(code, stage string, retryable bool, format string, args ...any) *Error {
+	return &Error{Code: code, Stage: stage, Retryable: retryable, Err: fmt.Errorf(format, args...)}
+}
+
+// wrapError attaches a code and stage to err. An *Error that already carries
+// the same code is reused rather than nested; a nested one passes on its
+// retryability, since only the cause knows whether it was transient. A
+// rejected extraction in the chain supplies the reason.
+func wrapError(code, stage string, err error) *Error {
+	if err == nil {
+		return nil
+	}
+	reason := ""
+	var rejected *extractRejectedError
+	if errors.As(err, &rejected) {
+		reason = rejected.Reason
+	}
+	inner, ok := AsError(err)
+	if ok && inner.Code == code {
+		if inner.Stage == "" {
+			inner.Stage = stage
+		}
+		if inner.Reason == "" {
+			inner.Reason = reason
+		}
+		return inner
+	}
+	retryable := retryableCodes[code]
+	if ok {
+		retryable = inner.Retryable
+		if reason == "" {
+			reason = inner.Reason
+		}
+	}
+	return &Error{Code: code, Stage: stage, Reason: reason, Retryable: retryable, Err: err}
+}
+
+// retryableCodes lists the failures that are transient by nature when the
+// cause does not say otherwise.
+var retryableCodes = map[string]bool{
+	ErrCodeNetCheckverRequest: true,
+	ErrCodeNetCheckverHTTP:    true,
+	ErrCodeNetDownload:        true,
+	ErrCodePkgDownload:        true,
+	ErrCodeLockBusy:           true,
+	ErrCodeSwitchProcess:      true,
+}
+
+// retryableHTTPStatus reports whether a failed HTTP status is worth retrying:
+// server errors and rate limiting are, everything else is not.
+func retryableHTTPStatus(status int) bool {
+	return status >= 500 || status == 429 || status == 403
+}
+
+// withTarget fills in the app and version an error belongs to.
+func withTarget(err error, app, version string) error {
+	ue, ok := AsError(err)
+	if !ok {
+		return err
+	}
+	if ue.App == "" {
+		ue.App = app
+	}
+	if ue.Version == "" {
+		ue.Version = version
+	}
+	return err
+}
@@ -0,0 +1,48 @@
+package updater
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestWrapErrorKeepsInnerRetryability(t *testing.T) {
+	inner := newError(ErrCodeNetDownload, "download", false, "download http status: %d", 404)
+	err := wrapError(ErrCodePkgDownload, "download", fmt.Errorf("download x: %w", inner))
+	if err.Code != ErrCodePkgDownload || err.Retryable {
+		t.Fatalf("unexpected wrapped error: %#v", err)
+	}
+	if !errors.Is(err, inner) {
+		t.Fatalf("expected cause to stay in the chain")
+	}
+	if got, want := err.Error(), "PKG_DOWNLOAD: download x: NET_DOWNLOAD: download http status: 404"; got != want {
+		t.Fatalf("unexpected message: %q want %q", got, want)
+	}
+
+	same := wrapError(ErrCodeNetDownload, "download", inner)
+	if same != inner {
+		t.Fatalf("expected an error with the same code to be reused")
+	}
+	if plain := wrapError(ErrCodeLockBusy, "lock", errors.New("busy")); !plain.Retryable {
+		t.Fatalf("expected LOCK_BUSY to default to retryable")
+	}
+}
+
+func TestUpdateReturnsUpdaterErrorForInvalidInput(t *testing.T) {
+	err := NewManager(t.TempDir()).Update("", nil)
+	ue, ok := AsError(err)
+	if !ok || ue.Code != ErrCodeManifestInvalid {
+		t.Fatalf("expected %s, got %v", ErrCodeManifestInvalid, err)
+	}
+}
+
+func TestWrapErrorCarriesExtractReason(t *testing.T) {
+	rejected := rejectExtract(ErrReasonExtractLink, "link target escapes extract root: %s", "../x")
+	err := wrapError(ErrCodePkgExtract, "extract", fmt.Errorf("extract zip: %w", rejected))
+	if err.Reason != ErrReasonExtractLink {
+		t.Fatalf("expected reason %s, got %#v", ErrReasonExtractLink, err)
+	}
+	if plain := wrapError(ErrCodePkgExtract, "extract", errors.New("unexpected EOF")); plain.Reason != "" {
+		t.Fatalf("expected no reason for an unreadable archive, got %q", plain.Reason)
+	}
+}
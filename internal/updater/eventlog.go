@@ -0,0 +1,391 @@
+package updater
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Event logs live in apps/<app>/logs. logEvent appends to one file per UTC
+// day; compaction later folds old daily files into one gzip archive per
+// month (a concatenation of gzip members, which gzip readers handle as one
+// stream).
+const (
+	eventLogPrefix     = "events-"
+	eventLogSuffix     = ".log"
+	eventArchiveSuffix = ".log.gz"
+)
+
+func eventLogName(t time.Time) string {
+	return eventLogPrefix + t.UTC().Format("20060102") + eventLogSuffix
+}
+
+// LogRetention bounds how much event log history each app keeps. Zero
+// disables the corresponding rule.
+type LogRetention struct {
+	MaxAgeDays       int
+	MaxBytes         int64
+	CompactAfterDays int
+}
+
+func DefaultLogRetention() LogRetention {
+	return LogRetention{
+		MaxAgeDays:       30,
+		MaxBytes:         64 << 20,
+		CompactAfterDays: 7,
+	}
+}
+
+type eventLogFile struct {
+	path    string
+	start   time.Time
+	end     time.Time
+	archive bool
+	size    int64
+}
+
+// listEventLogs returns the daily files and monthly archives in logDir,
+// oldest first. Other files (pre_install logs) are ignored.
+func listEventLogs(logDir string) ([]eventLogFile, error) {
+	entries, err := os.ReadDir(logDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var files []eventLogFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, eventLogPrefix) {
+			continue
+		}
+		stamp := strings.TrimPrefix(name, eventLogPrefix)
+		file := eventLogFile{path: filepath.Join(logDir, name)}
+		switch {
+		case strings.HasSuffix(stamp, eventArchiveSuffix):
+			month, err := time.Parse("200601", strings.TrimSuffix(stamp, eventArchiveSuffix))
+			if err != nil {
+				continue
+			}
+			file.start, file.end, file.archive = month, month.AddDate(0, 1, 0), true
+		case strings.HasSuffix(stamp, eventLogSuffix):
+			day, err := time.Parse("20060102", strings.TrimSuffix(stamp, eventLogSuffix))
+			if err != nil {
+				continue
+			}
+			file.start, file.end = day, day.AddDate(0, 0, 1)
+		default:
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			file.size = info.Size()
+		}
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].start.Equal(files[j].start) {
+			return files[i].start.Before(files[j].start)
+		}
+		return files[i].archive && !files[j].archive
+	})
+	return files, nil
+}
+
+// EventFilter selects event log entries. Zero fields match everything. Event
+// accepts shell patterns such as PKG_*.
+type EventFilter struct {
+	App        string
+	TxID       string
+	Since      time.Time
+	Stage      string
+	Event      string
+	ErrorsOnly bool
+}
+
+func (f EventFilter) Match(e StageEvent) bool {
+	if f.App != "" && !strings.EqualFold(f.App, e.App) {
+		return false
+	}
+	if f.TxID != "" && f.TxID != e.TxID {
+		return false
+	}
+	if f.Stage != "" && !strings.EqualFold(f.Stage, e.Stage) {
+		return false
+	}
+	if f.Event != "" {
+		if ok, _ := path.Match(strings.ToUpper(f.Event), strings.ToUpper(e.Event)); !ok {
+			return false
+		}
+	}
+	if f.ErrorsOnly && e.ErrorCode == "" {
+		return false
+	}
+	if !f.Since.IsZero() {
+		if ts, err := time.Parse(time.RFC3339Nano, e.Timestamp); err == nil && ts.Before(f.Since) {
+			return false
+		}
+	}
+	return true
+}
+
+// EventReader reads the event logs of one app (Filter.App) or of all apps
+// under Root. Each Read returns the matching entries written since the
+// previous Read, merged across apps in timestamp order, so calling Read in
+// a loop follows the logs.
+type EventReader struct {
+	Root   string
+	Filter EventFilter
+
+	started bool
+	offsets map[string]int64
+}
+
+func (r *EventReader) Read() ([]StageEvent, error) {
+	if r.offsets == nil {
+		r.offsets = map[string]int64{}
+	}
+	apps, err := r.apps()
+	if err != nil {
+		return nil, err
+	}
+	var events []StageEvent
+	for _, app := range apps {
+		files, err := listEventLogs(filepath.Join(r.Root, "apps", app, "logs"))
+		if err != nil {
+			return nil, fmt.Errorf("list event logs of %s: %w", app, err)
+		}
+		for _, file := range files {
+			var batch []StageEvent
+			if file.archive {
+				if r.started || (!r.Filter.Since.IsZero() && !file.end.After(r.Filter.Since)) {
+					continue
+				}
+				batch, err = readEventArchive(file.path)
+			} else {
+				batch, err = r.readDaily(file)
+			}
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range batch {
+				if r.Filter.Match(e) {
+					events = append(events, e)
+				}
+			}
+		}
+	}
+	r.started = true
+	sortEvents(events)
+	return events, nil
+}
+
+func (r *EventReader) apps() ([]string, error) {
+	if r.Filter.App != "" {
+		return []string{r.Filter.App}, nil
+	}
+	entries, err := os.ReadDir(filepath.Join(r.Root, "apps"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read apps directory: %w", err)
+	}
+	var apps []string
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			apps = append(apps, entry.Name())
+		}
+	}
+	return apps, nil
+}
+
+// readDaily reads the complete lines appended to a daily file since the last
+// Read. A trailing partial line is left for the next Read.
+func (r *EventReader) readDaily(file eventLogFile) ([]StageEvent, error) {
+	offset := r.offsets[file.path]
+	if file.size < offset {
+		offset = 0
+	}
+	if file.size == offset {
+		return nil, nil
+	}
+	f, err := os.Open(file.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("open event log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("read event log: %w", err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("read event log: %w", err)
+	}
+	complete := bytes.LastIndexByte(data, '\n') + 1
+	r.offsets[file.path] = offset + int64(complete)
+	return decodeEvents(bytes.NewReader(data[:complete]))
+}
+
+func readEventArchive(path string) ([]StageEvent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open event archive: %w", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("read event archive %s: %w", filepath.Base(path), err)
+	}
+	defer zr.Close()
+	return decodeEvents(zr)
+}
+
+// decodeEvents parses JSON lines, skipping lines that are not events so one
+// damaged write does not hide the rest of the log.
+func decodeEvents(r io.Reader) ([]StageEvent, error) {
+	var events []StageEvent
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4<<20)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var e StageEvent
+		if err := json.Unmarshal(line, &e); err != nil || e.Event == "" {
+			continue
+		}
+		events = append(events, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read event log: %w", err)
+	}
+	return events, nil
+}
+
+// sortEvents orders events by timestamp, keeping the file order of events
+// written in the same instant.
+func sortEvents(events []StageEvent) {
+	times := make(map[string]time.Time, len(events))
+	for _, e := range events {
+		if _, ok := times[e.Timestamp]; !ok {
+			times[e.Timestamp], _ = time.Parse(time.RFC3339Nano, e.Timestamp)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return times[events[i].Timestamp].Before(times[events[j].Timestamp])
+	})
+}
+
+// pruneEventLogs applies LogRetention to apps/<app>/logs: daily files older
+// than CompactAfterDays are folded into their month's archive, files wholly
+// older than MaxAgeDays are removed, and then the oldest files go until the
+// rest fits in MaxBytes. Today's file is never touched, and neither is the
+// archive of the current month, which compaction keeps appending to.
+func (m *Manager) pruneEventLogs(appName string) error {
+	logDir := filepath.Join(m.Root, "apps", appName, "logs")
+	policy := m.LogRetention
+	today := m.now().UTC().Truncate(24 * time.Hour)
+
+	files, err := listEventLogs(logDir)
+	if err != nil {
+		return err
+	}
+	if policy.CompactAfterDays > 0 {
+		cutoff := today.AddDate(0, 0, -policy.CompactAfterDays)
+		compacted := false
+		for _, file := range files {
+			if file.archive || file.start.After(cutoff) || !file.start.Before(today) {
+				continue
+			}
+			if err := compactEventLog(logDir, file); err != nil {
+				return err
+			}
+			compacted = true
+		}
+		if compacted {
+			if files, err = listEventLogs(logDir); err != nil {
+				return err
+			}
+		}
+	}
+
+	var kept []eventLogFile
+	for _, file := range files {
+		if policy.MaxAgeDays > 0 && !file.end.After(today.AddDate(0, 0, -policy.MaxAgeDays)) {
+			if err := os.Remove(file.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("remove expired event log: %w", err)
+			}
+			continue
+		}
+		kept = append(kept, file)
+	}
+
+	if policy.MaxBytes <= 0 {
+		return nil
+	}
+	var total int64
+	for _, file := range kept {
+		total += file.size
+	}
+	for _, file := range kept {
+		if total <= policy.MaxBytes {
+			break
+		}
+		if !file.start.Before(today) || (file.archive && file.end.After(today)) {
+			continue
+		}
+		if err := os.Remove(file.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove event log over size limit: %w", err)
+		}
+		total -= file.size
+	}
+	return nil
+}
+
+// compactEventLog appends a daily file to its month's archive as a new gzip
+// member and removes it.
+func compactEventLog(logDir string, file eventLogFile) error {
+	src, err := os.Open(file.path)
+	if err != nil {
+		return fmt.Errorf("open event log for compaction: %w", err)
+	}
+	defer src.Close()
+	archivePath := filepath.Join(logDir, eventLogPrefix+file.start.Format("200601")+eventArchiveSuffix)
+	dst, err := os.OpenFile(archivePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open event archive: %w", err)
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		zw.Close()
+		dst.Close()
+		return fmt.Errorf("compact event log: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		return fmt.Errorf("compact event log: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("compact event log: %w", err)
+	}
+	src.Close()
+	if err := os.Remove(file.path); err != nil {
+		return fmt.Errorf("remove compacted event log: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package updater
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeEventLines(t *testing.T, path string, events ...StageEvent) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("create log dir: %v", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("open log: %v", err)
+	}
+	defer f.Close()
+	for _, e := range events {
+		b, _ := json.Marshal(e)
+		f.Write(append(b, '\n'))
+	}
+}
+
+func eventNames(events []StageEvent) string {
+	names := make([]string, 0, len(events))
+	for _, e := range events {
+		names = append(names, e.App+":"+e.Event)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestEventReaderMergesAppsAndFollows(t *testing.T) {
+	root := t.TempDir()
+	aLog := filepath.Join(root, "apps", "a", "logs", "events-20261018.log")
+	bLog := filepath.Join(root, "apps", "b", "logs", "events-20261018.log")
+	writeEventLines(t, aLog,
+		StageEvent{Timestamp: "2026-10-18T10:00:00Z", App: "a", Stage: "update", Event: "UPDATE_BEGIN"},
+		StageEvent{Timestamp: "2026-10-18T10:00:02Z", App: "a", Stage: "download", Event: "PKG_DOWNLOAD_FAILED", ErrorCode: ErrCodePkgDownload},
+	)
+	writeEventLines(t, bLog, StageEvent{Timestamp: "2026-10-18T10:00:01Z", App: "b", Stage: "update", Event: "UPDATE_BEGIN"})
+
+	reader := &EventReader{Root: root}
+	events, err := reader.Read()
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if got := eventNames(events); got != "a:UPDATE_BEGIN,b:UPDATE_BEGIN,a:PKG_DOWNLOAD_FAILED" {
+		t.Fatalf("unexpected merge order: %s", got)
+	}
+
+	// A partial line is held back until it is complete.
+	f, _ := os.OpenFile(bLog, os.O_APPEND|os.O_WRONLY, 0o644)
+	f.WriteString(`{"timestamp":"2026-10-18T10:00:03Z","app":"b","stage":"verify",`)
+	f.Close()
+	if events, err = reader.Read(); err != nil || len(events) != 0 {
+		t.Fatalf("expected nothing new, got %v %v", events, err)
+	}
+	f, _ = os.OpenFile(bLog, os.O_APPEND|os.O_WRONLY, 0o644)
+	f.WriteString(`"event":"PKG_VERIFY_DONE"}` + "\n")
+	f.Close()
+	if events, err = reader.Read(); err != nil || eventNames(events) != "b:PKG_VERIFY_DONE" {
+		t.Fatalf("expected the completed line, got %v %v", events, err)
+	}
+}
+
+func TestEventFilterMatch(t *testing.T) {
+	e := StageEvent{Timestamp: "2026-10-18T10:00:00Z", App: "aria2", Stage: "download", Event: "PKG_DOWNLOAD_FAILED", ErrorCode: ErrCodePkgDownload}
+	cases := []struct {
+		filter EventFilter
+		want   bool
+	}{
+		{EventFilter{}, true},
+		{EventFilter{App: "ARIA2", Stage: "Download", Event: "pkg_*"}, true},
+		{EventFilter{Event: "SWITCH_*"}, false},
+		{EventFilter{ErrorsOnly: true}, true},
+		{EventFilter{Since: time.Date(2026, 10, 18, 10, 0, 1, 0, time.UTC)}, false},
+	}
+	for i, c := range cases {
+		if got := c.filter.Match(e); got != c.want {
+			t.Fatalf("case %d: got %v want %v", i, got, c.want)
+		}
+	}
+}
+
+func TestPruneEventLogsCompactsAndExpires(t *testing.T) {
+	root := t.TempDir()
+	logDir := filepath.Join(root, "apps", "a", "logs")
+	for _, day := range []string{"20260801", "20261001", "20261002", "20261015", "20261018"} {
+		writeEventLines(t, filepath.Join(logDir, "events-"+day+".log"),
+			StageEvent{Timestamp: day[:4] + "-" + day[4:6] + "-" + day[6:] + "T00:00:00Z", App: "a", Stage: "update", Event: "UPDATE_BEGIN"})
+	}
+	if err := os.WriteFile(filepath.Join(logDir, "pre_install-1.0.log"), []byte("x"), 0o644); err != nil {
+		t.Fatalf("write script log: %v", err)
+	}
+
+	mgr := NewManager(root)
+	mgr.Now = func() time.Time { return time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC) }
+	mgr.LogRetention = LogRetention{MaxAgeDays: 30, CompactAfterDays: 7}
+	if err := mgr.pruneEventLogs("a"); err != nil {
+		t.Fatalf("prune failed: %v", err)
+	}
+	entries, _ := os.ReadDir(logDir)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if got := strings.Join(names, ","); got != "events-202610.log.gz,events-20261015.log,events-20261018.log,pre_install-1.0.log" {
+		t.Fatalf("unexpected log files: %s", got)
+	}
+
+	events, err := (&EventReader{Root: root}).Read()
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if len(events) != 4 || events[0].Timestamp != "2026-10-01T00:00:00Z" || events[1].Timestamp != "2026-10-02T00:00:00Z" {
+		t.Fatalf("expected compacted events readable in order, got %+v", events)
+	}
+
+	// The size limit drops the oldest files but keeps today's and the
+	// current month's archive.
+	mgr.LogRetention = LogRetention{MaxBytes: 1}
+	if err := mgr.pruneEventLogs("a"); err != nil {
+		t.Fatalf("prune failed: %v", err)
+	}
+	files, _ := listEventLogs(logDir)
+	if len(files) != 2 || filepath.Base(files[0].path) != "events-202610.log.gz" || filepath.Base(files[1].path) != "events-20261018.log" {
+		t.Fatalf("expected the current archive and today's file, got %+v", files)
+	}
+}
+
+func TestPruneEventLogsDropsOldestClosedMonthsFirst(t *testing.T) {
+	root := t.TempDir()
+	logDir := filepath.Join(root, "apps", "a", "logs")
+	if err := os.MkdirAll(logDir, 0o755); err != nil {
+		t.Fatalf("create log dir: %v", err)
+	}
+	for _, name := range []string{"events-202608.log.gz", "events-202609.log.gz", "events-202610.log.gz", "events-20261018.log"} {
+		if err := os.WriteFile(filepath.Join(logDir, name), make([]byte, 100), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	mgr := NewManager(root)
+	mgr.Now = func() time.Time { return time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC) }
+	mgr.LogRetention = LogRetention{MaxBytes: 350}
+	if err := mgr.pruneEventLogs("a"); err != nil {
+		t.Fatalf("prune failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(logDir, "events-202608.log.gz")); !os.IsNotExist(err) {
+		t.Fatalf("expected the oldest month dropped first: %v", err)
+	}
+
+	// Over the limit even then, closed months go but the current one stays.
+	mgr.LogRetention = LogRetention{MaxBytes: 150}
+	if err := mgr.pruneEventLogs("a"); err != nil {
+		t.Fatalf("prune failed: %v", err)
+	}
+	var names []string
+	files, _ := listEventLogs(logDir)
+	for _, file := range files {
+		names = append(names, filepath.Base(file.path))
+	}
+	if got := strings.Join(names, ","); got != "events-202610.log.gz,events-20261018.log" {
+		t.Fatalf("expected the current archive and today's file kept, got %s", got)
+	}
+}
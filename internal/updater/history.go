@@ -0,0 +1,234 @@
+package updater
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// History outcomes.
+const (
+	OutcomeSuccess  = "success"
+	OutcomeFailed   = "failed"
+	OutcomeDeclined = "declined"
+	// OutcomeDeferred means the version was staged but the app was running,
+	// so the switch was left for later.
+	OutcomeDeferred = "deferred"
+)
+
+// HistoryEntry is one update transaction that got as far as downloading, or
+// one rollback, appended to apps/<app>/history.jsonl when the transaction
+// ends. Checks that find the app already current are not recorded.
+type HistoryEntry struct {
+	TxID            string `json:"tx_id"`
+	App             string `json:"app"`
+	Version         string `json:"version"`
+	PreviousVersion string `json:"previous_version,omitempty"`
+	Outcome         string `json:"outcome"`
+	ErrorCode       string `json:"error_code,omitempty"`
+	StartedAt       string `json:"started_at"`
+	InstalledAt     string `json:"installed_at,omitempty"`
+	SwitchedAt      string `json:"switched_at,omitempty"`
+	DownloadBytes   int64  `json:"download_bytes,omitempty"`
+	DurationMS      int64  `json:"duration_ms"`
+	// StageMS is the time from the first to the last event of each stage.
+	StageMS    map[string]int64 `json:"stage_ms,omitempty"`
+	RolledBack bool             `json:"rolled_back,omitempty"`
+}
+
+func historyPath(root, appName string) string {
+	return filepath.Join(root, "apps", appName, "history.jsonl")
+}
+
+func appendHistory(root string, entry HistoryEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encode history: %w", err)
+	}
+	f, err := os.OpenFile(historyPath(root, entry.App), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open history: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("write history: %w", err)
+	}
+	return nil
+}
+
+// ReadHistory returns the recorded transactions of an app, oldest first. An
+// app without history yields an empty list.
+func ReadHistory(root, appName string) ([]HistoryEntry, error) {
+	b, err := os.ReadFile(historyPath(root, appName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read history: %w", err)
+	}
+	var entries []HistoryEntry
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal(line, &entry); err != nil || entry.TxID == "" {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// HistoryReport aggregates history across apps.
+type HistoryReport struct {
+	Apps         int     `json:"apps"`
+	Transactions int     `json:"transactions"`
+	Succeeded    int     `json:"succeeded"`
+	Failed       int     `json:"failed"`
+	Declined     int     `json:"declined"`
+	Deferred     int     `json:"deferred"`
+	Rollbacks    int     `json:"rollbacks"`
+	FailureRate  float64 `json:"failure_rate"`
+	// Failures lists failure counts per error code, most frequent first.
+	Failures []CodeFailures `json:"failures,omitempty"`
+	// Averages over the transactions that reached the stage.
+	AvgDownloadMS    int64 `json:"avg_download_ms"`
+	AvgExtractMS     int64 `json:"avg_extract_ms"`
+	AvgDownloadBytes int64 `json:"avg_download_bytes"`
+}
+
+type CodeFailures struct {
+	Code  string  `json:"code"`
+	Count int     `json:"count"`
+	Rate  float64 `json:"rate"`
+}
+
+// Report reads the history of every app under root and aggregates it.
+func Report(root string) (HistoryReport, error) {
+	entries, err := os.ReadDir(filepath.Join(root, "apps"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return HistoryReport{}, fmt.Errorf("read apps directory: %w", err)
+	}
+	var all []HistoryEntry
+	apps := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		history, err := ReadHistory(root, entry.Name())
+		if err != nil {
+			return HistoryReport{}, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		if len(history) > 0 {
+			apps++
+			all = append(all, history...)
+		}
+	}
+	report := SummarizeHistory(all)
+	report.Apps = apps
+	return report, nil
+}
+
+// SummarizeHistory computes the report figures for a set of transactions.
+func SummarizeHistory(entries []HistoryEntry) HistoryReport {
+	report := HistoryReport{Transactions: len(entries)}
+	byCode := map[string]int{}
+	var downloadMS, extractMS, downloadBytes int64
+	var downloads, extracts, sized int64
+	for _, entry := range entries {
+		switch entry.Outcome {
+		case OutcomeSuccess:
+			report.Succeeded++
+		case OutcomeDeclined:
+			report.Declined++
+		case OutcomeDeferred:
+			report.Deferred++
+		case OutcomeFailed:
+			report.Failed++
+			code := entry.ErrorCode
+			if code == "" {
+				code = "UNKNOWN"
+			}
+			byCode[code]++
+		}
+		if entry.RolledBack {
+			report.Rollbacks++
+		}
+		if ms, ok := entry.StageMS["download"]; ok {
+			downloadMS += ms
+			downloads++
+		}
+		if ms, ok := entry.StageMS["extract"]; ok {
+			extractMS += ms
+			extracts++
+		}
+		if entry.DownloadBytes > 0 {
+			downloadBytes += entry.DownloadBytes
+			sized++
+		}
+	}
+	if report.Transactions == 0 {
+		return report
+	}
+	total := float64(report.Transactions)
+	report.FailureRate = float64(report.Failed) / total
+	for code, count := range byCode {
+		report.Failures = append(report.Failures, CodeFailures{Code: code, Count: count, Rate: float64(count) / total})
+	}
+	sort.Slice(report.Failures, func(i, j int) bool {
+		if report.Failures[i].Count != report.Failures[j].Count {
+			return report.Failures[i].Count > report.Failures[j].Count
+		}
+		return report.Failures[i].Code < report.Failures[j].Code
+	})
+	if downloads > 0 {
+		report.AvgDownloadMS = downloadMS / downloads
+	}
+	if extracts > 0 {
+		report.AvgExtractMS = extractMS / extracts
+	}
+	if sized > 0 {
+		report.AvgDownloadBytes = downloadBytes / sized
+	}
+	return report
+}
+
+// recordHistory appends the transaction of appName to its history if it got
+// as far as downloading. err is what Update returns.
+func (m *Manager) recordHistory(tx *transaction, appName string, err error) error {
+	if !tx.attempted {
+		return nil
+	}
+	entry := HistoryEntry{
+		TxID:            tx.id,
+		App:             appName,
+		Version:         tx.version,
+		PreviousVersion: tx.previousVersion,
+		Outcome:         OutcomeSuccess,
+		StartedAt:       tx.startedAt.UTC().Format(time.RFC3339),
+		InstalledAt:     tx.installedAt,
+		SwitchedAt:      tx.switchedAt,
+		DownloadBytes:   tx.downloadBytes,
+		DurationMS:      time.Since(tx.started).Milliseconds(),
+		StageMS:         tx.stageMS,
+		RolledBack:      tx.rolledBack,
+	}
+	switch {
+	case err != nil:
+		entry.Outcome = OutcomeFailed
+		if ue, ok := AsError(err); ok {
+			entry.ErrorCode = ue.Code
+		}
+	case tx.declined:
+		entry.Outcome = OutcomeDeclined
+	case tx.deferred:
+		entry.Outcome = OutcomeDeferred
+	}
+	return appendHistory(m.Root, entry)
+}
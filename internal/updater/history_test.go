@@ -0,0 +1,74 @@
+package updater
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"appstract/internal/manifest"
+)
+
+func TestUpdateAppendsHistory(t *testing.T) {
+	root := t.TempDir()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not the expected bytes"))
+	}))
+	defer server.Close()
+	if err := saveState(filepath.Join(root, "apps", "aria2", "runtime.json"), RuntimeState{CurrentVersion: "1.37.0"}); err != nil {
+		t.Fatalf("write state: %v", err)
+	}
+	man := &manifest.Manifest{
+		Version:      "1.37.0",
+		Architecture: manifest.Architecture{X64: manifest.Artifact{URL: server.URL + "/aria2.zip", Hash: sha256Hex([]byte("x"))}},
+		Bin:          "aria2c.exe",
+	}
+	mgr := NewManager(root)
+	mgr.Client = server.Client()
+	mgr.GOARCH = "amd64"
+
+	// Already current: a check, not a transaction worth recording.
+	if err := mgr.Update("aria2", man); err != nil {
+		t.Fatalf("expected no-op update, got %v", err)
+	}
+	man.Version = "1.38.0"
+	if err := mgr.Update("aria2", man); err == nil {
+		t.Fatalf("expected hash mismatch")
+	}
+
+	history, err := ReadHistory(root, "aria2")
+	if err != nil || len(history) != 1 {
+		t.Fatalf("expected one history entry, got %+v (%v)", history, err)
+	}
+	entry := history[0]
+	if entry.TxID == "" || entry.Version != "1.38.0" || entry.PreviousVersion != "1.37.0" ||
+		entry.Outcome != OutcomeFailed || entry.ErrorCode != ErrCodePkgVerify || entry.DownloadBytes != int64(len("not the expected bytes")) {
+		t.Fatalf("unexpected history entry: %+v", entry)
+	}
+	if _, ok := entry.StageMS["download"]; !ok {
+		t.Fatalf("expected download stage time, got %+v", entry.StageMS)
+	}
+	if entry.InstalledAt != "" || entry.SwitchedAt != "" || entry.RolledBack {
+		t.Fatalf("failed download must not record install or switch: %+v", entry)
+	}
+}
+
+func TestSummarizeHistory(t *testing.T) {
+	report := SummarizeHistory([]HistoryEntry{
+		{Outcome: OutcomeSuccess, DownloadBytes: 100, StageMS: map[string]int64{"download": 100, "extract": 40}},
+		{Outcome: OutcomeSuccess, DownloadBytes: 300, StageMS: map[string]int64{"download": 300, "extract": 60}, RolledBack: true},
+		{Outcome: OutcomeFailed, ErrorCode: ErrCodePkgDownload, StageMS: map[string]int64{"download": 200}},
+		{Outcome: OutcomeFailed, ErrorCode: ErrCodePkgDownload},
+		{Outcome: OutcomeFailed, ErrorCode: ErrCodePkgVerify},
+		{Outcome: OutcomeDeclined},
+	})
+	if report.Transactions != 6 || report.Succeeded != 2 || report.Failed != 3 || report.Declined != 1 || report.Rollbacks != 1 {
+		t.Fatalf("unexpected counts: %+v", report)
+	}
+	if report.FailureRate != 0.5 || len(report.Failures) != 2 || report.Failures[0].Code != ErrCodePkgDownload || report.Failures[0].Count != 2 {
+		t.Fatalf("unexpected failures: %+v", report)
+	}
+	if report.AvgDownloadMS != 200 || report.AvgExtractMS != 50 || report.AvgDownloadBytes != 200 {
+		t.Fatalf("unexpected averages: %+v", report)
+	}
+}
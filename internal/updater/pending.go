@@ -0,0 +1,113 @@
+package updater
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ApplyPending switches apps/<app>/current to the staged PendingVersion and
+// returns that version. It never stops processes: while the app is running
+// or another update holds the lock, or when nothing is pending, it returns ""
+// and the version stays staged.
+// bin is the manifest bin, which the staged version must contain.
+func (m *Manager) ApplyPending(appName, bin string) (applied string, err error) {
+	if appName == "" {
+		return "", newError(ErrCodeManifestInvalid, "switch", false, "app name is required")
+	}
+	// Look before taking the lock: with nothing pending, an update or check
+	// holding it is no reason to hold up the launch.
+	statePath := filepath.Join(m.Root, "apps", appName, "runtime.json")
+	state, err := loadState(statePath)
+	if err != nil {
+		return "", withTarget(wrapError(ErrCodeFilesystem, "state", err), appName, "")
+	}
+	if state.PendingVersion == "" {
+		return "", nil
+	}
+	lockPath := filepath.Join(m.Root, "apps", appName, ".lock")
+	if err := acquireLock(lockPath); err != nil {
+		if ue, ok := AsError(err); ok && ue.Code == ErrCodeLockBusy {
+			m.report(MessageLevelDefault, "pending version %s left staged: update in progress", state.PendingVersion)
+			return "", nil
+		}
+		return "", withTarget(err, appName, "")
+	}
+	defer releaseLock(lockPath)
+
+	if state, err = loadState(statePath); err != nil {
+		return "", withTarget(wrapError(ErrCodeFilesystem, "state", err), appName, "")
+	}
+	version := state.PendingVersion
+	if version == "" {
+		return "", nil
+	}
+	fail := func(code, stage string, err error) error {
+		return withTarget(wrapError(code, stage, err), appName, version)
+	}
+	versionDir := filepath.Join(m.Root, "apps", appName, version)
+	if version == state.CurrentVersion || !isDir(versionDir) {
+		// Switched to or cleaned up since it was staged.
+		state.PendingVersion = ""
+		if err := saveState(statePath, state); err != nil {
+			return "", fail(ErrCodeFilesystem, "state", err)
+		}
+		return "", nil
+	}
+	currentPath := filepath.Join(m.Root, "apps", appName, "current")
+	if running, reason := m.appRunning(currentPath); running {
+		m.report(MessageLevelDefault, "pending version %s left staged: %s", version, reason)
+		return "", nil
+	}
+
+	m.beginTx(appName, version, state.CurrentVersion)
+	defer func() { m.finishTx(appName, err) }()
+	m.updateTx(appName, func(tx *transaction) { tx.attempted = true })
+	m.report(MessageLevelDefault, "applying pending version: app=%s version=%s", appName, version)
+	_ = m.logEvent(appName, "switch", "SWITCH_PENDING_BEGIN", "", fmt.Sprintf("from=%s to=%s", state.CurrentVersion, version))
+
+	if bin != "" {
+		if _, err := os.Stat(filepath.Join(versionDir, bin)); err != nil {
+			state.PendingVersion = ""
+			state.LastErrorCode = ErrCodeSwitchHealthcheck
+			state.LastErrorMsg = err.Error()
+			_ = m.logEvent(appName, "healthcheck", "SWITCH_HEALTHCHECK_FAILED", state.LastErrorCode, err.Error())
+			_ = saveState(statePath, state)
+			return "", fail(ErrCodeSwitchHealthcheck, "healthcheck", fmt.Errorf("staged version is missing bin: %w", err))
+		}
+	}
+	prevTarget, _ := resolveCurrentTarget(currentPath)
+	if err := switchCurrent(currentPath, versionDir); err != nil {
+		rollbackErr := rollbackCurrent(currentPath, prevTarget)
+		m.updateTx(appName, func(tx *transaction) { tx.rolledBack = rollbackErr == nil })
+		state.LastErrorCode = ErrCodeSwitchCurrent
+		state.LastErrorMsg = err.Error()
+		_ = m.logEvent(appName, "switch", "SWITCH_CURRENT_FAILED", state.LastErrorCode, err.Error())
+		_ = saveState(statePath, state)
+		return "", fail(ErrCodeSwitchCurrent, "switch", err)
+	}
+	now := m.now().UTC().Format(time.RFC3339)
+	m.updateTx(appName, func(tx *transaction) { tx.switchedAt = now })
+	_ = m.logEvent(appName, "switch", "SWITCH_CURRENT_DONE", "", "current version switched")
+
+	state.CurrentVersion = version
+	state.PendingVersion = ""
+	state.LastUpdateAt = now
+	state.LastErrorCode = ""
+	state.LastErrorMsg = ""
+	if err := saveState(statePath, state); err != nil {
+		return "", fail(ErrCodeFilesystem, "state", err)
+	}
+	cleanupErr := m.cleanupOldVersions(appName, version)
+	m.syncEnvironment(appName)
+	if cleanupErr != nil {
+		return version, fail(ErrCodeFilesystem, "cleanup", cleanupErr)
+	}
+	if err := os.RemoveAll(filepath.Join(m.Root, "apps", appName, "_staging")); err != nil {
+		return version, fail(ErrCodeFilesystem, "cleanup", err)
+	}
+	_ = m.logEvent(appName, "switch", "SWITCH_PENDING_DONE", "", "pending version applied")
+	m.report(MessageLevelDefault, "[ok] pending version applied: app=%s version=%s", appName, version)
+	return version, nil
+}
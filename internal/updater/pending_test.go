@@ -0,0 +1,103 @@
+package updater
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"appstract/internal/manifest"
+)
+
+func TestStageOnlyThenApplyPending(t *testing.T) {
+	root := t.TempDir()
+	zipData := buildZip(t, map[string]string{"aria2c.exe": "binary"})
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(zipData)
+	}))
+	defer server.Close()
+	appDir := filepath.Join(root, "apps", "aria2")
+	if err := os.MkdirAll(filepath.Join(appDir, "1.36.0"), 0o755); err != nil {
+		t.Fatalf("create version dir: %v", err)
+	}
+	if err := switchCurrent(filepath.Join(appDir, "current"), filepath.Join(appDir, "1.36.0")); err != nil {
+		t.Fatalf("switch current: %v", err)
+	}
+	if err := saveState(filepath.Join(appDir, "runtime.json"), RuntimeState{CurrentVersion: "1.36.0"}); err != nil {
+		t.Fatalf("write state: %v", err)
+	}
+	man := &manifest.Manifest{
+		Version:      "1.37.0",
+		Architecture: manifest.Architecture{X64: manifest.Artifact{URL: server.URL + "/aria2.zip", Hash: sha256Hex(zipData)}},
+		Bin:          "aria2c.exe",
+	}
+
+	mgr := NewManager(root)
+	mgr.Client = server.Client()
+	mgr.GOARCH = "amd64"
+	mgr.EnvBackends = nil
+	mgr.StageOnly = true
+	running := false
+	mgr.findPIDs = func(prefix string) ([]int, error) {
+		if running {
+			return []int{42}, nil
+		}
+		return nil, nil
+	}
+	mgr.killPID = func(pid int, force bool) error {
+		t.Fatalf("staging and applying must not stop the app")
+		return nil
+	}
+	if err := mgr.Update("aria2", man); err != nil {
+		t.Fatalf("stage failed: %v", err)
+	}
+	state, _ := ReadState(root, "aria2")
+	if state.CurrentVersion != "1.36.0" || state.PendingVersion != "1.37.0" {
+		t.Fatalf("expected 1.37.0 pending, got %+v", state)
+	}
+	// A second stage-only check finds the version staged and adds nothing
+	// to the history.
+	if err := mgr.Update("aria2", man); err != nil {
+		t.Fatalf("second stage failed: %v", err)
+	}
+	if history, _ := ReadHistory(root, "aria2"); len(history) != 1 || history[0].Outcome != OutcomeDeferred {
+		t.Fatalf("expected one deferred history entry, got %+v", history)
+	}
+
+	running = true
+	if applied, err := mgr.ApplyPending("aria2", man.Bin); err != nil || applied != "" {
+		t.Fatalf("expected nothing applied while running, got %q %v", applied, err)
+	}
+	running = false
+	// A background update holding the lock leaves the version staged too.
+	lockPath := filepath.Join(appDir, ".lock")
+	if err := acquireLock(lockPath); err != nil {
+		t.Fatalf("take lock: %v", err)
+	}
+	if applied, err := mgr.ApplyPending("aria2", man.Bin); err != nil || applied != "" {
+		t.Fatalf("expected nothing applied while locked, got %q %v", applied, err)
+	}
+	releaseLock(lockPath)
+	applied, err := mgr.ApplyPending("aria2", man.Bin)
+	if err != nil || applied != "1.37.0" {
+		t.Fatalf("expected 1.37.0 applied, got %q %v", applied, err)
+	}
+	state, _ = ReadState(root, "aria2")
+	target, _ := resolveCurrentTarget(filepath.Join(appDir, "current"))
+	if state.CurrentVersion != "1.37.0" || state.PendingVersion != "" || filepath.Base(target) != "1.37.0" {
+		t.Fatalf("unexpected state after apply: %+v target %q", state, target)
+	}
+	history, _ := ReadHistory(root, "aria2")
+	if last := history[len(history)-1]; last.Outcome != OutcomeSuccess || last.PreviousVersion != "1.36.0" || last.SwitchedAt == "" {
+		t.Fatalf("unexpected apply history entry: %+v", last)
+	}
+	// With nothing pending a held lock is not an error.
+	if err := acquireLock(lockPath); err != nil {
+		t.Fatalf("take lock: %v", err)
+	}
+	defer releaseLock(lockPath)
+	if applied, err := mgr.ApplyPending("aria2", man.Bin); err != nil || applied != "" {
+		t.Fatalf("expected nothing left to apply, got %q %v", applied, err)
+	}
+}
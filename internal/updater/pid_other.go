@@ -0,0 +1,25 @@
+//go:build !windows
+
+package updater
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// isPIDRunning probes pid with signal 0. A process owned by another user
+// answers EPERM, which still means it is alive.
+func isPIDRunning(pid int) (bool, error) {
+	if pid <= 0 {
+		return false, nil
+	}
+	err := syscall.Kill(pid, 0)
+	switch {
+	case err == nil, errors.Is(err, syscall.EPERM):
+		return true, nil
+	case errors.Is(err, syscall.ESRCH):
+		return false, nil
+	}
+	return false, fmt.Errorf("query pid %d: %w", pid, err)
+}
@@ -0,0 +1,33 @@
+//go:build !windows
+
+package updater
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestLockOfExitedProcessIsStale(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("run child: %v", err)
+	}
+	lockPath := filepath.Join(t.TempDir(), ".lock")
+	if err := os.WriteFile(lockPath, []byte(`{"pid":`+strconv.Itoa(cmd.Process.Pid)+`}`), 0o644); err != nil {
+		t.Fatalf("write lock: %v", err)
+	}
+	if stale, err := lockFileIsStale(lockPath); err != nil || !stale {
+		t.Fatalf("expected lock of exited pid to be stale, got %v, %v", stale, err)
+	}
+	if err := acquireLock(lockPath); err != nil {
+		t.Fatalf("expected stale lock to be taken over: %v", err)
+	}
+	releaseLock(lockPath)
+
+	if running, err := isPIDRunning(os.Getpid()); err != nil || !running {
+		t.Fatalf("expected own pid to be running, got %v, %v", running, err)
+	}
+}
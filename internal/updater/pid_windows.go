@@ -0,0 +1,26 @@
+//go:build windows
+
+package updater
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// isPIDRunning asks tasklist whether pid is a live process.
+func isPIDRunning(pid int) (bool, error) {
+	if pid <= 0 {
+		return false, nil
+	}
+	out, err := exec.Command("tasklist", "/FI", "PID eq "+strconv.Itoa(pid), "/FO", "CSV", "/NH").CombinedOutput()
+	if err != nil {
+		return false, fmt.Errorf("query pid %d: %w: %s", pid, err, strings.TrimSpace(string(out)))
+	}
+	raw := strings.ToLower(string(out))
+	if strings.Contains(raw, "no tasks are running") {
+		return false, nil
+	}
+	return strings.Contains(raw, `"`+strconv.Itoa(pid)+`"`), nil
+}
@@ -0,0 +1,118 @@
+package updater
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"appstract/internal/manifest"
+)
+
+// runExtractPipeline applies the manifest extract steps to the downloaded
+// archive inside staging and returns the directory that plays the role of the
+// extracted root (before extract_dir is applied).
+func (m *Manager) runExtractPipeline(appName string, steps []manifest.ExtractStep, archivePath, staging string) (string, error) {
+	current := archivePath
+	for i, step := range steps {
+		next, err := m.runExtractStep(i, step, current, staging)
+		if err != nil {
+			err = fmt.Errorf("extract step %d (%s): %w", i+1, step.Action, err)
+			_ = m.logEvent(appName, "extract", "PKG_EXTRACT_STEP_FAILED", ErrCodePkgExtract, err.Error())
+			return "", err
+		}
+		m.report(MessageLevelDebug, "extract step %d/%d %s: %s", i+1, len(steps), step.Action, next)
+		_ = m.logEvent(appName, "extract", "PKG_EXTRACT_STEP_DONE", "", fmt.Sprintf("step=%d action=%s result=%s", i+1, step.Action, next))
+		current = next
+	}
+	info, err := os.Stat(current)
+	if err != nil {
+		return "", fmt.Errorf("stat extract pipeline result: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("extract pipeline must end in a directory, got file: %s", filepath.Base(current))
+	}
+	return current, nil
+}
+
+func (m *Manager) runExtractStep(index int, step manifest.ExtractStep, current, staging string) (string, error) {
+	info, err := os.Stat(current)
+	if err != nil {
+		return "", fmt.Errorf("stat step input: %w", err)
+	}
+	switch step.Action {
+	case manifest.ExtractActionExtract:
+		if info.IsDir() {
+			return "", fmt.Errorf("input is a directory; add a select step first")
+		}
+		dst := filepath.Join(staging, "extract-"+strconv.Itoa(index+1))
+		if err := os.RemoveAll(dst); err != nil {
+			return "", fmt.Errorf("cleanup step dir: %w", err)
+		}
+		if step.Tool == manifest.ExtractTool7Zip {
+			if err := extractWith7ZipPackage(current, dst); err != nil {
+				return "", err
+			}
+			return dst, checkExtractedLimits(current, dst, m.ExtractLimits)
+		}
+		return dst, extractPackage(current, dst, m.ExtractLimits)
+	case manifest.ExtractActionSelect:
+		if !info.IsDir() {
+			return "", fmt.Errorf("input is a file; add an extract step first")
+		}
+		return selectExtracted(current, step.Glob)
+	case manifest.ExtractActionStrip:
+		if !info.IsDir() {
+			return "", fmt.Errorf("input is a file; add an extract step first")
+		}
+		components := step.Components
+		if components == 0 {
+			components = 1
+		}
+		dir := current
+		for n := 0; n < components; n++ {
+			inner, err := singleSubdirectory(dir)
+			if err != nil {
+				return "", err
+			}
+			dir = inner
+		}
+		return dir, nil
+	default:
+		return "", fmt.Errorf("unsupported action %q", step.Action)
+	}
+}
+
+func selectExtracted(dir, pattern string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, filepath.FromSlash(pattern)))
+	if err != nil {
+		return "", fmt.Errorf("invalid glob %q: %w", pattern, err)
+	}
+	var inside []string
+	for _, match := range matches {
+		if pathWithin(dir, match) {
+			inside = append(inside, match)
+		}
+	}
+	switch len(inside) {
+	case 0:
+		return "", fmt.Errorf("glob %q matched nothing", pattern)
+	case 1:
+		return inside[0], nil
+	default:
+		sort.Strings(inside)
+		return "", fmt.Errorf("glob %q is ambiguous, matched %d entries (first: %s)", pattern, len(inside), filepath.Base(inside[0]))
+	}
+}
+
+func singleSubdirectory(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("read strip dir: %w", err)
+	}
+	if len(entries) != 1 || !entries[0].IsDir() {
+		return "", fmt.Errorf("cannot strip %s: expected exactly one directory, found %d entries", filepath.Base(dir), len(entries))
+	}
+	return filepath.Join(dir, entries[0].Name()), nil
+}
@@ -0,0 +1,97 @@
+package updater
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"appstract/internal/manifest"
+)
+
+func TestRunExtractPipelineNestedArchive(t *testing.T) {
+	root := t.TempDir()
+	staging := filepath.Join(root, "apps", "tool", "_staging", "2.0.0")
+	if err := os.MkdirAll(staging, 0o755); err != nil {
+		t.Fatalf("mkdir staging: %v", err)
+	}
+	inner := buildZip(t, map[string]string{
+		"tool-2.0.0/tool.exe": "binary",
+	})
+	outer := buildZip(t, map[string]string{
+		"readme.txt":          "hello",
+		"payload/tool-64.zip": string(inner),
+	})
+	archivePath := filepath.Join(staging, "tool-bundle.zip")
+	if err := os.WriteFile(archivePath, outer, 0o644); err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+
+	mgr := NewManager(root)
+	mgr.Now = func() time.Time { return time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC) }
+	steps := []manifest.ExtractStep{
+		{Action: manifest.ExtractActionExtract},
+		{Action: manifest.ExtractActionSelect, Glob: "payload/*-64.zip"},
+		{Action: manifest.ExtractActionExtract},
+		{Action: manifest.ExtractActionStrip},
+	}
+	dir, err := mgr.runExtractPipeline("tool", steps, archivePath, staging)
+	if err != nil {
+		t.Fatalf("runExtractPipeline failed: %v", err)
+	}
+	if filepath.Base(dir) != "tool-2.0.0" {
+		t.Fatalf("expected stripped directory, got %s", dir)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tool.exe")); err != nil {
+		t.Fatalf("expected tool.exe in pipeline result: %v", err)
+	}
+
+	logBytes, err := os.ReadFile(filepath.Join(root, "apps", "tool", "logs", "events-20260301.log"))
+	if err != nil {
+		t.Fatalf("read event log: %v", err)
+	}
+	if got := strings.Count(string(logBytes), `"event":"PKG_EXTRACT_STEP_DONE"`); got != len(steps) {
+		t.Fatalf("expected %d step events, got %d: %s", len(steps), got, logBytes)
+	}
+}
+
+func TestRunExtractPipelineRejectsAmbiguousSelect(t *testing.T) {
+	root := t.TempDir()
+	staging := filepath.Join(root, "staging")
+	if err := os.MkdirAll(staging, 0o755); err != nil {
+		t.Fatalf("mkdir staging: %v", err)
+	}
+	archivePath := filepath.Join(staging, "bundle.zip")
+	if err := os.WriteFile(archivePath, buildZip(t, map[string]string{"a.zip": "a", "b.zip": "b"}), 0o644); err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+
+	mgr := NewManager(root)
+	steps := []manifest.ExtractStep{
+		{Action: manifest.ExtractActionExtract},
+		{Action: manifest.ExtractActionSelect, Glob: "*.zip"},
+	}
+	_, err := mgr.runExtractPipeline("tool", steps, archivePath, staging)
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Fatalf("expected ambiguous select error, got: %v", err)
+	}
+}
+
+func TestRunExtractPipelineMustEndInDirectory(t *testing.T) {
+	root := t.TempDir()
+	archivePath := filepath.Join(root, "bundle.zip")
+	if err := os.WriteFile(archivePath, buildZip(t, map[string]string{"setup.exe": "x"}), 0o644); err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+
+	mgr := NewManager(root)
+	steps := []manifest.ExtractStep{
+		{Action: manifest.ExtractActionExtract},
+		{Action: manifest.ExtractActionSelect, Glob: "setup.exe"},
+	}
+	_, err := mgr.runExtractPipeline("tool", steps, archivePath, root)
+	if err == nil || !strings.Contains(err.Error(), "must end in a directory") {
+		t.Fatalf("expected directory result error, got: %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package updater
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Remove deletes apps/<app> while holding its lock, so no update, check or
+// rollback sees a half-deleted tree. Running processes are stopped first,
+// as for a rollback; a held lock fails with LOCK_BUSY and leaves the files.
+func (m *Manager) Remove(appName string) error {
+	if appName == "" {
+		return newError(ErrCodeManifestInvalid, "remove", false, "app name is required")
+	}
+	fail := func(code string, err error) error {
+		return withTarget(wrapError(code, "remove", err), appName, "")
+	}
+	appDir := filepath.Join(m.Root, "apps", appName)
+	lockPath := filepath.Join(appDir, ".lock")
+	if err := acquireLock(lockPath); err != nil {
+		return withTarget(err, appName, "")
+	}
+	defer releaseLock(lockPath)
+
+	currentPath := filepath.Join(appDir, "current")
+	if _, err := os.Lstat(currentPath); err == nil {
+		if err := m.terminateProcesses(appName, currentPath); err != nil {
+			return fail(ErrCodeSwitchProcess, err)
+		}
+	}
+	entries, err := os.ReadDir(appDir)
+	if err != nil {
+		return fail(ErrCodeFilesystem, fmt.Errorf("read app directory: %w", err))
+	}
+	// The lock goes last, together with the directory it guards.
+	for _, e := range entries {
+		if e.Name() == ".lock" {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(appDir, e.Name())); err != nil {
+			return fail(ErrCodeFilesystem, fmt.Errorf("remove app directory: %w", err))
+		}
+	}
+	releaseLock(lockPath)
+	if err := os.Remove(appDir); err != nil && !os.IsNotExist(err) {
+		return fail(ErrCodeFilesystem, fmt.Errorf("remove app directory: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package updater
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRemoveStopsAppUnderLock(t *testing.T) {
+	root := t.TempDir()
+	appDir := filepath.Join(root, "apps", "demo")
+	if err := os.MkdirAll(filepath.Join(appDir, "1.0"), 0o755); err != nil {
+		t.Fatalf("create version dir: %v", err)
+	}
+	if err := switchCurrent(filepath.Join(appDir, "current"), filepath.Join(appDir, "1.0")); err != nil {
+		t.Fatalf("switch current: %v", err)
+	}
+	if err := saveState(filepath.Join(appDir, "runtime.json"), RuntimeState{CurrentVersion: "1.0"}); err != nil {
+		t.Fatalf("write state: %v", err)
+	}
+
+	mgr := NewManager(root)
+	running := []int{42}
+	mgr.findPIDs = func(prefix string) ([]int, error) { return running, nil }
+	mgr.closePID = nil
+	var killed []int
+	mgr.killPID = func(pid int, force bool) error {
+		killed = append(killed, pid)
+		running = nil
+		return nil
+	}
+
+	lockPath := filepath.Join(appDir, ".lock")
+	if err := acquireLock(lockPath); err != nil {
+		t.Fatalf("take lock: %v", err)
+	}
+	err := mgr.Remove("demo")
+	if ue, ok := AsError(err); !ok || ue.Code != ErrCodeLockBusy {
+		t.Fatalf("expected LOCK_BUSY while an update holds the lock, got %v", err)
+	}
+	if len(killed) != 0 || !isDir(filepath.Join(appDir, "1.0")) {
+		t.Fatalf("a refused remove must leave the app alone: killed=%v", killed)
+	}
+	releaseLock(lockPath)
+
+	if err := mgr.Remove("demo"); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+	if len(killed) != 1 {
+		t.Fatalf("expected the running app stopped, got %v", killed)
+	}
+	if _, err := os.Stat(appDir); !os.IsNotExist(err) {
+		t.Fatalf("expected apps/demo removed: %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package updater
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Rollback switches apps/<app>/current back to an older installed version.
+// An empty version picks the version the current one replaced, according to
+// the history, or else the most recently installed other version directory.
+// Running processes are stopped first, as for an update switch.
+func (m *Manager) Rollback(appName, version string) (err error) {
+	if appName == "" {
+		return newError(ErrCodeManifestInvalid, "rollback", false, "app name is required")
+	}
+	if version != "" && !isVersionName(version) {
+		return withTarget(newError(ErrCodeManifestInvalid, "rollback", false, "invalid version: %q", version), appName, "")
+	}
+	fail := func(code string, err error) error {
+		return withTarget(wrapError(code, "rollback", err), appName, version)
+	}
+	lockPath := filepath.Join(m.Root, "apps", appName, ".lock")
+	if err := acquireLock(lockPath); err != nil {
+		return withTarget(err, appName, version)
+	}
+	defer releaseLock(lockPath)
+
+	statePath := filepath.Join(m.Root, "apps", appName, "runtime.json")
+	state, err := loadState(statePath)
+	if err != nil {
+		return fail(ErrCodeFilesystem, err)
+	}
+	if state.CurrentVersion == "" {
+		return fail(ErrCodeSwitchRollback, fmt.Errorf("app %s is not installed", appName))
+	}
+	if version == "" {
+		if version, err = m.rollbackTarget(appName, state.CurrentVersion); err != nil {
+			return fail(ErrCodeSwitchRollback, err)
+		}
+	}
+	if version == state.CurrentVersion {
+		return fail(ErrCodeSwitchRollback, fmt.Errorf("%s is already the current version", version))
+	}
+	versionDir := filepath.Join(m.Root, "apps", appName, version)
+	if !isDir(versionDir) {
+		return fail(ErrCodeSwitchRollback, fmt.Errorf("version %s is not installed", version))
+	}
+
+	m.beginTx(appName, version, state.CurrentVersion)
+	defer func() { m.finishTx(appName, err) }()
+	m.updateTx(appName, func(tx *transaction) { tx.attempted, tx.rolledBack = true, true })
+	m.report(MessageLevelDefault, "rollback start: app=%s %s -> %s", appName, state.CurrentVersion, version)
+	_ = m.logEvent(appName, "rollback", "ROLLBACK_BEGIN", "", fmt.Sprintf("from=%s to=%s", state.CurrentVersion, version))
+
+	currentPath := filepath.Join(m.Root, "apps", appName, "current")
+	if err := m.terminateProcesses(appName, currentPath); err != nil {
+		_ = m.logEvent(appName, "rollback", "ROLLBACK_FAILED", ErrCodeSwitchProcess, err.Error())
+		return fail(ErrCodeSwitchProcess, err)
+	}
+	if err := switchCurrent(currentPath, versionDir); err != nil {
+		_ = m.logEvent(appName, "rollback", "ROLLBACK_FAILED", ErrCodeSwitchRollback, err.Error())
+		return fail(ErrCodeSwitchRollback, err)
+	}
+	now := m.now().UTC().Format(time.RFC3339)
+	m.updateTx(appName, func(tx *transaction) { tx.switchedAt = now })
+	state.CurrentVersion = version
+	state.PendingVersion = ""
+	state.LastUpdateAt = now
+	if err := saveState(statePath, state); err != nil {
+		return fail(ErrCodeFilesystem, err)
+	}
+	m.syncEnvironment(appName)
+	_ = m.logEvent(appName, "rollback", "ROLLBACK_DONE", "", "current switched to "+version)
+	m.report(MessageLevelDefault, "[ok] rollback complete: app=%s version=%s", appName, version)
+	return nil
+}
+
+func (m *Manager) rollbackTarget(appName, current string) (string, error) {
+	appDir := filepath.Join(m.Root, "apps", appName)
+	history, err := ReadHistory(m.Root, appName)
+	if err != nil {
+		return "", err
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		entry := history[i]
+		if entry.Outcome != OutcomeSuccess || entry.Version != current || entry.PreviousVersion == "" {
+			continue
+		}
+		if isDir(filepath.Join(appDir, entry.PreviousVersion)) {
+			return entry.PreviousVersion, nil
+		}
+		break
+	}
+	versions, err := installedVersions(appDir)
+	if err != nil {
+		return "", err
+	}
+	for _, v := range versions {
+		if v != current {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("no other installed version of %s to roll back to", appName)
+}
+
+// installedVersions lists the version directories of an app, most recently
+// modified first.
+func installedVersions(appDir string) ([]string, error) {
+	entries, err := os.ReadDir(appDir)
+	if err != nil {
+		return nil, fmt.Errorf("read app directory: %w", err)
+	}
+	type versionEntry struct {
+		name    string
+		modTime time.Time
+	}
+	var versions []versionEntry
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() || !isVersionName(name) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		versions = append(versions, versionEntry{name: name, modTime: info.ModTime()})
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].modTime.After(versions[j].modTime) })
+	names := make([]string, 0, len(versions))
+	for _, v := range versions {
+		names = append(names, v.name)
+	}
+	return names, nil
+}
+
+// isVersionName reports whether name can be a version directory under
+// apps/<app>: a single path element that is not one of the reserved entries.
+func isVersionName(name string) bool {
+	switch name {
+	case "", ".", "..", "current", "_staging", "logs":
+		return false
+	}
+	return !strings.ContainsAny(name, `/\`)
+}
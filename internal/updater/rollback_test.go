@@ -0,0 +1,70 @@
+package updater
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRollbackSwitchesToPreviousVersion(t *testing.T) {
+	root := t.TempDir()
+	appDir := filepath.Join(root, "apps", "demo")
+	base := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	for i, v := range []string{"1.0", "2.0", "3.0"} {
+		dir := filepath.Join(appDir, v)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("create version dir: %v", err)
+		}
+		stamp := base.AddDate(0, 0, i)
+		os.Chtimes(dir, stamp, stamp)
+	}
+	if err := switchCurrent(filepath.Join(appDir, "current"), filepath.Join(appDir, "3.0")); err != nil {
+		t.Fatalf("switch current: %v", err)
+	}
+	if err := saveState(filepath.Join(appDir, "runtime.json"), RuntimeState{CurrentVersion: "3.0"}); err != nil {
+		t.Fatalf("write state: %v", err)
+	}
+	// History says 3.0 replaced 1.0, so the newer 2.0 directory is not the
+	// rollback target.
+	if err := appendHistory(root, HistoryEntry{TxID: "t1", App: "demo", Version: "3.0", PreviousVersion: "1.0", Outcome: OutcomeSuccess}); err != nil {
+		t.Fatalf("write history: %v", err)
+	}
+
+	mgr := NewManager(root)
+	mgr.EnvBackends = nil
+	mgr.findPIDs = func(prefix string) ([]int, error) { return nil, nil }
+	mgr.killPID = func(pid int, force bool) error { return nil }
+	if err := mgr.Rollback("demo", ""); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	state, _ := ReadState(root, "demo")
+	target, _ := resolveCurrentTarget(filepath.Join(appDir, "current"))
+	if state.CurrentVersion != "1.0" || filepath.Base(target) != "1.0" {
+		t.Fatalf("expected current 1.0, got state %q target %q", state.CurrentVersion, target)
+	}
+	history, _ := ReadHistory(root, "demo")
+	if last := history[len(history)-1]; last.Version != "1.0" || last.PreviousVersion != "3.0" || !last.RolledBack || last.Outcome != OutcomeSuccess {
+		t.Fatalf("unexpected rollback history entry: %+v", last)
+	}
+
+	// Without history the newest other version is picked.
+	os.Remove(historyPath(root, "demo"))
+	if err := mgr.Rollback("demo", ""); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	if state, _ := ReadState(root, "demo"); state.CurrentVersion != "3.0" {
+		t.Fatalf("expected newest other version 3.0, got %q", state.CurrentVersion)
+	}
+
+	err := mgr.Rollback("demo", "9.9")
+	if ue, ok := AsError(err); !ok || ue.Code != ErrCodeSwitchRollback {
+		t.Fatalf("expected SWITCH_ROLLBACK for a missing version, got %v", err)
+	}
+	for _, version := range []string{"../other/1.0", "..", ".", "_staging", "current", "logs", `sub\1.0`} {
+		err := mgr.Rollback("demo", version)
+		if ue, ok := AsError(err); !ok || ue.Code != ErrCodeManifestInvalid {
+			t.Fatalf("expected MANIFEST_INVALID for version %q, got %v", version, err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package updater
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"appstract/internal/manifest"
+)
+
+// AppStatus is what a workspace knows about one app: its runtime state, the
+// version its manifest asks for and the versions installed side by side.
+type AppStatus struct {
+	App string `json:"app"`
+	RuntimeState
+	Installed       bool     `json:"installed"`
+	ManifestVersion string   `json:"manifest_version,omitempty"`
+	Versions        []string `json:"versions,omitempty"`
+	// Locked reports an update or rollback holding apps/<app>/.lock, and
+	// LockPID the process holding it, when known.
+	Locked  bool `json:"locked"`
+	LockPID int  `json:"lock_pid,omitempty"`
+}
+
+// ListApps returns the apps that have a manifest in manifests/ or a
+// directory in apps/, sorted by name.
+func ListApps(root string) ([]string, error) {
+	seen := map[string]bool{}
+	var apps []string
+	add := func(name string) {
+		if name == "" || strings.HasPrefix(name, ".") || seen[strings.ToLower(name)] {
+			return
+		}
+		seen[strings.ToLower(name)] = true
+		apps = append(apps, name)
+	}
+	entries, err := os.ReadDir(filepath.Join(root, "manifests"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read manifests: %w", err)
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.EqualFold(filepath.Ext(name), ".json") {
+			continue
+		}
+		add(strings.TrimSuffix(name, filepath.Ext(name)))
+	}
+	entries, err = os.ReadDir(filepath.Join(root, "apps"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read apps: %w", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			add(e.Name())
+		}
+	}
+	sort.Slice(apps, func(i, j int) bool { return strings.ToLower(apps[i]) < strings.ToLower(apps[j]) })
+	return apps, nil
+}
+
+// ReadStatus collects the status of one app. A manifest that does not parse
+// leaves ManifestVersion empty rather than failing the status.
+func ReadStatus(root, appName string) (AppStatus, error) {
+	status := AppStatus{App: appName}
+	state, err := ReadState(root, appName)
+	if err != nil {
+		return status, err
+	}
+	status.RuntimeState = state
+	appDir := filepath.Join(root, "apps", appName)
+	if _, err := os.Stat(filepath.Join(appDir, "current")); err == nil {
+		status.Installed = true
+	}
+	if man, err := manifest.ParseFile(filepath.Join(root, "manifests", appName+".json")); err == nil {
+		status.ManifestVersion = man.Version
+	}
+	if versions, err := installedVersions(appDir); err == nil {
+		status.Versions = versions
+	}
+	lockPath := filepath.Join(appDir, ".lock")
+	if _, err := os.Stat(lockPath); err == nil {
+		stale, err := lockFileIsStale(lockPath)
+		status.Locked = err != nil || !stale
+		if status.Locked {
+			status.LockPID = lockHolder(lockPath)
+		}
+	}
+	return status, nil
+}
+
+// lockHolder returns the PID recorded in a lock file, or 0.
+func lockHolder(lockPath string) int {
+	b, err := os.ReadFile(lockPath)
+	if err != nil {
+		return 0
+	}
+	var info lockInfo
+	if json.Unmarshal(b, &info) != nil {
+		return 0
+	}
+	return info.PID
+}
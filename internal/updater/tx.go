@@ -0,0 +1,116 @@
+package updater
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// eventTimeLayout is RFC3339 with fixed millisecond precision, so event
+// timestamps order correctly both as times and as strings.
+const eventTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// transaction is the update an app is going through. logEvent stamps every
+// event of the app with it while it is active; the per-app lock keeps it to
+// one transaction per app at a time.
+type transaction struct {
+	id              string
+	version         string
+	previousVersion string
+	// stageStart holds when each stage logged its first event; elapsed
+	// times are measured from there, so the update stage spans the whole
+	// transaction. stageMS keeps the latest elapsed time of each stage.
+	stageStart map[string]time.Time
+	stageMS    map[string]int64
+
+	// What the history entry records.
+	started       time.Time
+	startedAt     time.Time
+	attempted     bool
+	declined      bool
+	deferred      bool
+	rolledBack    bool
+	installedAt   string
+	switchedAt    string
+	downloadBytes int64
+}
+
+func newTxID(now time.Time) string {
+	var b [4]byte
+	_, _ = rand.Read(b[:])
+	return now.UTC().Format("20060102T150405") + "-" + hex.EncodeToString(b[:])
+}
+
+// beginTx starts a transaction for appName and returns its ID.
+func (m *Manager) beginTx(appName, version, previousVersion string) string {
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+	if m.txs == nil {
+		m.txs = map[string]*transaction{}
+	}
+	now := m.now()
+	tx := &transaction{
+		id:              newTxID(now),
+		version:         version,
+		previousVersion: previousVersion,
+		stageStart:      map[string]time.Time{},
+		stageMS:         map[string]int64{},
+		started:         time.Now(),
+		startedAt:       now,
+	}
+	m.txs[appName] = tx
+	return tx.id
+}
+
+// updateTx changes the active transaction of appName, if any.
+func (m *Manager) updateTx(appName string, change func(tx *transaction)) {
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+	if tx := m.txs[appName]; tx != nil {
+		change(tx)
+	}
+}
+
+// finishTx ends the transaction of appName and appends it to the app's
+// history. err is what Update returns.
+func (m *Manager) finishTx(appName string, err error) {
+	m.txMu.Lock()
+	tx := m.txs[appName]
+	delete(m.txs, appName)
+	m.txMu.Unlock()
+	if tx == nil {
+		return
+	}
+	if histErr := m.recordHistory(tx, appName, err); histErr != nil {
+		m.report(MessageLevelDebug, "record history for %s failed: %v", appName, histErr)
+	}
+}
+
+// stampTx fills in the transaction fields of an event. Elapsed time uses the
+// monotonic clock rather than Manager.Now, which tests pin.
+func (m *Manager) stampTx(entry *StageEvent) {
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+	tx := m.txs[entry.App]
+	if tx == nil {
+		return
+	}
+	entry.TxID = tx.id
+	entry.Version = tx.version
+	entry.PreviousVersion = tx.previousVersion
+	now := time.Now()
+	start, ok := tx.stageStart[entry.Stage]
+	if !ok {
+		tx.stageStart[entry.Stage] = now
+		start = now
+	}
+	entry.ElapsedMS = now.Sub(start).Milliseconds()
+	tx.stageMS[entry.Stage] = entry.ElapsedMS
+}
+
+func (m *Manager) now() time.Time {
+	if m.Now != nil {
+		return m.Now()
+	}
+	return time.Now()
+}
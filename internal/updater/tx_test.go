@@ -0,0 +1,79 @@
+package updater
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"appstract/internal/manifest"
+)
+
+func TestUpdateStampsEventsWithTransaction(t *testing.T) {
+	root := t.TempDir()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	if err := saveState(filepath.Join(root, "apps", "aria2", "runtime.json"), RuntimeState{CurrentVersion: "1.36.0"}); err != nil {
+		t.Fatalf("write state: %v", err)
+	}
+	man := &manifest.Manifest{
+		Version:      "1.37.0",
+		Architecture: manifest.Architecture{X64: manifest.Artifact{URL: server.URL + "/aria2.zip", Hash: sha256Hex([]byte("x"))}},
+		Bin:          "aria2c.exe",
+	}
+
+	mgr := NewManager(root)
+	mgr.Client = server.Client()
+	mgr.GOARCH = "amd64"
+	var seen []StageEvent
+	mgr.OnEvent = func(e StageEvent) { seen = append(seen, e) }
+	if err := mgr.Update("aria2", man); err == nil {
+		t.Fatalf("expected download failure")
+	}
+	if err := mgr.Update("aria2", man); err == nil {
+		t.Fatalf("expected download failure")
+	}
+
+	events, err := (&EventReader{Root: root}).Read()
+	if err != nil || len(events) != len(seen) || len(events) < 6 {
+		t.Fatalf("expected logged events to match OnEvent, got %d vs %d (%v)", len(events), len(seen), err)
+	}
+	first := events[0].TxID
+	if first == "" || events[len(events)-1].TxID == first {
+		t.Fatalf("expected two distinct transactions, got %+v", events)
+	}
+	for _, e := range events {
+		if e.TxID == "" || e.Version != "1.37.0" || e.PreviousVersion != "1.36.0" {
+			t.Fatalf("event missing transaction fields: %+v", e)
+		}
+	}
+	only, _ := (&EventReader{Root: root, Filter: EventFilter{TxID: first}}).Read()
+	if len(only) != len(events)/2 || only[0].Event != "UPDATE_BEGIN" {
+		t.Fatalf("expected one transaction's events, got %+v", only)
+	}
+}
+
+func TestStampTxMeasuresFromStageStart(t *testing.T) {
+	mgr := NewManager(t.TempDir())
+	id := mgr.beginTx("a", "2.0", "1.0")
+	first := StageEvent{App: "a", Stage: "download"}
+	mgr.stampTx(&first)
+	time.Sleep(20 * time.Millisecond)
+	later := StageEvent{App: "a", Stage: "download"}
+	mgr.stampTx(&later)
+	other := StageEvent{App: "b", Stage: "download"}
+	mgr.stampTx(&other)
+	mgr.finishTx("a", nil)
+	after := StageEvent{App: "a", Stage: "download"}
+	mgr.stampTx(&after)
+
+	if first.TxID != id || first.ElapsedMS != 0 || later.ElapsedMS < 20 {
+		t.Fatalf("unexpected stamps: %+v %+v", first, later)
+	}
+	if other.TxID != "" || after.TxID != "" {
+		t.Fatalf("expected events outside the transaction to stay unstamped: %+v %+v", other, after)
+	}
+}
@@ -1,11 +1,8 @@
 package updater
 
 import (
-	"archive/zip"
 	"bytes"
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -21,8 +18,10 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"appstract/internal/appenv"
 	"appstract/internal/manifest"
 	"appstract/internal/winui"
 )
@@ -34,6 +33,9 @@ type RuntimeState struct {
 	LastErrorCode  string `json:"last_error_code,omitempty"`
 	LastErrorMsg   string `json:"last_error_message,omitempty"`
 	PendingVersion string `json:"pending_version,omitempty"`
+	// ConsecutiveFailures counts the updates that failed since the last
+	// one that succeeded, for callers that back off.
+	ConsecutiveFailures int `json:"consecutive_failures,omitempty"`
 }
 
 type Manager struct {
@@ -49,12 +51,32 @@ type Manager struct {
 	StopTimeout   time.Duration
 	OnMessage     func(level MessageLevel, msg string)
 	OnProgress    func(progress DownloadProgress)
+	OnEvent       func(event StageEvent)
+	ExtractLimits ExtractLimits
+	Architecture  string
+	GOARCH        string
+	// EnvBackends receive the regenerated app environment after every
+	// switch and cleanup; empty disables environment publishing.
+	EnvBackends []appenv.Backend
+	// LogRetention is applied to apps/<app>/logs on every cleanup.
+	LogRetention LogRetention
+	// DeferSwitchIfRunning leaves a new version staged, recorded as
+	// PendingVersion, instead of stopping the app to switch to it. A later
+	// update or ApplyPending switches once the app is no longer running.
+	DeferSwitchIfRunning bool
+	// StageOnly stops every update once the new version is staged and
+	// recorded as PendingVersion; nothing is stopped or switched. Use
+	// ApplyPending to switch to it later.
+	StageOnly bool
 
 	findPIDs func(prefix string) ([]int, error)
 	closePID func(pid int) error
 	killPID  func(pid int, force bool) error
 	launch   func(path string) error
 	confirm  func(appName, version string) (bool, error)
+
+	txMu sync.Mutex
+	txs  map[string]*transaction
 }
 
 type MessageLevel int
@@ -72,13 +94,22 @@ type DownloadProgress struct {
 	Done       bool
 }
 
-type switchLogEvent struct {
-	Timestamp string `json:"timestamp"`
-	App       string `json:"app"`
-	Stage     string `json:"stage"`
-	Event     string `json:"event"`
-	ErrorCode string `json:"error_code,omitempty"`
-	Message   string `json:"message,omitempty"`
+// StageEvent is one stage transition of an update transaction, as appended
+// to apps/<app>/logs/events-YYYYMMDD.log and passed to Manager.OnEvent.
+// Events logged during Update carry the transaction ID, the version being
+// installed, the version it replaces and the milliseconds since the stage
+// logged its first event.
+type StageEvent struct {
+	Timestamp       string `json:"timestamp"`
+	App             string `json:"app"`
+	Stage           string `json:"stage"`
+	Event           string `json:"event"`
+	ErrorCode       string `json:"error_code,omitempty"`
+	Message         string `json:"message,omitempty"`
+	TxID            string `json:"tx_id,omitempty"`
+	Version         string `json:"version,omitempty"`
+	PreviousVersion string `json:"previous_version,omitempty"`
+	ElapsedMS       int64  `json:"elapsed_ms,omitempty"`
 }
 
 type githubRelease struct {
@@ -92,7 +123,6 @@ type githubAsset struct {
 }
 
 var junctionCreator = createJunction
-var unzipPackage = unzip
 var extractWith7ZipPackage = extractWith7Zip
 
 func NewManager(root string) *Manager {
@@ -106,6 +136,9 @@ func NewManager(root string) *Manager {
 		ScriptTimeout: 2 * time.Minute,
 		KeepVersions:  2,
 		StopTimeout:   10 * time.Second,
+		ExtractLimits: DefaultExtractLimits(),
+		EnvBackends:   []appenv.Backend{appenv.FileBackend{Root: root}},
+		LogRetention:  DefaultLogRetention(),
 		findPIDs:      findRunningPIDsByPrefix,
 		closePID:      gracefulCloseByPID,
 		killPID:       killProcessByPID,
@@ -117,113 +150,171 @@ func NewManager(root string) *Manager {
 func (m *Manager) UpdateFromManifest(appName, manifestPath string) error {
 	man, err := manifest.ParseFile(manifestPath)
 	if err != nil {
-		return err
+		return &Error{Code: ErrCodeManifestInvalid, Stage: "manifest", App: appName, Err: err}
 	}
 	return m.Update(appName, man)
 }
 
-func (m *Manager) Update(appName string, man *manifest.Manifest) error {
+func (m *Manager) Update(appName string, man *manifest.Manifest) (err error) {
 	if appName == "" {
-		return fmt.Errorf("app name is required")
+		return newError(ErrCodeManifestInvalid, "update", false, "app name is required")
 	}
 	if man == nil {
-		return fmt.Errorf("manifest is required")
+		return newError(ErrCodeManifestInvalid, "update", false, "manifest is required")
 	}
 
+	defer func() { m.recordOutcome(appName, err) }()
 	effective := *man
+	fail := func(code, stage string, err error) error {
+		return withTarget(wrapError(code, stage, err), appName, effective.Version)
+	}
 	if m.UseCheckver {
 		if err := m.applyCheckver(&effective); err != nil {
-			return err
+			return withTarget(err, appName, effective.Version)
 		}
 	}
 
-	artifact, err := effective.ResolveArtifact64()
+	arch, artifact, err := effective.ResolveArtifactFor(m.HostArch(), m.Architecture)
 	if err != nil {
-		return err
+		return fail(ErrCodeManifestInvalid, "manifest", err)
 	}
 	m.report(MessageLevelDefault, "update start: app=%s version=%s", appName, effective.Version)
-	m.report(MessageLevelDebug, "artifact url=%s", artifact.URL)
+	m.report(MessageLevelDebug, "artifact arch=%s url=%s", arch, artifact.URL)
+	previous, _ := ReadState(m.Root, appName)
+	txID := m.beginTx(appName, effective.Version, previous.CurrentVersion)
+	defer func() { m.finishTx(appName, err) }()
+	m.report(MessageLevelDebug, "transaction %s", txID)
 	_ = m.logEvent(appName, "update", "UPDATE_BEGIN", "", "update transaction started")
 
 	lockPath := filepath.Join(m.Root, "apps", appName, ".lock")
 	if err := acquireLock(lockPath); err != nil {
-		return err
+		return withTarget(err, appName, effective.Version)
 	}
 	defer releaseLock(lockPath)
 
 	statePath := filepath.Join(m.Root, "apps", appName, "runtime.json")
 	state, err := loadState(statePath)
 	if err != nil {
-		return err
+		return fail(ErrCodeFilesystem, "state", err)
 	}
+	m.updateTx(appName, func(tx *transaction) { tx.previousVersion = state.CurrentVersion })
 	state.LastCheckAt = m.Now().UTC().Format(time.RFC3339)
 
 	if state.CurrentVersion == effective.Version && state.CurrentVersion != "" {
 		state.PendingVersion = ""
 		if err := saveState(statePath, state); err != nil {
-			return err
+			return fail(ErrCodeFilesystem, "state", err)
+		}
+		err := m.cleanupOldVersions(appName, effective.Version)
+		m.syncEnvironment(appName)
+		if err != nil {
+			return fail(ErrCodeFilesystem, "cleanup", err)
 		}
-		return m.cleanupOldVersions(appName, effective.Version)
+		return nil
 	}
 
 	staging := filepath.Join(m.Root, "apps", appName, "_staging", effective.Version)
 	versionDir := filepath.Join(m.Root, "apps", appName, effective.Version)
+	if state.PendingVersion == effective.Version && isDir(versionDir) {
+		m.report(MessageLevelDefault, "version %s is already staged", effective.Version)
+		if m.StageOnly {
+			if err := saveState(statePath, state); err != nil {
+				return fail(ErrCodeFilesystem, "state", err)
+			}
+			return nil
+		}
+		m.updateTx(appName, func(tx *transaction) { tx.attempted = true })
+		_ = m.logEvent(appName, "install", "PKG_STAGED_REUSE", "", versionDir)
+	} else if err := m.stage(appName, &effective, artifact, &state, statePath, staging, versionDir, fail); err != nil {
+		return err
+	}
+	return m.activate(appName, &effective, &state, statePath, versionDir, fail)
+}
+
+// stage downloads, verifies and extracts the artifact, runs pre_install and
+// moves the result to versionDir, leaving PendingVersion set to the version.
+func (m *Manager) stage(appName string, effective *manifest.Manifest, artifact manifest.Artifact, state *RuntimeState, statePath, staging, versionDir string, fail func(code, stage string, err error) error) error {
 	if err := os.RemoveAll(staging); err != nil {
-		return fmt.Errorf("cleanup old staging: %w", err)
+		return fail(ErrCodeFilesystem, "download", fmt.Errorf("cleanup old staging: %w", err))
 	}
 	if err := os.MkdirAll(staging, 0o755); err != nil {
-		return fmt.Errorf("create staging: %w", err)
+		return fail(ErrCodeFilesystem, "download", fmt.Errorf("create staging: %w", err))
 	}
 
 	state.PendingVersion = effective.Version
-	if err := saveState(statePath, state); err != nil {
-		return err
-	}
-
-	archivePath := filepath.Join(staging, archiveFileNameFromURL(artifact.URL))
-	m.report(MessageLevelDefault, "downloading package: %s", filepath.Base(archivePath))
-	_ = m.logEvent(appName, "download", "PKG_DOWNLOAD_BEGIN", "", artifact.URL)
-	if err := m.download(appName, artifact.URL, archivePath); err != nil {
+	if err := saveState(statePath, *state); err != nil {
+		return fail(ErrCodeFilesystem, "state", err)
+	}
+	m.updateTx(appName, func(tx *transaction) { tx.attempted = true })
+
+	items := artifact.Items()
+	archivePaths := stagedArchivePaths(staging, items)
+	archivePath := archivePaths[0]
+	if err := m.downloadItems(appName, items, archivePaths); err != nil {
+		// A typed failure such as NET_DOWNLOAD keeps its code, so the exit
+		// code and runtime.json tell network failures apart.
+		code := ErrCodePkgDownload
+		if ue, ok := AsError(err); ok {
+			code = ue.Code
+		}
 		state.PendingVersion = ""
-		state.LastErrorCode = ErrCodePkgDownload
+		state.LastErrorCode = code
 		state.LastErrorMsg = err.Error()
 		_ = m.logEvent(appName, "download", "PKG_DOWNLOAD_FAILED", state.LastErrorCode, err.Error())
-		_ = saveState(statePath, state)
-		return err
+		_ = saveState(statePath, *state)
+		return fail(code, "download", err)
 	}
-	m.report(MessageLevelDefault, "[ok] download complete: %s", filepath.Base(archivePath))
-	_ = m.logEvent(appName, "download", "PKG_DOWNLOAD_DONE", "", archivePath)
+	var downloaded int64
+	for _, p := range archivePaths {
+		if info, err := os.Stat(p); err == nil {
+			downloaded += info.Size()
+		}
+	}
+	m.updateTx(appName, func(tx *transaction) { tx.downloadBytes = downloaded })
 	m.report(MessageLevelDefault, "verifying package hash...")
-	if err := verifySHA256(archivePath, artifact.Hash); err != nil {
-		state.PendingVersion = ""
-		state.LastErrorCode = ErrCodePkgVerify
-		state.LastErrorMsg = err.Error()
-		_ = m.logEvent(appName, "verify", "PKG_VERIFY_FAILED", state.LastErrorCode, err.Error())
-		_ = saveState(statePath, state)
-		return err
+	_ = m.logEvent(appName, "verify", "PKG_VERIFY_BEGIN", "", "verifying sha256")
+	for i, item := range items {
+		if err := verifySHA256(archivePaths[i], item.Hash); err != nil {
+			state.PendingVersion = ""
+			state.LastErrorCode = ErrCodePkgVerify
+			state.LastErrorMsg = err.Error()
+			_ = m.logEvent(appName, "verify", "PKG_VERIFY_FAILED", state.LastErrorCode, err.Error())
+			_ = saveState(statePath, *state)
+			return fail(ErrCodePkgVerify, "verify", err)
+		}
 	}
 	m.report(MessageLevelDefault, "[ok] hash verify complete")
 	_ = m.logEvent(appName, "verify", "PKG_VERIFY_DONE", "", "sha256 verified")
 
 	extractedRoot := filepath.Join(staging, "extracted")
 	m.report(MessageLevelDefault, "extracting package...")
-	if err := extractPackage(archivePath, extractedRoot); err != nil {
+	_ = m.logEvent(appName, "extract", "PKG_EXTRACT_BEGIN", "", archivePath)
+	var extractErr error
+	if len(effective.Extract) > 0 {
+		extractedRoot, extractErr = m.runExtractPipeline(appName, effective.Extract, archivePath, staging)
+	} else {
+		extractErr = extractArtifact(artifact, archivePath, extractedRoot, m.ExtractLimits)
+	}
+	sourceDir := extractedRoot
+	if extractErr == nil && artifact.ExtractDir != "" {
+		sourceDir = filepath.Join(extractedRoot, artifact.ExtractDir)
+	}
+	if extractErr == nil && (len(items) > 1 || artifact.ExtractTo != "") {
+		sourceDir, extractErr = m.mergeArtifactItems(appName, artifact, archivePaths, sourceDir, staging)
+	}
+	if err := extractErr; err != nil {
 		state.PendingVersion = ""
 		state.LastErrorCode = ErrCodePkgExtract
 		state.LastErrorMsg = err.Error()
 		_ = m.logEvent(appName, "extract", "PKG_EXTRACT_FAILED", state.LastErrorCode, err.Error())
-		_ = saveState(statePath, state)
-		return err
+		_ = saveState(statePath, *state)
+		return fail(ErrCodePkgExtract, "extract", err)
 	}
 	m.report(MessageLevelDefault, "[ok] extract complete")
 	_ = m.logEvent(appName, "extract", "PKG_EXTRACT_DONE", "", extractedRoot)
 
-	sourceDir := extractedRoot
-	if artifact.ExtractDir != "" {
-		sourceDir = filepath.Join(extractedRoot, artifact.ExtractDir)
-	}
 	if _, err := os.Stat(sourceDir); err != nil {
-		return fmt.Errorf("source extract directory missing: %w", err)
+		return fail(ErrCodePkgExtract, "extract", fmt.Errorf("source extract directory missing: %w", err))
 	}
 	_ = m.logEvent(appName, "script", "SCRIPT_PREINSTALL_BEGIN", "", "running pre_install hooks")
 	m.report(MessageLevelDefault, "running pre_install scripts...")
@@ -232,21 +323,40 @@ func (m *Manager) Update(appName string, man *manifest.Manifest) error {
 		state.LastErrorCode = ErrCodeScriptPreInstall
 		state.LastErrorMsg = err.Error()
 		_ = m.logEvent(appName, "script", "SCRIPT_PREINSTALL_FAILED", state.LastErrorCode, err.Error())
-		_ = saveState(statePath, state)
-		return err
+		_ = saveState(statePath, *state)
+		return fail(ErrCodeScriptPreInstall, "script", err)
 	}
 	m.report(MessageLevelDefault, "[ok] pre_install scripts complete")
 	_ = m.logEvent(appName, "script", "SCRIPT_PREINSTALL_DONE", "", "pre_install completed")
 
 	if err := os.RemoveAll(versionDir); err != nil {
-		return fmt.Errorf("cleanup version dir: %w", err)
+		return fail(ErrCodePkgInstall, "install", fmt.Errorf("cleanup version dir: %w", err))
 	}
 	if err := os.Rename(sourceDir, versionDir); err != nil {
-		return fmt.Errorf("move extracted version: %w", err)
+		return fail(ErrCodePkgInstall, "install", fmt.Errorf("move extracted version: %w", err))
 	}
+	installedAt := m.Now().UTC().Format(time.RFC3339)
+	m.updateTx(appName, func(tx *transaction) { tx.installedAt = installedAt })
+	return nil
+}
 
+// activate switches current to the staged versionDir, unless the switch is
+// declined or deferred, then records the new current version.
+func (m *Manager) activate(appName string, effective *manifest.Manifest, state *RuntimeState, statePath, versionDir string, fail func(code, stage string, err error) error) error {
 	currentPath := filepath.Join(m.Root, "apps", appName, "current")
 	prevTarget, _ := resolveCurrentTarget(currentPath)
+	if reason := m.deferReason(currentPath); reason != "" {
+		m.updateTx(appName, func(tx *transaction) { tx.deferred = true })
+		state.PendingVersion = effective.Version
+		state.LastErrorCode = ""
+		state.LastErrorMsg = ""
+		_ = m.logEvent(appName, "switch", "SWITCH_DEFERRED", "", reason)
+		m.report(MessageLevelDefault, "switch deferred: app=%s version=%s (%s)", appName, effective.Version, reason)
+		if err := saveState(statePath, *state); err != nil {
+			return fail(ErrCodeFilesystem, "state", err)
+		}
+		return nil
+	}
 	if m.PromptSwitch {
 		confirmFn := m.confirm
 		if confirmFn == nil {
@@ -257,13 +367,17 @@ func (m *Manager) Update(appName string, man *manifest.Manifest) error {
 			state.PendingVersion = ""
 			state.LastErrorCode = ErrCodeSwitchPrompt
 			state.LastErrorMsg = err.Error()
-			_ = saveState(statePath, state)
-			return err
+			_ = saveState(statePath, *state)
+			return fail(ErrCodeSwitchPrompt, "switch", err)
 		}
 		if !approved {
 			state.PendingVersion = ""
+			m.updateTx(appName, func(tx *transaction) { tx.declined = true })
 			_ = m.logEvent(appName, "switch", "SWITCH_USER_DECLINED", "", "user declined immediate switch")
-			return saveState(statePath, state)
+			if err := saveState(statePath, *state); err != nil {
+				return fail(ErrCodeFilesystem, "state", err)
+			}
+			return nil
 		}
 	}
 	_ = m.logEvent(appName, "switch", "SWITCH_PROCESS_BEGIN", "", "begin process stop for current path")
@@ -272,8 +386,8 @@ func (m *Manager) Update(appName string, man *manifest.Manifest) error {
 		state.LastErrorCode = ErrCodeSwitchProcess
 		state.LastErrorMsg = err.Error()
 		_ = m.logEvent(appName, "switch", "SWITCH_PROCESS_FAILED", state.LastErrorCode, err.Error())
-		_ = saveState(statePath, state)
-		return err
+		_ = saveState(statePath, *state)
+		return fail(ErrCodeSwitchProcess, "switch", err)
 	}
 	_ = m.logEvent(appName, "switch", "SWITCH_PROCESS_DONE", "", "target processes stopped")
 	if err := switchCurrent(currentPath, versionDir); err != nil {
@@ -281,12 +395,15 @@ func (m *Manager) Update(appName string, man *manifest.Manifest) error {
 		state.LastErrorCode = ErrCodeSwitchCurrent
 		state.LastErrorMsg = err.Error()
 		_ = m.logEvent(appName, "switch", "SWITCH_CURRENT_FAILED", state.LastErrorCode, err.Error())
-		_ = saveState(statePath, state)
-		return err
+		_ = saveState(statePath, *state)
+		return fail(ErrCodeSwitchCurrent, "switch", err)
 	}
+	switchedAt := m.Now().UTC().Format(time.RFC3339)
+	m.updateTx(appName, func(tx *transaction) { tx.switchedAt = switchedAt })
 	_ = m.logEvent(appName, "switch", "SWITCH_CURRENT_DONE", "", "current version switched")
 	if err := m.healthcheckAndRelaunch(currentPath, effective.Bin); err != nil {
 		rollbackErr := rollbackCurrent(currentPath, prevTarget)
+		m.updateTx(appName, func(tx *transaction) { tx.rolledBack = rollbackErr == nil })
 		state.PendingVersion = ""
 		state.LastErrorCode = ErrCodeSwitchHealthcheck
 		state.LastErrorMsg = err.Error()
@@ -296,12 +413,13 @@ func (m *Manager) Update(appName string, man *manifest.Manifest) error {
 			state.LastErrorMsg = rollbackErr.Error()
 			_ = m.logEvent(appName, "rollback", "SWITCH_ROLLBACK_FAILED", state.LastErrorCode, rollbackErr.Error())
 		}
-		_ = saveState(statePath, state)
+		_ = saveState(statePath, *state)
+		m.syncEnvironment(appName)
 		if rollbackErr != nil {
-			return fmt.Errorf("healthcheck failed: %v; rollback failed: %v", err, rollbackErr)
+			return fail(ErrCodeSwitchRollback, "rollback", fmt.Errorf("healthcheck failed: %v; rollback failed: %w", err, rollbackErr))
 		}
 		_ = m.logEvent(appName, "rollback", "SWITCH_ROLLBACK_DONE", "", "rollback to previous current completed")
-		return err
+		return fail(ErrCodeSwitchHealthcheck, "healthcheck", err)
 	}
 	_ = m.logEvent(appName, "healthcheck", "SWITCH_HEALTHCHECK_DONE", "", "healthcheck passed")
 	m.report(MessageLevelDefault, "[ok] switch complete: app=%s version=%s", appName, effective.Version)
@@ -312,16 +430,21 @@ func (m *Manager) Update(appName string, man *manifest.Manifest) error {
 	state.LastErrorCode = ""
 	state.LastErrorMsg = ""
 
-	if err := saveState(statePath, state); err != nil {
-		return err
+	if err := saveState(statePath, *state); err != nil {
+		return fail(ErrCodeFilesystem, "state", err)
 	}
-	if err := m.cleanupOldVersions(appName, effective.Version); err != nil {
-		return err
+	cleanupErr := m.cleanupOldVersions(appName, effective.Version)
+	m.syncEnvironment(appName)
+	if cleanupErr != nil {
+		return fail(ErrCodeFilesystem, "cleanup", cleanupErr)
 	}
 	_ = m.logEvent(appName, "switch", "SWITCH_DONE", "", "update switch transaction completed")
 	_ = m.logEvent(appName, "update", "UPDATE_DONE", "", "update transaction completed")
 	m.report(MessageLevelDefault, "[ok] update done: app=%s version=%s", appName, effective.Version)
-	return os.RemoveAll(filepath.Join(m.Root, "apps", appName, "_staging"))
+	if err := os.RemoveAll(filepath.Join(m.Root, "apps", appName, "_staging")); err != nil {
+		return fail(ErrCodeFilesystem, "cleanup", err)
+	}
+	return nil
 }
 
 func (m *Manager) report(level MessageLevel, format string, args ...any) {
@@ -348,18 +471,19 @@ func (m *Manager) logEvent(appName, stage, event, errorCode, message string) err
 	if appName == "" {
 		return nil
 	}
-	when := time.Now
-	if m.Now != nil {
-		when = m.Now
-	}
-	entry := switchLogEvent{
-		Timestamp: when().UTC().Format(time.RFC3339),
+	when := m.now()
+	entry := StageEvent{
+		Timestamp: when.UTC().Format(eventTimeLayout),
 		App:       appName,
 		Stage:     stage,
 		Event:     event,
 		ErrorCode: errorCode,
 		Message:   message,
 	}
+	m.stampTx(&entry)
+	if m.OnEvent != nil {
+		m.OnEvent(entry)
+	}
 	b, err := json.Marshal(entry)
 	if err != nil {
 		return fmt.Errorf("encode event log: %w", err)
@@ -368,7 +492,7 @@ func (m *Manager) logEvent(appName, stage, event, errorCode, message string) err
 	if err := os.MkdirAll(logDir, 0o755); err != nil {
 		return fmt.Errorf("create event log dir: %w", err)
 	}
-	logPath := filepath.Join(logDir, "events-"+when().UTC().Format("20060102")+".log")
+	logPath := filepath.Join(logDir, eventLogName(when))
 	f, err := os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
 	if err != nil {
 		return fmt.Errorf("open event log: %w", err)
@@ -385,58 +509,97 @@ func (m *Manager) applyCheckver(man *manifest.Manifest) error {
 	if err != nil {
 		return err
 	}
+	fail := func(err error) error {
+		return &Error{Code: ErrCodeCheckverResolve, Stage: "checkver", Version: version, Err: err}
+	}
 	if version == "" || version == man.Version {
 		return nil
 	}
-	artifact := man.Autoupdate.Architecture.X64
-	if artifact.URL == "" {
-		return fmt.Errorf("checkver found newer version %s but autoupdate.64bit.url is empty", version)
+	rendered, err := renderAutoupdateArchitecture(man, captures)
+	if err != nil {
+		return fail(fmt.Errorf("checkver found newer version %s but %w", version, err))
 	}
-	artifact.URL = renderTemplate(artifact.URL, captures)
-	artifact.ExtractDir = renderTemplate(artifact.ExtractDir, captures)
-	artifact.Hash = ""
 	man.Version = version
-	man.Architecture.X64 = artifact
-	if _, err := man.ResolveArtifact64(); err != nil {
-		return fmt.Errorf("checkver resolved newer version %s but no verifiable hash is available: %w", version, err)
+	man.Architecture = rendered
+	if _, _, err := man.ResolveArtifactFor(m.HostArch(), m.Architecture); err != nil {
+		return fail(fmt.Errorf("checkver resolved newer version %s but no verifiable hash is available: %w", version, err))
 	}
 	return nil
 }
 
+// renderAutoupdateArchitecture expands the autoupdate URL and extract_dir
+// templates for every architecture that has one. Hashes are cleared because
+// they belong to the previous version.
+func renderAutoupdateArchitecture(man *manifest.Manifest, captures map[string]string) (manifest.Architecture, error) {
+	var rendered manifest.Architecture
+	found := false
+	for _, arch := range manifest.Architectures {
+		artifact := man.Autoupdate.Architecture.Get(arch)
+		if artifact.URL == "" {
+			continue
+		}
+		if artifact.Type == "" {
+			artifact.Type = man.Architecture.Get(arch).Type
+		}
+		items := artifact.Items()
+		for i := range items {
+			items[i].URL = renderTemplate(items[i].URL, captures)
+			items[i].ExtractDir = renderTemplate(items[i].ExtractDir, captures)
+			items[i].ExtractTo = renderTemplate(items[i].ExtractTo, captures)
+			items[i].Hash = ""
+		}
+		rendered.Set(arch, artifact.WithItems(items))
+		found = true
+	}
+	if !found {
+		return manifest.Architecture{}, fmt.Errorf("autoupdate.architecture has no url")
+	}
+	return rendered, nil
+}
+
+// HostArch is the GOARCH artifacts are resolved for: GOARCH when set, else
+// the architecture appstract was built for.
+func (m *Manager) HostArch() string {
+	if m.GOARCH != "" {
+		return m.GOARCH
+	}
+	return runtime.GOARCH
+}
+
 func (m *Manager) DiscoverLatest(man *manifest.Manifest) (string, map[string]string, error) {
 	if man.Checkver.GitHub == "" || man.Checkver.Regex == "" || man.Checkver.Replace == "" {
 		return "", nil, nil
 	}
 	owner, repo, err := parseGitHubRepo(man.Checkver.GitHub)
 	if err != nil {
-		return "", nil, err
+		return "", nil, wrapError(ErrCodeCheckverResolve, "checkver", err)
 	}
 	apiBase := strings.TrimSuffix(m.GitHubAPIBase, "/")
 	endpoint := fmt.Sprintf("%s/repos/%s/%s/releases/latest", apiBase, owner, repo)
 
 	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
 	if err != nil {
-		return "", nil, fmt.Errorf("build checkver request: %w", err)
+		return "", nil, newError(ErrCodeNetCheckverRequest, "checkver", false, "build checkver request: %w", err)
 	}
 	req.Header.Set("Accept", "application/vnd.github+json")
 
 	resp, err := m.Client.Do(req)
 	if err != nil {
-		return "", nil, fmt.Errorf("%s: checkver request failed: %w", ErrCodeNetCheckverRequest, err)
+		return "", nil, newError(ErrCodeNetCheckverRequest, "checkver", true, "checkver request failed: %w", err)
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		return "", nil, fmt.Errorf("%s: checkver http status: %d", ErrCodeNetCheckverHTTP, resp.StatusCode)
+		return "", nil, newError(ErrCodeNetCheckverHTTP, "checkver", retryableHTTPStatus(resp.StatusCode), "checkver http status: %d", resp.StatusCode)
 	}
 
 	var rel githubRelease
 	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
-		return "", nil, fmt.Errorf("decode checkver response: %w", err)
+		return "", nil, newError(ErrCodeCheckverResolve, "checkver", true, "decode checkver response: %w", err)
 	}
 
 	re, err := regexp.Compile(man.Checkver.Regex)
 	if err != nil {
-		return "", nil, fmt.Errorf("compile checkver regex: %w", err)
+		return "", nil, newError(ErrCodeCheckverResolve, "checkver", false, "compile checkver regex: %w", err)
 	}
 	for _, asset := range rel.Assets {
 		matches := re.FindStringSubmatch(asset.BrowserDownloadURL)
@@ -454,35 +617,35 @@ func (m *Manager) DiscoverLatest(man *manifest.Manifest) (string, map[string]str
 		}
 		version := renderTemplate(man.Checkver.Replace, captures)
 		if version == "" {
-			return "", nil, fmt.Errorf("checkver replace produced empty version")
+			return "", nil, newError(ErrCodeCheckverResolve, "checkver", false, "checkver replace produced empty version")
 		}
 		return version, captures, nil
 	}
-	return "", nil, fmt.Errorf("checkver found no matching release assets")
+	return "", nil, newError(ErrCodeCheckverResolve, "checkver", false, "checkver found no matching release assets")
 }
 
 func (m *Manager) download(appName, url, dst string) error {
 	parsed, err := neturl.Parse(url)
 	if err != nil {
-		return fmt.Errorf("%s: invalid download url: %w", ErrCodeNetDownload, err)
+		return newError(ErrCodeNetDownload, "download", false, "invalid download url: %w", err)
 	}
 	if !strings.EqualFold(parsed.Scheme, "https") {
-		return fmt.Errorf("%s: insecure download url scheme %q", ErrCodeNetDownload, parsed.Scheme)
+		return newError(ErrCodeNetDownload, "download", false, "insecure download url scheme %q", parsed.Scheme)
 	}
 	resp, err := m.Client.Get(url)
 	if err != nil {
-		return fmt.Errorf("%s: download request: %w", ErrCodeNetDownload, err)
+		return newError(ErrCodeNetDownload, "download", true, "download request: %w", err)
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		return fmt.Errorf("%s: download http status: %d", ErrCodeNetDownload, resp.StatusCode)
+		return newError(ErrCodeNetDownload, "download", retryableHTTPStatus(resp.StatusCode), "download http status: %d", resp.StatusCode)
 	}
 	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
-		return fmt.Errorf("create download dir: %w", err)
+		return newError(ErrCodeFilesystem, "download", false, "create download dir: %w", err)
 	}
 	f, err := os.Create(dst)
 	if err != nil {
-		return fmt.Errorf("create download file: %w", err)
+		return newError(ErrCodeFilesystem, "download", false, "create download file: %w", err)
 	}
 	defer f.Close()
 
@@ -495,7 +658,7 @@ func (m *Manager) download(appName, url, dst string) error {
 		n, readErr := resp.Body.Read(buf)
 		if n > 0 {
 			if _, writeErr := f.Write(buf[:n]); writeErr != nil {
-				return fmt.Errorf("write download file: %w", writeErr)
+				return newError(ErrCodeFilesystem, "download", false, "write download file: %w", writeErr)
 			}
 			downloaded += int64(n)
 			if time.Since(lastReportAt) >= 150*time.Millisecond {
@@ -509,23 +672,17 @@ func (m *Manager) download(appName, url, dst string) error {
 		if errors.Is(readErr, io.EOF) {
 			break
 		}
-		return fmt.Errorf("write download file: %w", readErr)
+		return newError(ErrCodeNetDownload, "download", true, "read download body: %w", readErr)
 	}
 	m.reportProgress(appName, url, downloaded, total, true)
 	return nil
 }
 
 func verifySHA256(path, expected string) error {
-	f, err := os.Open(path)
+	actual, err := fileSHA256(path)
 	if err != nil {
-		return fmt.Errorf("open file for hash: %w", err)
-	}
-	defer f.Close()
-	h := sha256.New()
-	if _, err := io.Copy(h, f); err != nil {
-		return fmt.Errorf("hash file: %w", err)
+		return err
 	}
-	actual := hex.EncodeToString(h.Sum(nil))
 	cleanExpected := strings.ToLower(strings.TrimPrefix(expected, "sha256:"))
 	if actual != cleanExpected {
 		return fmt.Errorf("sha256 mismatch: expected %s got %s", cleanExpected, actual)
@@ -552,13 +709,23 @@ func archiveFileNameFromURL(raw string) string {
 	return name
 }
 
-func extractPackage(archivePath, dst string) error {
+func extractPackage(archivePath, dst string, limits ExtractLimits) error {
 	name := strings.ToLower(filepath.Base(archivePath))
 	if shouldPrefer7Zip(name) {
-		return extractWith7ZipPackage(archivePath, dst)
+		if err := extractWith7ZipPackage(archivePath, dst); err != nil {
+			return err
+		}
+		return checkExtractedLimits(archivePath, dst, limits)
+	}
+
+	switch format := sniffArchiveFormat(archivePath); format {
+	case archiveFormatZip:
+		return unzipPackage(archivePath, dst, limits)
+	case archiveFormatTar, archiveFormatGzip, archiveFormatBzip2, archiveFormatXz:
+		return untarPackage(archivePath, dst, format, limits)
 	}
 
-	zipErr := unzipPackage(archivePath, dst)
+	zipErr := unzipPackage(archivePath, dst, limits)
 	if zipErr == nil {
 		return nil
 	}
@@ -569,7 +736,7 @@ func extractPackage(archivePath, dst string) error {
 	if sevenZipErr != nil {
 		return fmt.Errorf("extract package failed (zip=%v; 7zip=%w)", zipErr, sevenZipErr)
 	}
-	return nil
+	return checkExtractedLimits(archivePath, dst, limits)
 }
 
 func shouldPrefer7Zip(fileName string) bool {
@@ -582,52 +749,6 @@ func shouldPrefer7Zip(fileName string) bool {
 		strings.HasSuffix(normalized, "setup.exe")
 }
 
-func unzip(src, dst string) error {
-	r, err := zip.OpenReader(src)
-	if err != nil {
-		return fmt.Errorf("open zip: %w", err)
-	}
-	defer r.Close()
-	if err := os.MkdirAll(dst, 0o755); err != nil {
-		return fmt.Errorf("create extract root: %w", err)
-	}
-
-	for _, f := range r.File {
-		targetPath := filepath.Join(dst, f.Name)
-		cleanDst := filepath.Clean(dst) + string(os.PathSeparator)
-		cleanTarget := filepath.Clean(targetPath)
-		if !strings.HasPrefix(cleanTarget, cleanDst) {
-			return fmt.Errorf("invalid zip path: %s", f.Name)
-		}
-		if f.FileInfo().IsDir() {
-			if err := os.MkdirAll(cleanTarget, 0o755); err != nil {
-				return fmt.Errorf("create dir: %w", err)
-			}
-			continue
-		}
-		if err := os.MkdirAll(filepath.Dir(cleanTarget), 0o755); err != nil {
-			return fmt.Errorf("create parent dir: %w", err)
-		}
-		rc, err := f.Open()
-		if err != nil {
-			return fmt.Errorf("open zip entry: %w", err)
-		}
-		out, err := os.OpenFile(cleanTarget, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, f.Mode())
-		if err != nil {
-			rc.Close()
-			return fmt.Errorf("create extracted file: %w", err)
-		}
-		if _, err := io.Copy(out, rc); err != nil {
-			out.Close()
-			rc.Close()
-			return fmt.Errorf("extract file: %w", err)
-		}
-		out.Close()
-		rc.Close()
-	}
-	return nil
-}
-
 func extractWith7Zip(src, dst string) error {
 	sevenZipPath, err := find7Zip()
 	if err != nil {
@@ -750,6 +871,12 @@ func (m *Manager) runPreInstall(appName, dir string, steps []string) error {
 	return nil
 }
 
+// ReadState returns the runtime state recorded for an app. Apps that were
+// never installed yield a zero state.
+func ReadState(root, appName string) (RuntimeState, error) {
+	return loadState(filepath.Join(root, "apps", appName, "runtime.json"))
+}
+
 func loadState(path string) (RuntimeState, error) {
 	var s RuntimeState
 	b, err := os.ReadFile(path)
@@ -790,18 +917,18 @@ func acquireLock(lockPath string) error {
 	if err := tryAcquireLock(lockPath); err == nil {
 		return nil
 	} else if !errors.Is(err, os.ErrExist) {
-		return err
+		return wrapError(ErrCodeFilesystem, "lock", err)
 	}
 	stale, staleErr := lockFileIsStale(lockPath)
 	if staleErr != nil || !stale {
-		return fmt.Errorf("update already running")
+		return newError(ErrCodeLockBusy, "lock", true, "update already running")
 	}
 	_ = os.Remove(lockPath)
 	if err := tryAcquireLock(lockPath); err != nil {
 		if errors.Is(err, os.ErrExist) {
-			return fmt.Errorf("update already running")
+			return newError(ErrCodeLockBusy, "lock", true, "update already running")
 		}
-		return err
+		return wrapError(ErrCodeFilesystem, "lock", err)
 	}
 	return nil
 }
@@ -854,24 +981,6 @@ func lockFileIsStale(lockPath string) (bool, error) {
 	return !running, nil
 }
 
-func isPIDRunning(pid int) (bool, error) {
-	if pid <= 0 {
-		return false, nil
-	}
-	if runtime.GOOS != "windows" {
-		return true, nil
-	}
-	out, err := exec.Command("tasklist", "/FI", "PID eq "+strconv.Itoa(pid), "/FO", "CSV", "/NH").CombinedOutput()
-	if err != nil {
-		return false, fmt.Errorf("query pid %d: %w: %s", pid, err, strings.TrimSpace(string(out)))
-	}
-	raw := strings.ToLower(string(out))
-	if strings.Contains(raw, "no tasks are running") {
-		return false, nil
-	}
-	return strings.Contains(raw, `"`+strconv.Itoa(pid)+`"`), nil
-}
-
 func releaseLock(lockPath string) {
 	_ = os.Remove(lockPath)
 }
@@ -954,7 +1063,7 @@ func (m *Manager) terminateProcesses(appName, prefix string) error {
 	for time.Now().Before(deadline) {
 		remain, err := m.findPIDs(prefix)
 		if err != nil {
-			return fmt.Errorf("%s: query remaining processes: %w", ErrCodeSwitchProcess, err)
+			return newError(ErrCodeSwitchProcess, "switch", true, "query remaining processes: %w", err)
 		}
 		if len(remain) == 0 {
 			return nil
@@ -963,7 +1072,7 @@ func (m *Manager) terminateProcesses(appName, prefix string) error {
 	}
 	remain, err := m.findPIDs(prefix)
 	if err != nil {
-		return fmt.Errorf("%s: query remaining processes: %w", ErrCodeSwitchProcess, err)
+		return newError(ErrCodeSwitchProcess, "switch", true, "query remaining processes: %w", err)
 	}
 	for _, pid := range remain {
 		if err := m.killPID(pid, true); err != nil {
@@ -998,6 +1107,85 @@ func rollbackCurrent(currentPath, prevTarget string) error {
 	return switchCurrent(currentPath, prevTarget)
 }
 
+// appRunning reports whether a process runs from currentPath, with the
+// reason for the answer. When the process list cannot be read the app
+// counts as running, so open work is never lost to a guess.
+func (m *Manager) appRunning(currentPath string) (bool, string) {
+	if m.findPIDs == nil {
+		return false, ""
+	}
+	pids, err := m.findPIDs(currentPath)
+	if err != nil {
+		return true, fmt.Sprintf("cannot query running processes: %v", err)
+	}
+	if len(pids) > 0 {
+		return true, fmt.Sprintf("app is running (pids=%d)", len(pids))
+	}
+	return false, ""
+}
+
+// recordOutcome keeps RuntimeState.ConsecutiveFailures and the last error of
+// an installed app in step with the result of Update, including failures
+// before the state is loaded, and stamps failures as the last check so
+// backoff counts from them. It runs once the update has released the lock;
+// an update refused because another one holds the lock is not recorded.
+func (m *Manager) recordOutcome(appName string, err error) {
+	if ue, ok := AsError(err); ok && ue.Code == ErrCodeLockBusy {
+		return
+	}
+	statePath := filepath.Join(m.Root, "apps", appName, "runtime.json")
+	if _, statErr := os.Stat(statePath); statErr != nil {
+		return
+	}
+	lockPath := filepath.Join(m.Root, "apps", appName, ".lock")
+	if acquireLock(lockPath) != nil {
+		return
+	}
+	defer releaseLock(lockPath)
+	state, loadErr := loadState(statePath)
+	if loadErr != nil {
+		return
+	}
+	if err == nil {
+		if state.ConsecutiveFailures == 0 && state.LastErrorCode == "" {
+			return
+		}
+		state.ConsecutiveFailures = 0
+		state.LastErrorCode = ""
+		state.LastErrorMsg = ""
+	} else {
+		state.ConsecutiveFailures++
+		state.LastCheckAt = m.now().UTC().Format(time.RFC3339)
+		state.LastErrorCode = ErrCodeFilesystem
+		if ue, ok := AsError(err); ok {
+			state.LastErrorCode = ue.Code
+		}
+		state.LastErrorMsg = err.Error()
+	}
+	if saveErr := saveState(statePath, state); saveErr != nil {
+		m.report(MessageLevelDebug, "record update outcome for %s failed: %v", appName, saveErr)
+	}
+}
+
+// deferReason says why activate should leave the staged version pending
+// rather than switch to it now; empty means switch.
+func (m *Manager) deferReason(currentPath string) string {
+	if m.StageOnly {
+		return "stage only, switch on next launch"
+	}
+	if m.DeferSwitchIfRunning {
+		if running, reason := m.appRunning(currentPath); running {
+			return reason
+		}
+	}
+	return ""
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
 func resolveCurrentTarget(currentPath string) (string, error) {
 	if link, err := os.Readlink(currentPath); err == nil {
 		return link, nil
@@ -1014,6 +1202,9 @@ func resolveCurrentTarget(currentPath string) (string, error) {
 }
 
 func (m *Manager) cleanupOldVersions(appName, currentVersion string) error {
+	if err := m.pruneEventLogs(appName); err != nil {
+		m.report(MessageLevelDebug, "event log retention for %s failed: %v", appName, err)
+	}
 	appDir := filepath.Join(m.Root, "apps", appName)
 	entries, err := os.ReadDir(appDir)
 	if err != nil {
@@ -174,6 +174,37 @@ func TestDiscoverLatest(t *testing.T) {
 	}
 }
 
+func TestRenderAutoupdateArchitectureCoversAllArchitectures(t *testing.T) {
+	man := &manifest.Manifest{
+		Version: "1.0.0",
+		Architecture: manifest.Architecture{
+			ARM64: manifest.Artifact{URL: "https://example.com/1.0.0/app-arm64.exe", Hash: "old", Type: manifest.ArtifactTypeRaw},
+		},
+		Autoupdate: manifest.Autoupdate{
+			Architecture: manifest.Architecture{
+				X64:   manifest.Artifact{URL: "https://example.com/$version/app-x64.zip", ExtractDir: "app-$version"},
+				ARM64: manifest.Artifact{URL: "https://example.com/$version/app-arm64.exe"},
+			},
+		},
+	}
+	rendered, err := renderAutoupdateArchitecture(man, map[string]string{"version": "2.0.0"})
+	if err != nil {
+		t.Fatalf("renderAutoupdateArchitecture failed: %v", err)
+	}
+	if rendered.X64.URL != "https://example.com/2.0.0/app-x64.zip" || rendered.X64.ExtractDir != "app-2.0.0" {
+		t.Fatalf("unexpected 64bit artifact: %#v", rendered.X64)
+	}
+	if rendered.ARM64.URL != "https://example.com/2.0.0/app-arm64.exe" || rendered.ARM64.Hash != "" {
+		t.Fatalf("unexpected arm64 artifact: %#v", rendered.ARM64)
+	}
+	if rendered.ARM64.Type != manifest.ArtifactTypeRaw {
+		t.Fatalf("expected arm64 to inherit artifact type, got %q", rendered.ARM64.Type)
+	}
+	if rendered.X86.URL != "" {
+		t.Fatalf("expected no 32bit artifact, got %#v", rendered.X86)
+	}
+}
+
 func TestUpdateWithCheckverRejectsUnverifiableNewVersion(t *testing.T) {
 	root := t.TempDir()
 	appName := "aria2"
@@ -226,7 +257,7 @@ func TestUpdateWithCheckverRejectsUnverifiableNewVersion(t *testing.T) {
 	}
 }
 
-func TestUpdate_DownloadHTTPFailureSetsPkgDownload(t *testing.T) {
+func TestUpdate_DownloadHTTPFailureSetsNetDownload(t *testing.T) {
 	root := t.TempDir()
 	appName := "aria2"
 	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -252,6 +283,10 @@ func TestUpdate_DownloadHTTPFailureSetsPkgDownload(t *testing.T) {
 	if err == nil || !strings.Contains(err.Error(), ErrCodeNetDownload) {
 		t.Fatalf("expected NET_DOWNLOAD failure, got: %v", err)
 	}
+	ue, ok := AsError(err)
+	if !ok || ue.Code != ErrCodeNetDownload || ue.Stage != "download" || ue.App != appName || ue.Version != "1.37.0-1" || !ue.Retryable {
+		t.Fatalf("unexpected updater error: %#v", ue)
+	}
 	statePath := filepath.Join(root, "apps", appName, "runtime.json")
 	stateBytes, readErr := os.ReadFile(statePath)
 	if readErr != nil {
@@ -261,8 +296,8 @@ func TestUpdate_DownloadHTTPFailureSetsPkgDownload(t *testing.T) {
 	if err := json.Unmarshal(stateBytes, &state); err != nil {
 		t.Fatalf("decode runtime state: %v", err)
 	}
-	if state.LastErrorCode != ErrCodePkgDownload {
-		t.Fatalf("expected %s, got %s", ErrCodePkgDownload, state.LastErrorCode)
+	if state.LastErrorCode != ErrCodeNetDownload {
+		t.Fatalf("expected %s, got %s", ErrCodeNetDownload, state.LastErrorCode)
 	}
 	if state.PendingVersion != "" {
 		t.Fatalf("expected empty pending version on failure, got %q", state.PendingVersion)
@@ -841,6 +876,9 @@ func TestAcquireLock_ActiveLockRejected(t *testing.T) {
 	if err == nil || !strings.Contains(err.Error(), "update already running") {
 		t.Fatalf("expected update already running, got: %v", err)
 	}
+	if ue, ok := AsError(err); !ok || ue.Code != ErrCodeLockBusy || !ue.Retryable {
+		t.Fatalf("expected retryable %s, got: %#v", ErrCodeLockBusy, ue)
+	}
 }
 
 func TestAcquireLock_ConcurrentContention(t *testing.T) {
@@ -978,7 +1016,7 @@ func TestExtractPackagePrefers7ZipForSetupExe(t *testing.T) {
 
 	unzipCalled := false
 	sevenZipCalled := false
-	unzipPackage = func(src, dst string) error {
+	unzipPackage = func(src, dst string, limits ExtractLimits) error {
 		unzipCalled = true
 		return nil
 	}
@@ -987,7 +1025,7 @@ func TestExtractPackagePrefers7ZipForSetupExe(t *testing.T) {
 		return nil
 	}
 
-	if err := extractPackage("/tmp/app-setup.exe", "/tmp/out"); err != nil {
+	if err := extractPackage("/tmp/app-setup.exe", "/tmp/out", ExtractLimits{}); err != nil {
 		t.Fatalf("extractPackage failed: %v", err)
 	}
 	if unzipCalled {
@@ -1008,7 +1046,7 @@ func TestExtractPackageFallbackTo7ZipForNonZip(t *testing.T) {
 
 	unzipCalls := 0
 	sevenZipCalls := 0
-	unzipPackage = func(src, dst string) error {
+	unzipPackage = func(src, dst string, limits ExtractLimits) error {
 		unzipCalls++
 		return fmt.Errorf("open zip: invalid")
 	}
@@ -1017,7 +1055,7 @@ func TestExtractPackageFallbackTo7ZipForNonZip(t *testing.T) {
 		return nil
 	}
 
-	if err := extractPackage("/tmp/app.exe", "/tmp/out"); err != nil {
+	if err := extractPackage("/tmp/app.exe", "/tmp/out", ExtractLimits{}); err != nil {
 		t.Fatalf("extractPackage failed: %v", err)
 	}
 	if unzipCalls != 1 || sevenZipCalls != 1 {
@@ -1048,3 +1086,100 @@ func sha256Hex(b []byte) string {
 	sum := sha256.Sum256(b)
 	return hex.EncodeToString(sum[:])
 }
+
+func TestUpdate_DefersSwitchWhileRunning(t *testing.T) {
+	root := t.TempDir()
+	zipData := buildZip(t, map[string]string{"aria2c.exe": "binary"})
+	downloads := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		_, _ = w.Write(zipData)
+	}))
+	defer server.Close()
+	if err := saveState(filepath.Join(root, "apps", "aria2", "runtime.json"), RuntimeState{CurrentVersion: "1.36.0"}); err != nil {
+		t.Fatalf("write state: %v", err)
+	}
+	man := &manifest.Manifest{
+		Version:      "1.37.0",
+		Architecture: manifest.Architecture{X64: manifest.Artifact{URL: server.URL + "/aria2.zip", Hash: sha256Hex(zipData)}},
+		Bin:          "aria2c.exe",
+	}
+
+	mgr := NewManager(root)
+	mgr.Client = server.Client()
+	mgr.GOARCH = "amd64"
+	mgr.EnvBackends = nil
+	mgr.DeferSwitchIfRunning = true
+	running := true
+	mgr.findPIDs = func(prefix string) ([]int, error) {
+		if running {
+			return []int{42}, nil
+		}
+		return nil, nil
+	}
+	mgr.killPID = func(pid int, force bool) error {
+		t.Fatalf("a deferred switch must not stop the app")
+		return nil
+	}
+	var events []string
+	mgr.OnEvent = func(e StageEvent) { events = append(events, e.Event) }
+	if err := mgr.Update("aria2", man); err != nil {
+		t.Fatalf("expected the switch to be deferred, got %v", err)
+	}
+	state, _ := ReadState(root, "aria2")
+	if state.CurrentVersion != "1.36.0" || state.PendingVersion != "1.37.0" {
+		t.Fatalf("expected 1.37.0 pending, got %+v", state)
+	}
+	if _, err := os.Stat(filepath.Join(root, "apps", "aria2", "1.37.0", "aria2c.exe")); err != nil {
+		t.Fatalf("expected the version staged: %v", err)
+	}
+	history, _ := ReadHistory(root, "aria2")
+	if len(history) != 1 || history[0].Outcome != OutcomeDeferred {
+		t.Fatalf("expected a deferred history entry, got %+v", history)
+	}
+
+	// Once the app has exited, the staged version is switched to without
+	// downloading it again.
+	running = false
+	events = nil
+	_ = mgr.Update("aria2", man)
+	if downloads != 1 || !strings.Contains(strings.Join(events, ","), "PKG_STAGED_REUSE,SWITCH_PROCESS_BEGIN") {
+		t.Fatalf("expected the staged version to be reused, downloads=%d events=%v", downloads, events)
+	}
+}
+
+func TestUpdate_CountsConsecutiveFailures(t *testing.T) {
+	root := t.TempDir()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("not the package"))
+	}))
+	defer server.Close()
+	if err := saveState(filepath.Join(root, "apps", "aria2", "runtime.json"), RuntimeState{CurrentVersion: "1.36.0"}); err != nil {
+		t.Fatalf("write state: %v", err)
+	}
+	man := &manifest.Manifest{
+		Version:      "1.37.0",
+		Architecture: manifest.Architecture{X64: manifest.Artifact{URL: server.URL + "/aria2.zip", Hash: strings.Repeat("a", 64)}},
+		Bin:          "aria2c.exe",
+	}
+	mgr := NewManager(root)
+	mgr.Client = server.Client()
+	mgr.GOARCH = "amd64"
+	mgr.EnvBackends = nil
+	for i := 1; i <= 2; i++ {
+		if err := mgr.Update("aria2", man); err == nil {
+			t.Fatal("expected a hash mismatch")
+		}
+		if state, _ := ReadState(root, "aria2"); state.ConsecutiveFailures != i || state.LastCheckAt == "" || state.LastErrorCode != ErrCodePkgVerify {
+			t.Fatalf("expected %d consecutive failures, got %+v", i, state)
+		}
+	}
+
+	man.Version = "1.36.0"
+	if err := mgr.Update("aria2", man); err != nil {
+		t.Fatalf("expected the app to be current, got %v", err)
+	}
+	if state, _ := ReadState(root, "aria2"); state.ConsecutiveFailures != 0 || state.LastErrorCode != "" {
+		t.Fatalf("expected a success to reset the count and error, got %+v", state)
+	}
+}